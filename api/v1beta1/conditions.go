@@ -0,0 +1,79 @@
+package v1beta1
+
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types of the Module
+const (
+	// ConditionReady is True if last run finished successfully, False if it
+	// failed and Unknown while module is running
+	ConditionReady = "Ready"
+	// ConditionPlanned indicates whether last plan succeeded
+	ConditionPlanned = "Planned"
+	// ConditionApplied indicates whether last apply succeeded
+	ConditionApplied = "Applied"
+	// ConditionDrifted is True if last plan detected changes which are not applied
+	ConditionDrifted = "Drifted"
+	// ConditionStalled is True if last run failed and module requires attention
+	ConditionStalled = "Stalled"
+)
+
+// reasons indicating failure before or during plan stage
+var planFailureReasons = []string{
+	ReasonRunPreparationFailed,
+	ReasonDelegationFailed,
+	ReasonGitFailure,
+	ReasonInitialiseFailed,
+	ReasonPlanFailed,
+	ReasonStateLocked,
+}
+
+// SetRunningConditions updates conditions when module run starts
+func (m *Module) SetRunningConditions(msg string) {
+	m.setCondition(ConditionReady, metav1.ConditionUnknown, ReasonRunTriggered, msg)
+}
+
+// SetFinishedConditions updates conditions when module run finished successfully
+// with given reason
+func (m *Module) SetFinishedConditions(reason, msg string) {
+	m.setCondition(ConditionReady, metav1.ConditionTrue, reason, msg)
+	m.setCondition(ConditionStalled, metav1.ConditionFalse, reason, msg)
+	m.setCondition(ConditionPlanned, metav1.ConditionTrue, reason, msg)
+
+	switch reason {
+	case ReasonPlanOnlyDriftDetected, ReasonApplyDeferred:
+		m.setCondition(ConditionDrifted, metav1.ConditionTrue, reason, msg)
+	case ReasonApplied:
+		m.setCondition(ConditionDrifted, metav1.ConditionFalse, reason, msg)
+		m.setCondition(ConditionApplied, metav1.ConditionTrue, reason, msg)
+	default:
+		m.setCondition(ConditionDrifted, metav1.ConditionFalse, reason, msg)
+	}
+}
+
+// SetFailedConditions updates conditions when module run failed with given reason
+func (m *Module) SetFailedConditions(reason, msg string) {
+	m.setCondition(ConditionReady, metav1.ConditionFalse, reason, msg)
+	m.setCondition(ConditionStalled, metav1.ConditionTrue, reason, msg)
+
+	switch {
+	case reason == ReasonApplyFailed:
+		m.setCondition(ConditionApplied, metav1.ConditionFalse, reason, msg)
+	case slices.Contains(planFailureReasons, reason):
+		m.setCondition(ConditionPlanned, metav1.ConditionFalse, reason, msg)
+	}
+}
+
+func (m *Module) setCondition(condType string, status metav1.ConditionStatus, reason, msg string) {
+	meta.SetStatusCondition(&m.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: m.Generation,
+		Reason:             reason,
+		Message:            msg,
+	})
+}
@@ -0,0 +1,70 @@
+package v1beta1_test
+
+import (
+	"testing"
+
+	"github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestModule_Conditions(t *testing.T) {
+	m := &v1beta1.Module{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+
+	assertConditions := func(t *testing.T, want map[string]metav1.ConditionStatus) {
+		t.Helper()
+		for condType, status := range want {
+			if !meta.IsStatusConditionPresentAndEqual(m.Status.Conditions, condType, status) {
+				t.Errorf("condition %s: expected %s, got %v", condType, status, meta.FindStatusCondition(m.Status.Conditions, condType))
+			}
+		}
+	}
+
+	m.SetRunningConditions("run started")
+	assertConditions(t, map[string]metav1.ConditionStatus{
+		v1beta1.ConditionReady: metav1.ConditionUnknown,
+	})
+
+	m.SetFinishedConditions(v1beta1.ReasonPlanOnlyDriftDetected, "Plan: 1 to add, 0 to change, 0 to destroy.")
+	assertConditions(t, map[string]metav1.ConditionStatus{
+		v1beta1.ConditionReady:   metav1.ConditionTrue,
+		v1beta1.ConditionPlanned: metav1.ConditionTrue,
+		v1beta1.ConditionDrifted: metav1.ConditionTrue,
+		v1beta1.ConditionStalled: metav1.ConditionFalse,
+	})
+	if meta.FindStatusCondition(m.Status.Conditions, v1beta1.ConditionApplied) != nil {
+		t.Errorf("Applied condition should not be set by plan only run")
+	}
+
+	m.SetFailedConditions(v1beta1.ReasonApplyFailed, "unable to apply module")
+	assertConditions(t, map[string]metav1.ConditionStatus{
+		v1beta1.ConditionReady:   metav1.ConditionFalse,
+		v1beta1.ConditionPlanned: metav1.ConditionTrue,
+		v1beta1.ConditionApplied: metav1.ConditionFalse,
+		v1beta1.ConditionDrifted: metav1.ConditionTrue,
+		v1beta1.ConditionStalled: metav1.ConditionTrue,
+	})
+
+	m.SetFinishedConditions(v1beta1.ReasonApplied, "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.")
+	assertConditions(t, map[string]metav1.ConditionStatus{
+		v1beta1.ConditionReady:   metav1.ConditionTrue,
+		v1beta1.ConditionPlanned: metav1.ConditionTrue,
+		v1beta1.ConditionApplied: metav1.ConditionTrue,
+		v1beta1.ConditionDrifted: metav1.ConditionFalse,
+		v1beta1.ConditionStalled: metav1.ConditionFalse,
+	})
+
+	m.SetFailedConditions(v1beta1.ReasonPlanFailed, "unable to plan module")
+	assertConditions(t, map[string]metav1.ConditionStatus{
+		v1beta1.ConditionReady:   metav1.ConditionFalse,
+		v1beta1.ConditionPlanned: metav1.ConditionFalse,
+		v1beta1.ConditionApplied: metav1.ConditionTrue,
+		v1beta1.ConditionStalled: metav1.ConditionTrue,
+	})
+
+	for _, c := range m.Status.Conditions {
+		if c.ObservedGeneration != 2 {
+			t.Errorf("condition %s: expected observedGeneration 2, got %d", c.Type, c.ObservedGeneration)
+		}
+	}
+}
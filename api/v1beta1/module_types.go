@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -45,11 +46,16 @@ const (
 	ReasonPlanFailed           = "PlanFailed"
 	ReasonApplyFailed          = "ApplyFailed"
 	ReasonInvalidRequest       = "InvalidRequest"
+	ReasonApplyWindowClosed    = "ApplyWindowClosed"
+	ReasonStateLocked          = "StateLocked"
 
 	ReasonInitialised           = "Initialised"
 	ReasonPlanOnlyDriftDetected = "PlanOnlyDriftDetected"
 	ReasonNoDriftDetected       = "NoDriftDetected"
 	ReasonApplied               = "Applied"
+	ReasonStaleLockRemoved      = "StaleLockRemoved"
+	ReasonApplyDeferred         = "ApplyDeferred"
+	ReasonBreakGlassApply       = "BreakGlassApply"
 )
 
 const (
@@ -68,6 +74,9 @@ const (
 	// non-default run happens on PR branch instead
 	// PRPlan indicates terraform plan trigged by PullRequest on modules repo path.
 	PRPlan = "PullRequestPlan"
+	// PRApply indicates terraform apply requested via PR comment on modules
+	// PR branch. its only allowed if module's AllowPRApply is true
+	PRApply = "PullRequestApply"
 )
 
 // Overall state of Module run
@@ -101,6 +110,14 @@ type ModuleSpec struct {
 	// Path to the directory containing Terraform Root Module (.tf) files.
 	Path string `json:"path"`
 
+	// WatchPaths are the additional paths relative to the repository root
+	// which are used by the module (i.e. templates, policy docs, shared tfvars).
+	// changes to these paths will trigger run same as module path.
+	// glob patterns are supported, '*' matches within a directory and '**'
+	// matches any number of directories e.g. 'policies/**/*.json'
+	// +optional
+	WatchPaths []string `json:"watchPaths,omitempty"`
+
 	// The schedule in Cron format. Module will do periodic run for a given schedule
 	// if no schedule provided then module will only run if new PRs are added to given module path
 	// +optional
@@ -125,6 +142,12 @@ type ModuleSpec struct {
 	// +kubebuilder:default=true
 	PlanOnPR *bool `json:"planOnPR,omitempty"`
 
+	// if AllowPRApply is true, module admins with 'GitHubUser' subject can apply
+	// PR branch before merging by posting `@terraform-applier apply <module>` comment.
+	// PR must be approved, mergeable and up to date with the base branch.
+	// +optional
+	AllowPRApply bool `json:"allowPRApply,omitempty"`
+
 	// List of backend config attributes passed to the Terraform init
 	// for terraform backend configuration
 	// +optional
@@ -176,6 +199,63 @@ type ModuleSpec struct {
 	// List of roles and subjects assigned to that role for the module.
 	// +optional
 	RBAC []RBAC `json:"rbac,omitempty"`
+
+	// DefaultsRef is the name of the ModuleDefaults object in the same namespace
+	// as the Module. Backend, Env, Var, VaultRequests and RBAC from the defaults
+	// are merged with module's spec, values set on the module takes precedence.
+	// +optional
+	DefaultsRef string `json:"defaultsRef,omitempty"`
+
+	// ApplyWindows restricts when module can be applied. If set, automated runs
+	// (ScheduledRun and PollingRun) outside of these windows will be downgraded
+	// to plan only and apply will be deferred until next window opens.
+	// Manual apply outside of the windows is rejected unless requested as break-glass
+	// by module Admin. If not set module can be applied at any time.
+	// +optional
+	ApplyWindows []ApplyWindow `json:"applyWindows,omitempty"`
+
+	// Notifications routes module run events to the notification sinks
+	// configured on the controller. PR runs doesn't trigger notifications.
+	// +optional
+	Notifications []NotificationRule `json:"notifications,omitempty"`
+}
+
+// NotificationEvent is the module run event which triggers notification
+// +kubebuilder:validation:Enum=applied;failed;driftDetected
+type NotificationEvent string
+
+const (
+	// NotificationEventApplied is sent when module is applied successfully
+	NotificationEventApplied NotificationEvent = "applied"
+	// NotificationEventFailed is sent when module run fails
+	NotificationEventFailed NotificationEvent = "failed"
+	// NotificationEventDriftDetected is sent when plan detects drift which
+	// is not applied i.e. plan only module or apply is deferred
+	NotificationEventDriftDetected NotificationEvent = "driftDetected"
+)
+
+// NotificationRule sends notifications of the given events to the sink
+type NotificationRule struct {
+	// Sink is the name of the notification sink configured on the controller
+	// +required
+	Sink string `json:"sink"`
+
+	// Events for which notification is sent to the sink
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	Events []NotificationEvent `json:"events"`
+}
+
+// ApplyWindow is a recurring period of time during which applies are allowed
+type ApplyWindow struct {
+	// Schedule in Cron format at which window opens. e.g. '0 9 * * 1-4'
+	// schedule is evaluated in controller's timezone unless 'CRON_TZ=' prefix is used
+	// +required
+	Schedule string `json:"schedule"`
+
+	// Duration for which window stays open after each schedule. e.g. '8h'
+	// +required
+	Duration metav1.Duration `json:"duration"`
 }
 
 // ModuleStatus defines the observed state of Module
@@ -222,6 +302,60 @@ type ModuleStatus struct {
 	// LastAppliedCommitHash is the hash of git commit of last successful apply.
 	// +optional
 	LastAppliedCommitHash string `json:"lastAppliedCommitHash,omitempty"`
+
+	// LastPlan is the summary of changes detected by the last successful plan,
+	// it is reset after successful apply as there are no pending changes
+	// +optional
+	LastPlan *PlanSummary `json:"lastPlan,omitempty"`
+
+	// LastRunDuration is the duration of the last run
+	// +optional
+	LastRunDuration *metav1.Duration `json:"lastRunDuration,omitempty"`
+
+	// LastFailureMessage is the message of the last failed run.
+	// it is cleared on next successful run
+	// +optional
+	LastFailureMessage string `json:"lastFailureMessage,omitempty"`
+
+	// LastErrorExcerpt is the truncated terraform error output of the last failed run.
+	// it is cleared on next successful run
+	// +optional
+	LastErrorExcerpt string `json:"lastErrorExcerpt,omitempty"`
+
+	// Conditions represent the latest available observations of module's state.
+	// Supported condition types are Ready, Planned, Applied, Drifted and Stalled.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// StateLock contains details of the terraform state lock which prevented
+	// last run from acquiring the state. It is cleared once the lock is acquired.
+	// +optional
+	StateLock *StateLock `json:"stateLock,omitempty"`
+}
+
+// PlanSummary represents the number of resources terraform plans to change
+type PlanSummary struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+// StateLock represents the lock info reported by terraform when it was unable
+// to acquire the state lock
+type StateLock struct {
+	// ID of the lock, this ID can be used to force unlock the state
+	ID string `json:"id,omitempty"`
+	// Who is holding the lock, it is in the form of 'user@hostname'
+	// +optional
+	Who string `json:"who,omitempty"`
+	// Operation which acquired the lock e.g. 'OperationTypePlan'
+	// +optional
+	Operation string `json:"operation,omitempty"`
+	// Created is the time when lock was acquired
+	// +optional
+	Created *metav1.Time `json:"created,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -229,8 +363,14 @@ type ModuleStatus struct {
 //+kubebuilder:printcolumn:name="Schedule",type="string",JSONPath=".spec.schedule",description=""
 //+kubebuilder:printcolumn:name="PlanOnly",type="string",JSONPath=".spec.planOnly",description=""
 //+kubebuilder:printcolumn:name="AutoApply",type="string",JSONPath=".spec.autoApply",description=""
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`,description=""
 //+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.currentState",description=""
 //+kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.stateReason",description=""
+//+kubebuilder:printcolumn:name="Add",type="integer",JSONPath=".status.lastPlan.add",description="",priority=10
+//+kubebuilder:printcolumn:name="Change",type="integer",JSONPath=".status.lastPlan.change",description="",priority=10
+//+kubebuilder:printcolumn:name="Destroy",type="integer",JSONPath=".status.lastPlan.destroy",description="",priority=10
+//+kubebuilder:printcolumn:name="Duration",type="string",JSONPath=".status.lastRunDuration",description="",priority=10
+//+kubebuilder:printcolumn:name="Last Failure",type="string",JSONPath=".status.lastFailureMessage",description="",priority=10
 //+kubebuilder:printcolumn:name="Last Run Started At",type="string",JSONPath=`.status.lastDefaultRunStartedAt`,description=""
 //+kubebuilder:printcolumn:name="Last Applied At",type="string",JSONPath=`.status.lastAppliedAt`,description=""
 //+kubebuilder:printcolumn:name="Commit",type="string",JSONPath=`.status.lastDefaultRunCommitHash`,description="",priority=10
@@ -316,11 +456,12 @@ type RBAC struct {
 	Subjects []Subject `json:"subjects,omitempty"`
 }
 type Subject struct {
-	// Kind of object being referenced. Allowed values are "User" & "Group"
+	// Kind of object being referenced. Allowed values are "User", "Group" & "GitHubUser"
 	// +required
-	// +kubebuilder:validation:Enum=User;Group
+	// +kubebuilder:validation:Enum=User;Group;GitHubUser
 	Kind string `json:"kind,omitempty"`
 	// Name of the object being referenced. For "User" kind value should be email
+	// and for "GitHubUser" kind value should be GitHub login
 	// +required
 	Name string `json:"name,omitempty"`
 }
@@ -383,6 +524,45 @@ func (m *Module) IsAutoApply() bool {
 	return m.Spec.AutoApply != nil && *m.Spec.AutoApply
 }
 
+// IsApplyAllowedAt returns true if given time is within one of the apply
+// windows or if module doesn't have any apply windows set.
+// invalid windows are ignored, hence module with only invalid windows
+// will never be allowed to apply.
+func (m *Module) IsApplyAllowedAt(t time.Time) bool {
+	if len(m.Spec.ApplyWindows) == 0 {
+		return true
+	}
+
+	for _, w := range m.Spec.ApplyWindows {
+		sched, err := cron.ParseStandard(w.Schedule)
+		if err != nil || w.Duration.Duration <= 0 {
+			continue
+		}
+		// if window was opened during last 'duration' then t is within window
+		if !sched.Next(t.Add(-w.Duration.Duration)).After(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NextApplyWindow returns the time when next apply window opens after given time.
+// zero time is returned if there are no valid windows
+func (m *Module) NextApplyWindow(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range m.Spec.ApplyWindows {
+		sched, err := cron.ParseStandard(w.Schedule)
+		if err != nil || w.Duration.Duration <= 0 {
+			continue
+		}
+		if n := sched.Next(t); next.IsZero() || n.Before(next) {
+			next = n
+		}
+	}
+	return next
+}
+
 func (m *Module) NewRunRequest(reqType, lockID string) *Request {
 	req := Request{
 		RequestedAt: &metav1.Time{Time: time.Now()},
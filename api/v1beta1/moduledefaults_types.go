@@ -0,0 +1,135 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ModuleDefaultsSpec defines the common configuration shared by the modules
+// referencing it. Module's own spec always takes precedence over defaults.
+type ModuleDefaultsSpec struct {
+	// List of backend config attributes passed to the Terraform init
+	// for terraform backend configuration
+	// +optional
+	Backend []EnvVar `json:"backend,omitempty"`
+
+	// List of environment variables passed to the Terraform execution.
+	// +optional
+	Env []EnvVar `json:"env,omitempty"`
+
+	// List of input variables passed to the Terraform execution.
+	// +optional
+	Var []EnvVar `json:"var,omitempty"`
+
+	// VaultRequests specifies credential generate requests from the vault
+	// configured on the controller
+	// +optional
+	VaultRequests *VaultRequests `json:"vaultRequests,omitempty"`
+
+	// List of roles and subjects assigned to that role for the module.
+	// +optional
+	RBAC []RBAC `json:"rbac,omitempty"`
+
+	// Notifications routes module run events to the notification sinks
+	// configured on the controller.
+	// +optional
+	Notifications []NotificationRule `json:"notifications,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+
+// ModuleDefaults is the Schema for the moduledefaults API
+type ModuleDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ModuleDefaultsSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ModuleDefaultsList contains a list of ModuleDefaults
+type ModuleDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ModuleDefaults `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ModuleDefaults{}, &ModuleDefaultsList{})
+}
+
+// MergeDefaults merges given defaults into module's spec. Values set on
+// module always wins, envs and vars are merged by name, RBAC is merged by role
+// and notifications are merged by sink.
+func (m *Module) MergeDefaults(defaults *ModuleDefaults) {
+	if defaults == nil {
+		return
+	}
+
+	m.Spec.Backend = mergeEnvVars(defaults.Spec.Backend, m.Spec.Backend)
+	m.Spec.Env = mergeEnvVars(defaults.Spec.Env, m.Spec.Env)
+	m.Spec.Var = mergeEnvVars(defaults.Spec.Var, m.Spec.Var)
+
+	if defaults.Spec.VaultRequests != nil {
+		if m.Spec.VaultRequests == nil {
+			m.Spec.VaultRequests = &VaultRequests{}
+		}
+		if m.Spec.VaultRequests.AWS == nil && defaults.Spec.VaultRequests.AWS != nil {
+			m.Spec.VaultRequests.AWS = defaults.Spec.VaultRequests.AWS.DeepCopy()
+		}
+		if m.Spec.VaultRequests.GCP == nil && defaults.Spec.VaultRequests.GCP != nil {
+			m.Spec.VaultRequests.GCP = defaults.Spec.VaultRequests.GCP.DeepCopy()
+		}
+	}
+
+	for _, dr := range defaults.Spec.RBAC {
+		found := false
+		for _, mr := range m.Spec.RBAC {
+			if mr.Role == dr.Role {
+				found = true
+				break
+			}
+		}
+		if !found {
+			m.Spec.RBAC = append(m.Spec.RBAC, *dr.DeepCopy())
+		}
+	}
+
+	for _, dn := range defaults.Spec.Notifications {
+		found := false
+		for _, mn := range m.Spec.Notifications {
+			if mn.Sink == dn.Sink {
+				found = true
+				break
+			}
+		}
+		if !found {
+			m.Spec.Notifications = append(m.Spec.Notifications, *dn.DeepCopy())
+		}
+	}
+}
+
+// mergeEnvVars returns defaults followed by module's values, entry from
+// defaults is dropped if module has entry with same name
+func mergeEnvVars(defaults, module []EnvVar) []EnvVar {
+	if len(defaults) == 0 {
+		return module
+	}
+
+	merged := make([]EnvVar, 0, len(defaults)+len(module))
+	for _, d := range defaults {
+		overridden := false
+		for _, e := range module {
+			if e.Name == d.Name {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, *d.DeepCopy())
+		}
+	}
+
+	return append(merged, module...)
+}
@@ -0,0 +1,119 @@
+package v1beta1_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+)
+
+func TestModule_MergeDefaults(t *testing.T) {
+	defaults := &v1beta1.ModuleDefaults{
+		Spec: v1beta1.ModuleDefaultsSpec{
+			Backend: []v1beta1.EnvVar{
+				{Name: "bucket", Value: "default-bucket"},
+				{Name: "region", Value: "eu-west-1"},
+			},
+			Env: []v1beta1.EnvVar{
+				{Name: "TF_LOG", Value: "INFO"},
+			},
+			VaultRequests: &v1beta1.VaultRequests{
+				AWS: &v1beta1.VaultAWSRequest{VaultRole: "default-role"},
+				GCP: &v1beta1.VaultGCPRequest{Roleset: "default-roleset"},
+			},
+			RBAC: []v1beta1.RBAC{
+				{Role: "Admin", Subjects: []v1beta1.Subject{{Kind: "Group", Name: "default-admins"}}},
+			},
+			Notifications: []v1beta1.NotificationRule{
+				{Sink: "team-slack", Events: []v1beta1.NotificationEvent{v1beta1.NotificationEventFailed}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		spec v1beta1.ModuleSpec
+		want v1beta1.ModuleSpec
+	}{
+		{
+			name: "empty module",
+			spec: v1beta1.ModuleSpec{},
+			want: v1beta1.ModuleSpec{
+				Backend: []v1beta1.EnvVar{
+					{Name: "bucket", Value: "default-bucket"},
+					{Name: "region", Value: "eu-west-1"},
+				},
+				Env: []v1beta1.EnvVar{
+					{Name: "TF_LOG", Value: "INFO"},
+				},
+				VaultRequests: &v1beta1.VaultRequests{
+					AWS: &v1beta1.VaultAWSRequest{VaultRole: "default-role"},
+					GCP: &v1beta1.VaultGCPRequest{Roleset: "default-roleset"},
+				},
+				RBAC: []v1beta1.RBAC{
+					{Role: "Admin", Subjects: []v1beta1.Subject{{Kind: "Group", Name: "default-admins"}}},
+				},
+				Notifications: []v1beta1.NotificationRule{
+					{Sink: "team-slack", Events: []v1beta1.NotificationEvent{v1beta1.NotificationEventFailed}},
+				},
+			},
+		},
+		{
+			name: "module wins",
+			spec: v1beta1.ModuleSpec{
+				Backend: []v1beta1.EnvVar{
+					{Name: "bucket", Value: "module-bucket"},
+				},
+				Env: []v1beta1.EnvVar{
+					{Name: "FOO", Value: "bar"},
+				},
+				Var: []v1beta1.EnvVar{
+					{Name: "name", Value: "module"},
+				},
+				VaultRequests: &v1beta1.VaultRequests{
+					AWS: &v1beta1.VaultAWSRequest{VaultRole: "module-role"},
+				},
+				RBAC: []v1beta1.RBAC{
+					{Role: "Admin", Subjects: []v1beta1.Subject{{Kind: "User", Name: "user@example.com"}}},
+				},
+				Notifications: []v1beta1.NotificationRule{
+					{Sink: "team-slack", Events: []v1beta1.NotificationEvent{v1beta1.NotificationEventApplied}},
+					{Sink: "audit", Events: []v1beta1.NotificationEvent{v1beta1.NotificationEventDriftDetected}},
+				},
+			},
+			want: v1beta1.ModuleSpec{
+				Backend: []v1beta1.EnvVar{
+					{Name: "region", Value: "eu-west-1"},
+					{Name: "bucket", Value: "module-bucket"},
+				},
+				Env: []v1beta1.EnvVar{
+					{Name: "TF_LOG", Value: "INFO"},
+					{Name: "FOO", Value: "bar"},
+				},
+				Var: []v1beta1.EnvVar{
+					{Name: "name", Value: "module"},
+				},
+				VaultRequests: &v1beta1.VaultRequests{
+					AWS: &v1beta1.VaultAWSRequest{VaultRole: "module-role"},
+					GCP: &v1beta1.VaultGCPRequest{Roleset: "default-roleset"},
+				},
+				RBAC: []v1beta1.RBAC{
+					{Role: "Admin", Subjects: []v1beta1.Subject{{Kind: "User", Name: "user@example.com"}}},
+				},
+				Notifications: []v1beta1.NotificationRule{
+					{Sink: "team-slack", Events: []v1beta1.NotificationEvent{v1beta1.NotificationEventApplied}},
+					{Sink: "audit", Events: []v1beta1.NotificationEvent{v1beta1.NotificationEventDriftDetected}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &v1beta1.Module{Spec: tt.spec}
+			m.MergeDefaults(defaults)
+			if diff := cmp.Diff(tt.want, m.Spec); diff != "" {
+				t.Errorf("MergeDefaults() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ModuleSetLabelKey is set on all the modules generated by ModuleSet,
+	// value is the name of the ModuleSet
+	ModuleSetLabelKey = `terraform-applier.uw.systems/module-set`
+)
+
+// The potential reasons for ModuleSet events
+const (
+	ReasonModuleGenerated     = "ModuleGenerated"
+	ReasonModuleRemoved       = "ModuleRemoved"
+	ReasonModuleGenerateError = "ModuleGenerateError"
+)
+
+// ModuleSetSpec defines the desired state of ModuleSet
+type ModuleSetSpec struct {
+	// Directories is a glob pattern of the directories relative to the repository
+	// root for which Module should be generated. e.g. 'prod/aws/*'
+	// only directories containing terraform (.tf) files are selected.
+	Directories string `json:"directories"`
+
+	// Exclude is a list of glob patterns of directories which should be skipped
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+
+	// PollInterval specifies the interval at which the Git repository must be
+	// checked for new or deleted directories.
+	// +optional
+	// +kubebuilder:default=60
+	// +kubebuilder:validation:Minimum=60
+	PollInterval int `json:"pollInterval,omitempty"`
+
+	// Template used to generate Modules. 'repoURL' and 'repoRef' of the template
+	// are used to look up the directories.
+	// following values can be used in template's name, labels, annotations,
+	// path and schedule using go template syntax
+	// '{{.Path}}' path of the directory relative to repository root
+	// '{{.Dir}}' base name of the directory
+	// '{{.Parent}}' base name of the parent directory
+	Template ModuleSetTemplate `json:"template"`
+}
+
+// ModuleSetTemplate is the template of the generated Modules
+type ModuleSetTemplate struct {
+	// +optional
+	Metadata ModuleSetTemplateMeta `json:"metadata,omitempty"`
+
+	Spec ModuleSpec `json:"spec"`
+}
+
+type ModuleSetTemplateMeta struct {
+	// Name of the generated Module. It is converted to a valid
+	// kubernetes name by replacing invalid characters with '-'
+	// +optional
+	// +kubebuilder:default="{{.Dir}}"
+	Name string `json:"name,omitempty"`
+
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ModuleSetStatus defines the observed state of ModuleSet
+type ModuleSetStatus struct {
+	// ObservedGeneration is the last reconciled generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastSyncedCommitHash is the hash of git commit used to generate Modules.
+	// +optional
+	LastSyncedCommitHash string `json:"lastSyncedCommitHash,omitempty"`
+
+	// LastSyncedAt is the time when Modules were last generated
+	// +optional
+	LastSyncedAt *metav1.Time `json:"lastSyncedAt,omitempty"`
+
+	// Paths is the list of matched directories for which Modules are generated
+	// +optional
+	Paths []string `json:"paths,omitempty"`
+
+	// Error is the last error occurred during generation
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Directories",type="string",JSONPath=".spec.directories",description=""
+//+kubebuilder:printcolumn:name="Commit",type="string",JSONPath=".status.lastSyncedCommitHash",description=""
+//+kubebuilder:printcolumn:name="Last Synced At",type="string",JSONPath=".status.lastSyncedAt",description=""
+//+kubebuilder:printcolumn:name="Error",type="string",JSONPath=".status.error",description="",priority=10
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+
+// ModuleSet is the Schema for the modulesets API
+type ModuleSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ModuleSetSpec   `json:"spec,omitempty"`
+	Status ModuleSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ModuleSetList contains a list of ModuleSet
+type ModuleSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ModuleSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ModuleSet{}, &ModuleSetList{})
+}
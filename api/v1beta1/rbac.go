@@ -33,3 +33,27 @@ func CanForceRun(email string, groups []string, module *Module) bool {
 
 	return false
 }
+
+// CanRunPRCommand returns true if given GitHub login is module admin and
+// allowed to run privileged PR comment commands like apply, unlock and cancel.
+// it must only be called for GitHub PRs as logins from other providers can
+// not be matched against 'GitHubUser' subjects
+func CanRunPRCommand(login string, module *Module) bool {
+	if strings.TrimSpace(login) == "" {
+		return false
+	}
+
+	for _, rbac := range module.Spec.RBAC {
+		if rbac.Role != RoleAdmin {
+			continue
+		}
+		for _, subject := range rbac.Subjects {
+			// GitHub logins are case insensitive
+			if subject.Kind == "GitHubUser" && strings.EqualFold(subject.Name, login) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -166,3 +166,39 @@ func TestCanForceRun(t *testing.T) {
 		})
 	}
 }
+
+func TestCanRunPRCommand(t *testing.T) {
+	module := &Module{
+		Spec: ModuleSpec{
+			RBAC: []RBAC{{
+				Role: "View",
+				Subjects: []Subject{
+					{Kind: "GitHubUser", Name: "viewer"},
+				}}, {
+				Role: "Admin",
+				Subjects: []Subject{
+					{Kind: "User", Name: "u1"},
+					{Kind: "GitHubUser", Name: "Octo-Cat"},
+				}},
+			}}}
+
+	tests := []struct {
+		name  string
+		login string
+		want  bool
+	}{
+		{"empty login", "", false},
+		{"login match", "Octo-Cat", true},
+		{"login match is case insensitive", "octo-cat", true},
+		{"login with View role", "viewer", false},
+		{"User kind is not GitHub login", "u1", false},
+		{"unknown login", "someone", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanRunPRCommand(tt.login, module); got != tt.want {
+				t.Errorf("CanRunPRCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -37,6 +37,8 @@ type Run struct {
 	InitOutput   string        `json:"initOutput,omitempty"`
 	Output       string        `json:"output,omitempty"`
 	Summary      string        `json:"summary,omitempty"`
+	// TraceID is the OpenTelemetry trace ID of the run, its only set if tracing is enabled
+	TraceID string `json:"traceID,omitempty"`
 }
 
 func NewRun(module *Module, req *Request) Run {
@@ -57,12 +59,20 @@ type Request struct {
 	Type        string       `json:"type,omitempty"`
 	PR          *PullRequest `json:"pr,omitempty"`
 	LockID      string       `json:"lockID,omitempty"`
+	// BreakGlass allows ForcedApply request outside of module's apply windows
+	BreakGlass bool `json:"breakGlass,omitempty"`
 }
 
 type PullRequest struct {
 	Number     int    `json:"num,omitempty"`
 	HeadBranch string `json:"headBranch,omitempty"`
-	CommentID  int    `json:"commentID,omitempty"`
+	// HeadCommit pins the run to the given commit of the PR head branch
+	HeadCommit string `json:"headCommit,omitempty"`
+	// CommitID is the module path hash the request was made for
+	CommitID  string `json:"commitID,omitempty"`
+	CommentID int    `json:"commentID,omitempty"`
+	// CheckRunID is the ID of the GitHub check run created for the request
+	CheckRunID int64 `json:"checkRunID,omitempty"`
 }
 
 func (req *Request) Validate(module *Module) error {
@@ -75,14 +85,33 @@ func (req *Request) Validate(module *Module) error {
 		PollingRun,
 		ForcedPlan,
 		ForcedApply,
-		PRPlan:
+		PRPlan,
+		PRApply:
 	default:
 		return fmt.Errorf("unknown Request type provided")
 	}
 
 	// reject request if apply req is downgraded to plan only to avoid confusion
 	if req.Type == ForcedApply && !req.IsApply(module) {
-		return fmt.Errorf("Manual Apply rejected: Module.Spec.PlanOnly is true")
+		if module.IsPlanOnly() {
+			return fmt.Errorf("Manual Apply rejected: Module.Spec.PlanOnly is true")
+		}
+		return fmt.Errorf("Manual Apply rejected: module is outside of its apply windows")
+	}
+
+	if req.Type == PRApply {
+		if req.PR == nil {
+			return fmt.Errorf("PR Apply rejected: PR details are missing")
+		}
+		if !req.IsApply(module) {
+			if module.IsPlanOnly() {
+				return fmt.Errorf("PR Apply rejected: Module.Spec.PlanOnly is true")
+			}
+			if !module.Spec.AllowPRApply {
+				return fmt.Errorf("PR Apply rejected: Module.Spec.AllowPRApply is false")
+			}
+			return fmt.Errorf("PR Apply rejected: module is outside of its apply windows")
+		}
 	}
 
 	return nil
@@ -106,12 +135,18 @@ func (req *Request) IsApply(module *Module) bool {
 
 	// for scheduled and polling run respect module spec
 	if req.Type == ScheduledRun || req.Type == PollingRun {
-		return module.IsAutoApply()
+		return module.IsAutoApply() && req.inApplyWindow(module)
 	}
 
-	// this is override triggered by user
+	// this is override triggered by user, outside of apply windows
+	// its only allowed as break-glass
 	if req.Type == ForcedApply {
-		return true
+		return req.BreakGlass || req.inApplyWindow(module)
+	}
+
+	// PR branch can only be applied if module allows it
+	if req.Type == PRApply {
+		return module.Spec.AllowPRApply && req.inApplyWindow(module)
 	}
 
 	// these are plan only override requests
@@ -123,17 +158,46 @@ func (req *Request) IsApply(module *Module) bool {
 	return false
 }
 
+// IsApplyDeferred returns true if request would have been applied but
+// module is outside of its apply windows at the time of request
+func (req *Request) IsApplyDeferred(module *Module) bool {
+	if module.IsPlanOnly() || !module.IsAutoApply() {
+		return false
+	}
+	if req.Type != ScheduledRun && req.Type != PollingRun {
+		return false
+	}
+	return !req.inApplyWindow(module)
+}
+
+func (req *Request) inApplyWindow(module *Module) bool {
+	t := time.Now()
+	if !req.RequestedAt.IsZero() {
+		t = req.RequestedAt.Time
+	}
+	return module.IsApplyAllowedAt(t)
+}
+
 // SkipStatusUpdate will return if run info/stats needs to be added to CRD
-// and stored in etcd
+// and stored in etcd. PR apply also skips update so that default branch
+// runs are not affected by PR branch commit
 func (req *Request) SkipStatusUpdate() bool {
-	return req.Type == PRPlan
+	return req.IsPRRun()
+}
+
+// IsPRRun returns true if request is for PR branch
+func (req *Request) IsPRRun() bool {
+	return req.Type == PRPlan || req.Type == PRApply
 }
 
 // RepoRef returns the revision of the repository for the module source code
 // based on request type
 func (req *Request) RepoRef(module *Module) string {
 	// this is override triggered by user
-	if req.Type == PRPlan {
+	if req.IsPRRun() {
+		if req.PR.HeadCommit != "" {
+			return req.PR.HeadCommit
+		}
 		return req.PR.HeadBranch
 	}
 
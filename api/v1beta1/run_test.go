@@ -2,8 +2,10 @@ package v1beta1_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestRequest_IsApply(t *testing.T) {
@@ -12,6 +14,7 @@ func TestRequest_IsApply(t *testing.T) {
 		requestType   string
 		specPlanOnly  *bool
 		specAutoApply *bool
+		allowPRApply  bool
 		expected      bool
 	}{
 		{
@@ -63,6 +66,26 @@ func TestRequest_IsApply(t *testing.T) {
 			specPlanOnly:  new(false),
 			specAutoApply: new(true),
 			expected:      false,
+		}, {
+			name:          "PR Apply: Should be Plan if module doesn't allow PR apply",
+			requestType:   v1beta1.PRApply,
+			specPlanOnly:  new(false),
+			specAutoApply: new(true),
+			expected:      false,
+		}, {
+			name:          "PR Apply: Should Apply if module allows PR apply",
+			requestType:   v1beta1.PRApply,
+			specPlanOnly:  new(false),
+			specAutoApply: new(false),
+			allowPRApply:  true,
+			expected:      true,
+		}, {
+			name:          "PR Apply: Global Lock should be downgraded to Plan",
+			requestType:   v1beta1.PRApply,
+			specPlanOnly:  new(true),
+			specAutoApply: new(true),
+			allowPRApply:  true,
+			expected:      false,
 		}, {
 			name:          "Unknown Type: Should default to Plan",
 			requestType:   "UnknownType",
@@ -75,8 +98,9 @@ func TestRequest_IsApply(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			module := &v1beta1.Module{
 				Spec: v1beta1.ModuleSpec{
-					PlanOnly:  tt.specPlanOnly,
-					AutoApply: tt.specAutoApply,
+					PlanOnly:     tt.specPlanOnly,
+					AutoApply:    tt.specAutoApply,
+					AllowPRApply: tt.allowPRApply,
 				},
 			}
 			req := &v1beta1.Request{Type: tt.requestType}
@@ -89,3 +113,90 @@ func TestRequest_IsApply(t *testing.T) {
 		})
 	}
 }
+
+func TestRequest_IsApply_ApplyWindows(t *testing.T) {
+	// window open Mon-Thu 09:00 to 17:00 UTC
+	windows := []v1beta1.ApplyWindow{
+		{Schedule: "CRON_TZ=UTC 0 9 * * 1-4", Duration: metav1.Duration{Duration: 8 * time.Hour}},
+	}
+	// 2024-01-01 is Monday
+	inWindow := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	atOpen := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	atClose := time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)
+	afterHours := time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)
+	friday := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		requestType  string
+		requestedAt  time.Time
+		breakGlass   bool
+		expected     bool
+		wantDeferred bool
+	}{
+		{"ScheduledRun in window", v1beta1.ScheduledRun, inWindow, false, true, false},
+		{"PollingRun at window open", v1beta1.PollingRun, atOpen, false, true, false},
+		{"PollingRun at window close", v1beta1.PollingRun, atClose, false, false, true},
+		{"PollingRun after hours", v1beta1.PollingRun, afterHours, false, false, true},
+		{"ScheduledRun on friday", v1beta1.ScheduledRun, friday, false, false, true},
+		{"ScheduledRun break glass is ignored", v1beta1.ScheduledRun, friday, true, false, true},
+		{"ForcedApply in window", v1beta1.ForcedApply, inWindow, false, true, false},
+		{"ForcedApply outside window", v1beta1.ForcedApply, afterHours, false, false, false},
+		{"ForcedApply outside window with break glass", v1beta1.ForcedApply, afterHours, true, true, false},
+		{"ForcedPlan in window", v1beta1.ForcedPlan, inWindow, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &v1beta1.Module{
+				Spec: v1beta1.ModuleSpec{
+					PlanOnly:     new(false),
+					AutoApply:    new(true),
+					ApplyWindows: windows,
+				},
+			}
+			req := &v1beta1.Request{
+				Type:        tt.requestType,
+				RequestedAt: &metav1.Time{Time: tt.requestedAt},
+				BreakGlass:  tt.breakGlass,
+			}
+
+			if got := req.IsApply(module); got != tt.expected {
+				t.Errorf("IsApply() expected %v, got %v", tt.expected, got)
+			}
+			if got := req.IsApplyDeferred(module); got != tt.wantDeferred {
+				t.Errorf("IsApplyDeferred() expected %v, got %v", tt.wantDeferred, got)
+			}
+		})
+	}
+}
+
+func TestModule_NextApplyWindow(t *testing.T) {
+	module := &v1beta1.Module{
+		Spec: v1beta1.ModuleSpec{
+			ApplyWindows: []v1beta1.ApplyWindow{
+				{Schedule: "CRON_TZ=UTC 0 9 * * 1-4", Duration: metav1.Duration{Duration: 8 * time.Hour}},
+				{Schedule: "CRON_TZ=UTC 0 10 * * 5", Duration: metav1.Duration{Duration: 2 * time.Hour}},
+				{Schedule: "invalid", Duration: metav1.Duration{Duration: 2 * time.Hour}},
+			},
+		},
+	}
+
+	// thursday evening
+	now := time.Date(2024, 1, 4, 18, 0, 0, 0, time.UTC)
+	want := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	if got := module.NextApplyWindow(now); !got.Equal(want) {
+		t.Errorf("NextApplyWindow() expected %v, got %v", want, got)
+	}
+
+	if module.IsApplyAllowedAt(now) {
+		t.Errorf("IsApplyAllowedAt() expected false for %v", now)
+	}
+	if !module.IsApplyAllowedAt(want.Add(time.Hour)) {
+		t.Errorf("IsApplyAllowedAt() expected true for %v", want.Add(time.Hour))
+	}
+
+	module.Spec.ApplyWindows = nil
+	if !module.IsApplyAllowedAt(now) {
+		t.Errorf("IsApplyAllowedAt() expected true without windows")
+	}
+}
@@ -0,0 +1,189 @@
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+)
+
+// typePrefix is the reverse DNS prefix of all the event types
+const typePrefix = "systems.uw.terraform-applier."
+
+const (
+	TypeRunStarted    = typePrefix + "run.started"
+	TypeRunPlanned    = typePrefix + "run.planned"
+	TypeRunApplied    = typePrefix + "run.applied"
+	TypeRunFailed     = typePrefix + "run.failed"
+	TypeDriftDetected = typePrefix + "drift.detected"
+)
+
+const (
+	defaultBufferSize   = 1000
+	defaultMaxRetries   = 5
+	defaultRetryBackoff = time.Second
+)
+
+// Data is the payload of the run lifecycle events. run outputs are not
+// included as they can be large and might contain sensitive values
+type Data struct {
+	Cluster string           `json:"cluster"`
+	Reason  string           `json:"reason,omitempty"`
+	Message string           `json:"message,omitempty"`
+	Run     tfaplv1beta1.Run `json:"run"`
+}
+
+type event struct {
+	id        string
+	eventType string
+	subject   string
+	time      time.Time
+	data      []byte
+}
+
+// Emitter sends run lifecycle events to the sink as CloudEvents in HTTP
+// binary content mode. events are buffered in memory and sent in order by
+// a single worker with retries. if buffer is full new events are dropped.
+// Emitter is optional and nil Emitter is no-op.
+type Emitter struct {
+	SinkURL string
+	// Source is the CloudEvents source attribute
+	Source  string
+	Cluster string
+	HTTP    *http.Client
+	Log     *slog.Logger
+	// BufferSize is the max number of events waiting to be sent
+	BufferSize int
+	// MaxRetries is the number of retries of the failed event, retries are
+	// delayed with exponential backoff starting from RetryBackoff
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	buffer chan *event
+}
+
+// Start sends buffered events until context is done
+func (e *Emitter) Start(ctx context.Context) {
+	e.Log.Info("starting cloudevents emitter", "sink", e.SinkURL)
+	defer e.Log.Info("stopping cloudevents emitter")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-e.buffer:
+			e.sendWithRetry(ctx, ev)
+		}
+	}
+}
+
+// Init sets defaults and initialises buffer, it must be called before
+// emitting events
+func (e *Emitter) Init() {
+	if e.BufferSize == 0 {
+		e.BufferSize = defaultBufferSize
+	}
+	if e.MaxRetries == 0 {
+		e.MaxRetries = defaultMaxRetries
+	}
+	if e.RetryBackoff == 0 {
+		e.RetryBackoff = defaultRetryBackoff
+	}
+	if e.HTTP == nil {
+		e.HTTP = &http.Client{Timeout: 15 * time.Second}
+	}
+	e.buffer = make(chan *event, e.BufferSize)
+}
+
+// Emit adds event of the given type for the run to the buffer
+func (e *Emitter) Emit(eventType string, run *tfaplv1beta1.Run, reason, msg string) {
+	if e == nil || e.buffer == nil {
+		return
+	}
+
+	r := *run
+	r.Output = ""
+	r.InitOutput = ""
+
+	data, err := json.Marshal(Data{Cluster: e.Cluster, Reason: reason, Message: msg, Run: r})
+	if err != nil {
+		e.Log.Error("unable to marshal event data", "module", run.Module, "type", eventType, "err", err)
+		return
+	}
+
+	ev := &event{
+		id:        uuid.NewString(),
+		eventType: eventType,
+		subject:   run.Module.String(),
+		time:      time.Now(),
+		data:      data,
+	}
+
+	select {
+	case e.buffer <- ev:
+	default:
+		e.Log.Error("cloudevents buffer is full, dropping event", "module", run.Module, "type", eventType)
+	}
+}
+
+func (e *Emitter) sendWithRetry(ctx context.Context, ev *event) {
+	backoff := e.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := e.send(ctx, ev)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= e.MaxRetries {
+			e.Log.Error("unable to send event", "type", ev.eventType, "subject", ev.subject, "attempts", attempt+1, "err", err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send posts event to the sink and returns if failed request can be retried
+func (e *Emitter) send(ctx context.Context, ev *event) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.SinkURL, bytes.NewReader(ev.data))
+	if err != nil {
+		return false, fmt.Errorf("unable to create request err:%w", err)
+	}
+
+	// https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/http-protocol-binding.md#31-binary-content-mode
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("ce-specversion", "1.0")
+	req.Header.Set("ce-id", ev.id)
+	req.Header.Set("ce-source", e.Source)
+	req.Header.Set("ce-type", ev.eventType)
+	req.Header.Set("ce-subject", ev.subject)
+	req.Header.Set("ce-time", ev.time.UTC().Format(time.RFC3339Nano))
+
+	resp, err := e.HTTP.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("unable to send request err:%w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("non-ok status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+
+	// only server errors and throttled requests are retried
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
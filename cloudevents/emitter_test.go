@@ -0,0 +1,144 @@
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+func testRun() *tfaplv1beta1.Run {
+	return &tfaplv1beta1.Run{
+		Module:     types.NamespacedName{Namespace: "foo", Name: "admins"},
+		Request:    &tfaplv1beta1.Request{Type: tfaplv1beta1.ScheduledRun},
+		Status:     tfaplv1beta1.StatusOk,
+		Mode:       tfaplv1beta1.ModeApply,
+		CommitHash: "abc123",
+		Summary:    "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.",
+		InitOutput: "init output",
+		Output:     "apply output",
+	}
+}
+
+func TestEmitter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls atomic.Int32
+	events := make(chan received, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fail 1st request to verify retry
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		events <- received{r.Header, body}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	e := &Emitter{
+		SinkURL:      server.URL,
+		Source:       "terraform-applier/prod",
+		Cluster:      "prod",
+		HTTP:         server.Client(),
+		Log:          slog.Default(),
+		RetryBackoff: time.Millisecond,
+	}
+	e.Init()
+	go e.Start(ctx)
+
+	run := testRun()
+	e.Emit(TypeRunApplied, run, tfaplv1beta1.ReasonApplied, "applied")
+
+	var got received
+	select {
+	case got = <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not received")
+	}
+
+	for h, want := range map[string]string{
+		"Content-Type":   "application/json",
+		"ce-specversion": "1.0",
+		"ce-source":      "terraform-applier/prod",
+		"ce-type":        "systems.uw.terraform-applier.run.applied",
+		"ce-subject":     "foo/admins",
+	} {
+		if v := got.header.Get(h); v != want {
+			t.Errorf("header %s = %q, want %q", h, v, want)
+		}
+	}
+	if got.header.Get("ce-id") == "" {
+		t.Errorf("ce-id header is not set")
+	}
+	if _, err := time.Parse(time.RFC3339Nano, got.header.Get("ce-time")); err != nil {
+		t.Errorf("invalid ce-time header err:%s", err)
+	}
+
+	var data Data
+	if err := json.Unmarshal(got.body, &data); err != nil {
+		t.Fatalf("unable to decode data err:%s", err)
+	}
+	wantRun := testRun()
+	wantRun.InitOutput = ""
+	wantRun.Output = ""
+	want := Data{Cluster: "prod", Reason: tfaplv1beta1.ReasonApplied, Message: "applied", Run: *wantRun}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+
+	// outputs of the original run must not be modified
+	if run.Output != "apply output" {
+		t.Errorf("run output is modified")
+	}
+}
+
+func TestEmitter_noRetry(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	e := &Emitter{SinkURL: server.URL, HTTP: server.Client(), Log: slog.Default(), RetryBackoff: time.Millisecond}
+	e.Init()
+	e.Emit(TypeRunFailed, testRun(), tfaplv1beta1.ReasonPlanFailed, "unable to plan module")
+	e.sendWithRetry(context.Background(), <-e.buffer)
+
+	if c := calls.Load(); c != 1 {
+		t.Errorf("client errors should not be retried, calls = %d", c)
+	}
+}
+
+func TestEmitter_bufferFull(t *testing.T) {
+	e := &Emitter{SinkURL: "http://localhost", Log: slog.Default(), BufferSize: 2}
+	e.Init()
+
+	for range 3 {
+		e.Emit(TypeRunStarted, testRun(), tfaplv1beta1.ReasonRunTriggered, "")
+	}
+	if l := len(e.buffer); l != 2 {
+		t.Errorf("buffer length = %d, want 2", l)
+	}
+
+	// nil emitter is no-op
+	var nilEmitter *Emitter
+	nilEmitter.Emit(TypeRunStarted, testRun(), tfaplv1beta1.ReasonRunTriggered, "")
+}
@@ -4,11 +4,15 @@ import (
 	"os"
 
 	"github.com/utilitywarehouse/git-mirror/repopool"
+	"github.com/utilitywarehouse/terraform-applier/notifier"
+	"github.com/utilitywarehouse/terraform-applier/prplanner"
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	GitMirror repopool.Config `yaml:"git_mirror"`
+	GitMirror     repopool.Config  `yaml:"git_mirror"`
+	PRPlanner     prplanner.Config `yaml:"pr_planner"`
+	Notifications notifier.Config  `yaml:"notifications"`
 }
 
 func parseConfigFile(path string) (*Config, error) {
@@ -22,6 +22,7 @@ import (
 	"log/slog"
 	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -32,6 +33,7 @@ import (
 	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
 	"github.com/utilitywarehouse/terraform-applier/git"
 	"github.com/utilitywarehouse/terraform-applier/metrics"
+	"github.com/utilitywarehouse/terraform-applier/notifier"
 	"github.com/utilitywarehouse/terraform-applier/runner"
 	"github.com/utilitywarehouse/terraform-applier/sysutil"
 	corev1 "k8s.io/api/core/v1"
@@ -39,6 +41,10 @@ import (
 
 const trace = slog.Level(-8)
 
+// depsRetryInterval is the re-queue duration used while module's dependency
+// graph is being built
+const depsRetryInterval = 15 * time.Second
+
 // ModuleReconciler reconciles a Module object
 type ModuleReconciler struct {
 	client.Client
@@ -46,11 +52,13 @@ type ModuleReconciler struct {
 	Recorder               record.EventRecorder
 	Clock                  sysutil.ClockInterface
 	Repos                  git.Repositories
+	ModuleDeps             *git.ModuleDeps
 	Log                    *slog.Logger
 	MinIntervalBetweenRuns time.Duration
 	RunStatus              *sysutil.RunStatus
 	Metrics                metrics.PrometheusInterface
 	Runner                 runner.RunnerInterface
+	Notifier               *notifier.Notifier
 }
 
 //+kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;patch
@@ -59,6 +67,7 @@ type ModuleReconciler struct {
 //+kubebuilder:rbac:groups=terraform-applier.uw.systems,resources=modules,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=terraform-applier.uw.systems,resources=modules/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=terraform-applier.uw.systems,resources=modules/finalizers,verbs=update
+//+kubebuilder:rbac:groups=terraform-applier.uw.systems,resources=moduledefaults,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -76,9 +85,15 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req reconcile.Request)
 
 	module, err := sysutil.GetModule(ctx, r.Client, req.NamespacedName)
 	if err != nil {
-		log.Error("unable to fetch terraform module", "err", err)
 		// we'll ignore not-found errors, since they can't be fixed by an immediate requeue
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		// module is deleted so remove its metrics
+		if apierrors.IsNotFound(err) {
+			log.Debug("module not found, removing metrics")
+			r.Metrics.DeleteModule(req.Name, req.Namespace)
+			return ctrl.Result{}, nil
+		}
+		log.Error("unable to fetch terraform module", "err", err)
+		return ctrl.Result{}, err
 	}
 
 	// Do not requeue if module is being deleted
@@ -138,12 +153,13 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req reconcile.Request)
 	}
 
 	// case 3:
-	// check for new git hash changes on modules path
+	// check for new git hash changes on modules path and its local module dependencies
 	//
 	// git calls might be slow if repository is locked due to fetch operation.
-	// hence shorter context to re-try later
+	// hence shorter context to re-try later. dependency graph is built in
+	// background and only module path is checked until its ready
 	ctxWto, cancelWto := context.WithTimeout(ctx, 10*time.Second)
-	hash, err := r.Repos.Hash(ctxWto, module.Spec.RepoURL, module.Spec.RepoRef, module.Spec.Path)
+	hash, depsReady, err := git.ModuleHashNoWait(ctxWto, r.Repos, r.ModuleDeps, module.Spec.RepoURL, module.Spec.RepoRef, module.Spec.Path, module.Spec.WatchPaths)
 	cancelWto()
 	if err != nil {
 		msg := fmt.Sprintf("unable to reconcile: unable to get current hash of the repo err:%s", err)
@@ -153,6 +169,13 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req reconcile.Request)
 		return ctrl.Result{RequeueAfter: pollIntervalDuration}, nil
 	}
 
+	// last run hash includes dependencies, so without the graph its not possible
+	// to tell if module path is changed or hash differs due to dependencies
+	if hash != module.Status.LastDefaultRunCommitHash && !depsReady {
+		log.Debug("module dependency graph is not ready, will check again", "lastRun", module.Status.LastDefaultRunCommitHash, "current", hash)
+		return ctrl.Result{RequeueAfter: depsRetryInterval}, nil
+	}
+
 	if hash != module.Status.LastDefaultRunCommitHash {
 		log.Debug("requesting run as revision is changed on module path", "lastRun", module.Status.LastDefaultRunCommitHash, "current", hash)
 		// use next poll internal as minimum queue duration as status change will not trigger Reconcile
@@ -161,6 +184,16 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req reconcile.Request)
 	}
 
 	// case 4:
+	// check if apply was deferred due to apply windows and window is now open
+	//
+	if module.Status.StateReason == tfaplv1beta1.ReasonApplyDeferred && module.IsApplyAllowedAt(r.Clock.Now()) {
+		log.Debug("requesting deferred apply run as apply window is open")
+		// use next poll internal as minimum queue duration as status change will not trigger Reconcile
+		r.triggerRun(ctx, module, module.NewRunRequest(tfaplv1beta1.PollingRun, ""))
+		return ctrl.Result{RequeueAfter: pollIntervalDuration}, nil
+	}
+
+	// case 5:
 	// check if schedule run required
 	//
 
@@ -304,8 +337,10 @@ func (r *ModuleReconciler) setFailedStatus(req ctrl.Request, module *tfaplv1beta
 	module.Status.StateReason = reason
 	module.Status.LastDefaultRunStartedAt = nil
 	module.Status.ObservedGeneration = module.Generation
+	module.SetFailedConditions(reason, msg)
 
 	r.Recorder.Event(module, corev1.EventTypeWarning, reason, msg)
+	r.Notifier.Notify(module, nil, tfaplv1beta1.NotificationEventFailed, reason, msg)
 
 	if err := sysutil.PatchModuleStatus(context.Background(), r.Client, req.NamespacedName, module.Status); err != nil {
 		r.Log.With("module", req).Error("unable to set failed status", "err", err)
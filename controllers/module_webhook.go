@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/git"
+)
+
+// envVarNameRegex is the same regex used by kubernetes to validate C_IDENTIFIER
+var envVarNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ModuleWebhook implements validating and defaulting admission webhook for Module
+type ModuleWebhook struct {
+	Repos                  git.Repositories
+	MinIntervalBetweenRuns time.Duration
+	// NotificationSinks are the names of the sinks configured on the controller
+	NotificationSinks []string
+	Log               *slog.Logger
+}
+
+//+kubebuilder:webhook:path=/mutate-terraform-applier-uw-systems-v1beta1-module,mutating=true,failurePolicy=fail,sideEffects=None,groups=terraform-applier.uw.systems,resources=modules,verbs=create;update,versions=v1beta1,name=mmodule.terraform-applier.uw.systems,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-terraform-applier-uw-systems-v1beta1-module,mutating=false,failurePolicy=fail,sideEffects=None,groups=terraform-applier.uw.systems,resources=modules,verbs=create;update,versions=v1beta1,name=vmodule.terraform-applier.uw.systems,admissionReviewVersions=v1
+
+// SetupWithManager registers the webhooks with the Manager.
+func (w *ModuleWebhook) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &tfaplv1beta1.Module{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// Default normalises module spec
+func (w *ModuleWebhook) Default(ctx context.Context, module *tfaplv1beta1.Module) error {
+	module.Spec.Schedule = strings.TrimSpace(module.Spec.Schedule)
+	module.Spec.RepoURL = strings.TrimSpace(module.Spec.RepoURL)
+
+	if module.Spec.RepoRef == "" {
+		module.Spec.RepoRef = "HEAD"
+	}
+
+	// path is always relative to the repository root, paths escaping the
+	// repository are not rewritten here so that validation can reject them
+	if module.Spec.Path != "" {
+		module.Spec.Path = path.Clean(module.Spec.Path)
+	}
+
+	return nil
+}
+
+func (w *ModuleWebhook) ValidateCreate(ctx context.Context, module *tfaplv1beta1.Module) (admission.Warnings, error) {
+	return w.validate(ctx, module)
+}
+
+func (w *ModuleWebhook) ValidateUpdate(ctx context.Context, oldModule, module *tfaplv1beta1.Module) (admission.Warnings, error) {
+	// do not block metadata updates like run requests if spec is not changed
+	if equality.Semantic.DeepEqual(oldModule.Spec, module.Spec) {
+		return nil, nil
+	}
+	return w.validate(ctx, module)
+}
+
+func (w *ModuleWebhook) ValidateDelete(ctx context.Context, module *tfaplv1beta1.Module) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (w *ModuleWebhook) validate(ctx context.Context, module *tfaplv1beta1.Module) (admission.Warnings, error) {
+	var warnings admission.Warnings
+	var errs field.ErrorList
+
+	specPath := field.NewPath("spec")
+
+	if module.Spec.Schedule != "" {
+		// validate schedule with same rules used by reconciler, last run is set
+		// to now to avoid counting missed runs from the beginning of time
+		m := module.DeepCopy()
+		now := time.Now()
+		m.Status.LastDefaultRunStartedAt = nil
+		m.CreationTimestamp.Time = now
+		if _, _, err := NextSchedule(m, now, w.MinIntervalBetweenRuns); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("schedule"), module.Spec.Schedule, err.Error()))
+		}
+	}
+
+	for i, aw := range module.Spec.ApplyWindows {
+		p := specPath.Child("applyWindows").Index(i)
+		if _, err := cron.ParseStandard(aw.Schedule); err != nil {
+			errs = append(errs, field.Invalid(p.Child("schedule"), aw.Schedule, err.Error()))
+		}
+		if aw.Duration.Duration <= 0 {
+			errs = append(errs, field.Invalid(p.Child("duration"), aw.Duration.String(), "duration must be greater than 0"))
+		}
+	}
+
+	for i, wp := range module.Spec.WatchPaths {
+		p := specPath.Child("watchPaths").Index(i)
+		switch {
+		case wp == "" || path.IsAbs(wp):
+			errs = append(errs, field.Invalid(p, wp, "path must be relative to the repository root"))
+		case slices.Contains(strings.Split(wp, "/"), ".."):
+			errs = append(errs, field.Invalid(p, wp, "path must not contain '..'"))
+		default:
+			if _, err := path.Match(wp, ""); err != nil {
+				errs = append(errs, field.Invalid(p, wp, err.Error()))
+			}
+		}
+	}
+
+	seenSinks := make(map[string]bool)
+	for i, n := range module.Spec.Notifications {
+		p := specPath.Child("notifications").Index(i).Child("sink")
+		switch {
+		case seenSinks[n.Sink]:
+			errs = append(errs, field.Duplicate(p, n.Sink))
+		case !slices.Contains(w.NotificationSinks, n.Sink):
+			// sink might be configured on other controllers, do not block the request
+			warnings = append(warnings, fmt.Sprintf("notification sink %q is not configured on the controller", n.Sink))
+		}
+		seenSinks[n.Sink] = true
+	}
+
+	errs = append(errs, validateEnvVars(specPath.Child("backend"), module.Spec.Backend, false)...)
+	errs = append(errs, validateEnvVars(specPath.Child("env"), module.Spec.Env, true)...)
+	errs = append(errs, validateEnvVars(specPath.Child("var"), module.Spec.Var, false)...)
+	errs = append(errs, validateRBAC(specPath.Child("rbac"), module.Spec.RBAC)...)
+
+	switch {
+	case module.Spec.Path == "":
+		errs = append(errs, field.Required(specPath.Child("path"), ""))
+	case path.IsAbs(module.Spec.Path):
+		errs = append(errs, field.Invalid(specPath.Child("path"), module.Spec.Path, "path must be relative to the repository root"))
+	case slices.Contains(strings.Split(module.Spec.Path, "/"), ".."):
+		errs = append(errs, field.Invalid(specPath.Child("path"), module.Spec.Path, "path must not contain '..'"))
+	case w.Repos != nil:
+		// git calls might be slow if repository is locked due to fetch operation.
+		ctxWto, cancelWto := context.WithTimeout(ctx, 5*time.Second)
+		hash, err := w.Repos.Hash(ctxWto, module.Spec.RepoURL, module.Spec.RepoRef, module.Spec.Path)
+		cancelWto()
+		switch {
+		case err != nil:
+			// repository might not be mirrored yet, do not block the request
+			w.Log.Warn("unable to verify module path", "module", module.NamespacedName(), "err", err)
+			warnings = append(warnings, fmt.Sprintf("unable to verify path exists at %q: %s", module.Spec.RepoRef, err))
+		case hash == "":
+			errs = append(errs, field.NotFound(specPath.Child("path"), module.Spec.Path))
+		}
+	}
+
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+
+	return warnings, apierrors.NewInvalid(
+		tfaplv1beta1.GroupVersion.WithKind("Module").GroupKind(), module.Name, errs)
+}
+
+func validateEnvVars(fldPath *field.Path, envVars []tfaplv1beta1.EnvVar, isEnv bool) field.ErrorList {
+	var errs field.ErrorList
+
+	names := make(map[string]bool)
+	for i, ev := range envVars {
+		p := fldPath.Index(i)
+
+		switch {
+		case ev.Name == "":
+			errs = append(errs, field.Required(p.Child("name"), ""))
+		case isEnv && !envVarNameRegex.MatchString(ev.Name):
+			errs = append(errs, field.Invalid(p.Child("name"), ev.Name, "must be a valid environment variable name"))
+		case names[ev.Name]:
+			errs = append(errs, field.Duplicate(p.Child("name"), ev.Name))
+		}
+		names[ev.Name] = true
+
+		if ev.ValueFrom == nil {
+			continue
+		}
+
+		if ev.Value != "" {
+			errs = append(errs, field.Invalid(p.Child("valueFrom"), "", "may not be specified when `value` is not empty"))
+			continue
+		}
+
+		src := ev.ValueFrom
+		vp := p.Child("valueFrom")
+		switch {
+		case src.ConfigMapKeyRef != nil && src.SecretKeyRef != nil:
+			errs = append(errs, field.Invalid(vp, "", "may not have more than one field specified at a time"))
+		case src.ConfigMapKeyRef != nil:
+			errs = append(errs, validateKeyRef(vp.Child("configMapKeyRef"), src.ConfigMapKeyRef.Name, src.ConfigMapKeyRef.Key)...)
+		case src.SecretKeyRef != nil:
+			errs = append(errs, validateKeyRef(vp.Child("secretKeyRef"), src.SecretKeyRef.Name, src.SecretKeyRef.Key)...)
+		default:
+			errs = append(errs, field.Invalid(vp, "", "must specify one of: `configMapKeyRef` or `secretKeyRef`"))
+		}
+	}
+
+	return errs
+}
+
+func validateKeyRef(fldPath *field.Path, name, key string) field.ErrorList {
+	var errs field.ErrorList
+	if name == "" {
+		errs = append(errs, field.Required(fldPath.Child("name"), ""))
+	}
+	if key == "" {
+		errs = append(errs, field.Required(fldPath.Child("key"), ""))
+	}
+	return errs
+}
+
+func validateRBAC(fldPath *field.Path, rbac []tfaplv1beta1.RBAC) field.ErrorList {
+	var errs field.ErrorList
+
+	for i, r := range rbac {
+		p := fldPath.Index(i)
+		if r.Role != tfaplv1beta1.RoleAdmin {
+			errs = append(errs, field.NotSupported(p.Child("role"), r.Role, []string{tfaplv1beta1.RoleAdmin}))
+		}
+		if len(r.Subjects) == 0 {
+			errs = append(errs, field.Required(p.Child("subjects"), ""))
+		}
+
+		for j, s := range r.Subjects {
+			sp := p.Child("subjects").Index(j)
+			if strings.TrimSpace(s.Name) != s.Name || s.Name == "" {
+				errs = append(errs, field.Invalid(sp.Child("name"), s.Name, "must not be empty or contain leading or trailing spaces"))
+				continue
+			}
+
+			switch s.Kind {
+			case "User":
+				if addr, err := mail.ParseAddress(s.Name); err != nil || addr.Address != s.Name {
+					errs = append(errs, field.Invalid(sp.Child("name"), s.Name, "must be a valid email address for kind User"))
+				}
+			case "Group", "GitHubUser":
+			default:
+				errs = append(errs, field.NotSupported(sp.Child("kind"), s.Kind, []string{"User", "Group", "GitHubUser"}))
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/git"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestModuleWebhook_Default(t *testing.T) {
+	w := &ModuleWebhook{}
+
+	module := &tfaplv1beta1.Module{
+		Spec: tfaplv1beta1.ModuleSpec{
+			RepoURL:  " git@github.com:org/repo.git ",
+			Path:     "./modules/hello/",
+			Schedule: " 00 */1 * * * ",
+		},
+	}
+
+	if err := w.Default(context.Background(), module); err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+
+	want := tfaplv1beta1.ModuleSpec{
+		RepoURL:  "git@github.com:org/repo.git",
+		RepoRef:  "HEAD",
+		Path:     "modules/hello",
+		Schedule: "00 */1 * * *",
+	}
+	if module.Spec.RepoURL != want.RepoURL ||
+		module.Spec.RepoRef != want.RepoRef ||
+		module.Spec.Path != want.Path ||
+		module.Spec.Schedule != want.Schedule {
+		t.Errorf("Default() got %+v, want %+v", module.Spec, want)
+	}
+
+	paths := []struct {
+		path string
+		want string
+	}{
+		{".", "."},
+		{"./", "."},
+		{"modules/../hello", "hello"},
+		// paths escaping the repository are rejected by validation
+		{"../x", "../x"},
+		{"./../x/", "../x"},
+	}
+	for _, tt := range paths {
+		module := &tfaplv1beta1.Module{Spec: tfaplv1beta1.ModuleSpec{Path: tt.path}}
+		if err := w.Default(context.Background(), module); err != nil {
+			t.Fatalf("Default() error = %v", err)
+		}
+		if module.Spec.Path != tt.want {
+			t.Errorf("Default() path %q got %q, want %q", tt.path, module.Spec.Path, tt.want)
+		}
+	}
+}
+
+func TestModuleWebhook_Validate(t *testing.T) {
+	goMockCtrl := gomock.NewController(t)
+	repos := git.NewMockRepositories(goMockCtrl)
+
+	repos.EXPECT().Hash(gomock.Any(), "git@github.com:org/repo.git", "HEAD", "modules/hello").
+		Return("abc123", nil).AnyTimes()
+	repos.EXPECT().Hash(gomock.Any(), "git@github.com:org/repo.git", "HEAD", ".").
+		Return("abc123", nil).AnyTimes()
+	repos.EXPECT().Hash(gomock.Any(), "git@github.com:org/repo.git", "HEAD", "modules/missing").
+		Return("", nil).AnyTimes()
+	repos.EXPECT().Hash(gomock.Any(), "git@github.com:org/unknown.git", "HEAD", "modules/hello").
+		Return("", fmt.Errorf("repository not found")).AnyTimes()
+
+	w := &ModuleWebhook{
+		Repos:                  repos,
+		MinIntervalBetweenRuns: time.Minute,
+		NotificationSinks:      []string{"team-slack"},
+		Log:                    slog.Default(),
+	}
+
+	validSpec := func() tfaplv1beta1.ModuleSpec {
+		return tfaplv1beta1.ModuleSpec{
+			RepoURL:  "git@github.com:org/repo.git",
+			RepoRef:  "HEAD",
+			Path:     "modules/hello",
+			Schedule: "00 */1 * * *",
+			Env: []tfaplv1beta1.EnvVar{
+				{Name: "TF_LOG", Value: "INFO"},
+				{Name: "TOKEN", ValueFrom: &tfaplv1beta1.EnvVarSource{
+					SecretKeyRef: &tfaplv1beta1.SecretKeySelector{Name: "secret", Key: "token"},
+				}},
+			},
+			RBAC: []tfaplv1beta1.RBAC{{
+				Role: "Admin",
+				Subjects: []tfaplv1beta1.Subject{
+					{Kind: "User", Name: "user@example.com"},
+					{Kind: "Group", Name: "admins"},
+				},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		modify       func(spec *tfaplv1beta1.ModuleSpec)
+		wantErr      []string
+		wantWarnings int
+	}{
+		{"valid", func(spec *tfaplv1beta1.ModuleSpec) {}, nil, 0},
+		{
+			"invalid schedule",
+			func(spec *tfaplv1beta1.ModuleSpec) { spec.Schedule = "* * *" },
+			[]string{"spec.schedule"}, 0,
+		},
+		{
+			"schedule too frequent",
+			func(spec *tfaplv1beta1.ModuleSpec) { spec.Schedule = "@every 10s" },
+			[]string{"spec.schedule", "minimum allowed interval"}, 0,
+		},
+		{
+			"path not found",
+			func(spec *tfaplv1beta1.ModuleSpec) { spec.Path = "modules/missing" },
+			[]string{"spec.path: Not found"}, 0,
+		},
+		{
+			"repository root path",
+			func(spec *tfaplv1beta1.ModuleSpec) { spec.Path = "." },
+			nil, 0,
+		},
+		{
+			"path outside repository",
+			func(spec *tfaplv1beta1.ModuleSpec) { spec.Path = "../x" },
+			[]string{"spec.path: Invalid value"}, 0,
+		},
+		{
+			"absolute path",
+			func(spec *tfaplv1beta1.ModuleSpec) { spec.Path = "/modules/hello" },
+			[]string{"spec.path: Invalid value"}, 0,
+		},
+		{
+			"unknown repo is a warning",
+			func(spec *tfaplv1beta1.ModuleSpec) { spec.RepoURL = "git@github.com:org/unknown.git" },
+			nil, 1,
+		},
+		{
+			"duplicate env",
+			func(spec *tfaplv1beta1.ModuleSpec) {
+				spec.Env = append(spec.Env, tfaplv1beta1.EnvVar{Name: "TF_LOG", Value: "DEBUG"})
+			},
+			[]string{"spec.env[2].name: Duplicate value"}, 0,
+		},
+		{
+			"invalid env name",
+			func(spec *tfaplv1beta1.ModuleSpec) {
+				spec.Env = append(spec.Env, tfaplv1beta1.EnvVar{Name: "TF-LOG", Value: "DEBUG"})
+			},
+			[]string{"spec.env[2].name: Invalid value"}, 0,
+		},
+		{
+			"value and valueFrom",
+			func(spec *tfaplv1beta1.ModuleSpec) {
+				spec.Var = []tfaplv1beta1.EnvVar{{Name: "foo", Value: "bar", ValueFrom: &tfaplv1beta1.EnvVarSource{
+					ConfigMapKeyRef: &tfaplv1beta1.ConfigMapKeySelector{Name: "cm", Key: "foo"},
+				}}}
+			},
+			[]string{"spec.var[0].valueFrom"}, 0,
+		},
+		{
+			"empty valueFrom",
+			func(spec *tfaplv1beta1.ModuleSpec) {
+				spec.Backend = []tfaplv1beta1.EnvVar{{Name: "bucket", ValueFrom: &tfaplv1beta1.EnvVarSource{}}}
+			},
+			[]string{"spec.backend[0].valueFrom"}, 0,
+		},
+		{
+			"invalid rbac user",
+			func(spec *tfaplv1beta1.ModuleSpec) {
+				spec.RBAC[0].Subjects = append(spec.RBAC[0].Subjects, tfaplv1beta1.Subject{Kind: "User", Name: "not-an-email"})
+			},
+			[]string{"spec.rbac[0].subjects[2].name"}, 0,
+		},
+		{
+			"rbac group with spaces",
+			func(spec *tfaplv1beta1.ModuleSpec) {
+				spec.RBAC[0].Subjects = append(spec.RBAC[0].Subjects, tfaplv1beta1.Subject{Kind: "Group", Name: " admins"})
+			},
+			[]string{"spec.rbac[0].subjects[2].name"}, 0,
+		},
+		{
+			"invalid apply window",
+			func(spec *tfaplv1beta1.ModuleSpec) {
+				spec.ApplyWindows = []tfaplv1beta1.ApplyWindow{{Schedule: "0 9 * * 1-4"}}
+			},
+			[]string{"spec.applyWindows[0].duration"}, 0,
+		},
+		{
+			"valid watch paths",
+			func(spec *tfaplv1beta1.ModuleSpec) {
+				spec.WatchPaths = []string{"templates", "policies/**/*.json"}
+			},
+			nil, 0,
+		},
+		{
+			"invalid watch paths",
+			func(spec *tfaplv1beta1.ModuleSpec) {
+				spec.WatchPaths = []string{"/etc/foo", "../other", "policies/[*.json"}
+			},
+			[]string{"spec.watchPaths[0]", "spec.watchPaths[1]", "spec.watchPaths[2]"}, 0,
+		},
+		{
+			"notifications",
+			func(spec *tfaplv1beta1.ModuleSpec) {
+				spec.Notifications = []tfaplv1beta1.NotificationRule{
+					{Sink: "team-slack", Events: []tfaplv1beta1.NotificationEvent{tfaplv1beta1.NotificationEventFailed}},
+					{Sink: "unknown", Events: []tfaplv1beta1.NotificationEvent{tfaplv1beta1.NotificationEventApplied}},
+					{Sink: "team-slack", Events: []tfaplv1beta1.NotificationEvent{tfaplv1beta1.NotificationEventApplied}},
+				}
+			},
+			[]string{"spec.notifications[2].sink"}, 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &tfaplv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{Name: "hello", Namespace: "default"},
+				Spec:       validSpec(),
+			}
+			tt.modify(&module.Spec)
+
+			warnings, err := w.ValidateCreate(context.Background(), module)
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("ValidateCreate() got %d warnings, want %d: %v", len(warnings), tt.wantWarnings, warnings)
+			}
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("ValidateCreate() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ValidateCreate() expected error containing %v", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ValidateCreate() error = %v, want to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
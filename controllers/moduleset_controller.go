@@ -0,0 +1,418 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/git"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+)
+
+var reInvalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ModuleSetReconciler reconciles a ModuleSet object
+type ModuleSetReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	Clock    sysutil.ClockInterface
+	Repos    git.Repositories
+	Log      *slog.Logger
+}
+
+// templateData is the data available to ModuleSet template
+type templateData struct {
+	Path   string
+	Dir    string
+	Parent string
+}
+
+//+kubebuilder:rbac:groups=terraform-applier.uw.systems,resources=modulesets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=terraform-applier.uw.systems,resources=modulesets/status,verbs=get;update;patch
+
+// Reconcile will generate Modules for all the directories matching ModuleSet's
+// glob and remove generated Modules whose directories are removed from the repository.
+func (r *ModuleSetReconciler) Reconcile(ctx context.Context, req reconcile.Request) (ctrl.Result, error) {
+	log := r.Log.With("moduleSet", req.NamespacedName)
+
+	log.Log(ctx, trace, "reconciling...")
+
+	var set tfaplv1beta1.ModuleSet
+	if err := r.Get(ctx, req.NamespacedName, &set); err != nil {
+		log.Error("unable to fetch module set", "err", err)
+		// we'll ignore not-found errors, since they can't be fixed by an immediate requeue
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// generated modules are deleted by garbage collector via owner reference
+	if !set.ObjectMeta.DeletionTimestamp.IsZero() {
+		log.Info("module set is deleting..")
+		return ctrl.Result{}, nil
+	}
+
+	pollIntervalDuration := time.Duration(set.Spec.PollInterval) * time.Second
+
+	repoURL := set.Spec.Template.Spec.RepoURL
+	repoRef := set.Spec.Template.Spec.RepoRef
+	if repoRef == "" {
+		repoRef = "HEAD"
+	}
+
+	// git calls might be slow if repository is locked due to fetch operation.
+	// hence shorter context to re-try later
+	ctxWto, cancelWto := context.WithTimeout(ctx, 10*time.Second)
+	hash, err := r.Repos.Hash(ctxWto, repoURL, repoRef, ".")
+	cancelWto()
+	if err != nil {
+		msg := fmt.Sprintf("unable to get current hash of the repo err:%s", err)
+		log.Error(msg)
+		r.setFailedStatus(ctx, &set, tfaplv1beta1.ReasonGitFailure, msg)
+		return ctrl.Result{RequeueAfter: pollIntervalDuration}, nil
+	}
+
+	// only look up directories if there are new commits or spec is changed
+	paths := set.Status.Paths
+	if hash != set.Status.LastSyncedCommitHash ||
+		set.Generation != set.Status.ObservedGeneration ||
+		set.Status.Error != "" {
+		paths, err = r.findModuleDirs(ctx, &set, repoURL, hash)
+		if err != nil {
+			msg := fmt.Sprintf("unable to find module directories err:%s", err)
+			log.Error(msg)
+			r.setFailedStatus(ctx, &set, tfaplv1beta1.ReasonGitFailure, msg)
+			return ctrl.Result{RequeueAfter: pollIntervalDuration}, nil
+		}
+	}
+
+	modules, err := renderModules(&set, paths)
+	if err != nil {
+		msg := fmt.Sprintf("unable to render modules err:%s", err)
+		log.Error(msg)
+		r.setFailedStatus(ctx, &set, tfaplv1beta1.ReasonModuleGenerateError, msg)
+		return ctrl.Result{}, nil
+	}
+
+	generated := make(map[string]bool)
+	for _, module := range modules {
+		generated[module.Name] = true
+
+		if err := r.applyModule(ctx, &set, module); err != nil {
+			msg := fmt.Sprintf("unable to apply module %s err:%s", module.Name, err)
+			log.Error(msg)
+			r.setFailedStatus(ctx, &set, tfaplv1beta1.ReasonModuleGenerateError, msg)
+			return ctrl.Result{RequeueAfter: pollIntervalDuration}, nil
+		}
+	}
+
+	if err := r.removeStaleModules(ctx, &set, generated); err != nil {
+		msg := fmt.Sprintf("unable to remove stale modules err:%s", err)
+		log.Error(msg)
+		r.setFailedStatus(ctx, &set, tfaplv1beta1.ReasonModuleGenerateError, msg)
+		return ctrl.Result{RequeueAfter: pollIntervalDuration}, nil
+	}
+
+	set.Status.ObservedGeneration = set.Generation
+	set.Status.LastSyncedCommitHash = hash
+	set.Status.LastSyncedAt = &metav1.Time{Time: r.Clock.Now()}
+	set.Status.Paths = paths
+	set.Status.Error = ""
+	if err := r.Status().Update(ctx, &set); err != nil {
+		log.Error("unable to update status", "err", err)
+	}
+
+	return ctrl.Result{RequeueAfter: pollIntervalDuration}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ModuleSetReconciler) SetupWithManager(mgr ctrl.Manager, filter *Filter) error {
+	// set up a real clock
+	if r.Clock == nil {
+		r.Clock = &sysutil.Clock{}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&tfaplv1beta1.ModuleSet{}).
+		Owns(&tfaplv1beta1.Module{}).
+		WithEventFilter(predicate.And(
+			predicate.GenerationChangedPredicate{},
+			predicate.NewPredicateFuncs(filter.LabelSelectorFilter),
+		)).
+		Complete(r)
+}
+
+// findModuleDirs clones repository at given commit and returns sorted list of
+// directories matching module set's glob which contains terraform files
+func (r *ModuleSetReconciler) findModuleDirs(ctx context.Context, set *tfaplv1beta1.ModuleSet, repoURL, hash string) ([]string, error) {
+	tmpRoot, err := os.MkdirTemp("", set.Namespace+"-"+set.Name+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create tmp dir %w", err)
+	}
+	defer sysutil.RemoveAll(tmpRoot)
+
+	if _, err := r.Repos.Clone(ctx, repoURL, tmpRoot, hash, nil, true); err != nil {
+		return nil, fmt.Errorf("unable to clone repository err:%w", err)
+	}
+
+	return matchModuleDirs(tmpRoot, set.Spec.Directories, set.Spec.Exclude)
+}
+
+// matchModuleDirs returns sorted list of directories relative to root matching
+// given glob and not matching any of the exclude globs. only directories
+// containing terraform files are returned
+func matchModuleDirs(root, pattern string, exclude []string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(root, filepath.Clean(pattern)))
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, m := range matches {
+		rel, err := filepath.Rel(root, m)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+
+		// glob can't match outside of root but pattern like '../*' can
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+
+		if slices.ContainsFunc(exclude, func(ex string) bool {
+			matched, _ := path.Match(ex, rel)
+			return matched
+		}) {
+			continue
+		}
+
+		tfFiles, err := filepath.Glob(filepath.Join(m, "*.tf"))
+		if err != nil {
+			return nil, err
+		}
+		if len(tfFiles) == 0 {
+			continue
+		}
+
+		dirs = append(dirs, rel)
+	}
+
+	slices.Sort(dirs)
+	return dirs, nil
+}
+
+// renderModule returns Module generated from module set's template for given path
+// renderModules renders module for each path, generated modules must have
+// unique names and paths as modules with same path would plan and apply
+// same root module against the same state
+func renderModules(set *tfaplv1beta1.ModuleSet, paths []string) ([]*tfaplv1beta1.Module, error) {
+	names := make(map[string]bool)
+	modulePaths := make(map[string]bool)
+
+	var modules []*tfaplv1beta1.Module
+	for _, p := range paths {
+		module, err := renderModule(set, p)
+		if err != nil {
+			return nil, fmt.Errorf("unable to render module for path %s err:%w", p, err)
+		}
+
+		if names[module.Name] {
+			return nil, fmt.Errorf("multiple paths generated same module name %q, template name must be unique for each path", module.Name)
+		}
+		names[module.Name] = true
+
+		if modulePaths[module.Spec.Path] {
+			return nil, fmt.Errorf("multiple paths generated same module path %q, template path must be unique for each path", module.Spec.Path)
+		}
+		modulePaths[module.Spec.Path] = true
+
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
+
+func renderModule(set *tfaplv1beta1.ModuleSet, dirPath string) (*tfaplv1beta1.Module, error) {
+	data := templateData{
+		Path:   dirPath,
+		Dir:    path.Base(dirPath),
+		Parent: path.Base(path.Dir(dirPath)),
+	}
+
+	tmpl := set.Spec.Template
+
+	module := &tfaplv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   set.Namespace,
+			Labels:      make(map[string]string),
+			Annotations: make(map[string]string),
+		},
+		Spec: *tmpl.Spec.DeepCopy(),
+	}
+
+	nameTmpl := tmpl.Metadata.Name
+	if nameTmpl == "" {
+		nameTmpl = "{{.Dir}}"
+	}
+	name, err := execTemplate(nameTmpl, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template err:%w", err)
+	}
+	module.Name = sanitizeName(name)
+	if module.Name == "" {
+		return nil, fmt.Errorf("generated module name is empty")
+	}
+
+	// module inherits module set's labels so that label selector set on
+	// controller also applies to generated modules
+	maps.Copy(module.Labels, set.Labels)
+	for k, v := range tmpl.Metadata.Labels {
+		if module.Labels[k], err = execTemplate(v, data); err != nil {
+			return nil, fmt.Errorf("invalid label template %s err:%w", k, err)
+		}
+	}
+	module.Labels[tfaplv1beta1.ModuleSetLabelKey] = set.Name
+
+	for k, v := range tmpl.Metadata.Annotations {
+		if module.Annotations[k], err = execTemplate(v, data); err != nil {
+			return nil, fmt.Errorf("invalid annotation template %s err:%w", k, err)
+		}
+	}
+
+	if module.Spec.Path, err = execTemplate(module.Spec.Path, data); err != nil {
+		return nil, fmt.Errorf("invalid path template err:%w", err)
+	}
+
+	if module.Spec.Schedule, err = execTemplate(module.Spec.Schedule, data); err != nil {
+		return nil, fmt.Errorf("invalid schedule template err:%w", err)
+	}
+
+	return module, nil
+}
+
+func execTemplate(text string, data templateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	t, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// sanitizeName converts given string to valid kubernetes object name
+func sanitizeName(name string) string {
+	name = reInvalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.Trim(name, "-")
+}
+
+// applyModule creates or updates given generated module
+func (r *ModuleSetReconciler) applyModule(ctx context.Context, set *tfaplv1beta1.ModuleSet, generated *tfaplv1beta1.Module) error {
+	module := &tfaplv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: generated.Namespace,
+			Name:      generated.Name,
+		},
+	}
+
+	op, err := controllerutil.CreateOrPatch(ctx, r.Client, module, func() error {
+		// do not take over existing modules which are not generated by this set
+		if !module.CreationTimestamp.IsZero() &&
+			module.Labels[tfaplv1beta1.ModuleSetLabelKey] != set.Name {
+			return fmt.Errorf("module already exists and it is not managed by this module set")
+		}
+
+		if module.Labels == nil {
+			module.Labels = make(map[string]string)
+		}
+		maps.Copy(module.Labels, generated.Labels)
+
+		if module.Annotations == nil {
+			module.Annotations = make(map[string]string)
+		}
+		maps.Copy(module.Annotations, generated.Annotations)
+
+		module.Spec = generated.Spec
+
+		return controllerutil.SetControllerReference(set, module, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	if op == controllerutil.OperationResultCreated {
+		r.Log.Info("module generated", "moduleSet", set.Name, "module", module.NamespacedName())
+		r.Recorder.Event(set, corev1.EventTypeNormal, tfaplv1beta1.ReasonModuleGenerated, "module generated: "+module.Name)
+	}
+
+	return nil
+}
+
+// removeStaleModules deletes modules generated by the module set which are
+// not in the given generated list
+func (r *ModuleSetReconciler) removeStaleModules(ctx context.Context, set *tfaplv1beta1.ModuleSet, generated map[string]bool) error {
+	var modules tfaplv1beta1.ModuleList
+	if err := r.List(ctx, &modules,
+		client.InNamespace(set.Namespace),
+		client.MatchingLabels{tfaplv1beta1.ModuleSetLabelKey: set.Name},
+	); err != nil {
+		return err
+	}
+
+	for _, m := range modules.Items {
+		if generated[m.Name] || !metav1.IsControlledBy(&m, set) {
+			continue
+		}
+
+		if err := r.Delete(ctx, &m); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		r.Log.Info("module removed as its directory no longer exists", "moduleSet", set.Name, "module", m.NamespacedName())
+		r.Recorder.Event(set, corev1.EventTypeNormal, tfaplv1beta1.ReasonModuleRemoved, "module removed: "+m.Name)
+	}
+
+	return nil
+}
+
+func (r *ModuleSetReconciler) setFailedStatus(ctx context.Context, set *tfaplv1beta1.ModuleSet, reason, msg string) {
+	set.Status.ObservedGeneration = set.Generation
+	set.Status.Error = msg
+
+	r.Recorder.Event(set, corev1.EventTypeWarning, reason, msg)
+
+	if err := r.Status().Update(ctx, set); err != nil {
+		r.Log.With("moduleSet", client.ObjectKeyFromObject(set)).Error("unable to set failed status", "err", err)
+	}
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_matchModuleDirs(t *testing.T) {
+	root := t.TempDir()
+
+	for _, f := range []string{
+		"prod/aws/vpc/main.tf",
+		"prod/aws/eks/main.tf",
+		"prod/aws/legacy/main.tf",
+		"prod/aws/docs/README.md",
+		"prod/gcp/gke/main.tf",
+		"dev/aws/vpc/main.tf",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(f)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, f), []byte(""), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		exclude []string
+		want    []string
+	}{
+		{"single dir", "prod/aws/vpc", nil, []string{"prod/aws/vpc"}},
+		{"all aws", "prod/aws/*", nil, []string{"prod/aws/eks", "prod/aws/legacy", "prod/aws/vpc"}},
+		{"exclude", "prod/aws/*", []string{"prod/aws/legacy"}, []string{"prod/aws/eks", "prod/aws/vpc"}},
+		{"all prod", "prod/*/*", []string{"*/*/legacy"}, []string{"prod/aws/eks", "prod/aws/vpc", "prod/gcp/gke"}},
+		{"all envs", "*/aws/vpc", nil, []string{"dev/aws/vpc", "prod/aws/vpc"}},
+		{"outside root", "../*", nil, nil},
+		{"no match", "staging/*", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchModuleDirs(root, tt.pattern, tt.exclude)
+			if err != nil {
+				t.Fatalf("matchModuleDirs() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("matchModuleDirs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_renderModule(t *testing.T) {
+	set := &tfaplv1beta1.ModuleSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "prod-aws",
+			Namespace: "infra",
+			Labels:    map[string]string{"team": "infra"},
+		},
+		Spec: tfaplv1beta1.ModuleSetSpec{
+			Directories: "prod/*/*",
+			Template: tfaplv1beta1.ModuleSetTemplate{
+				Metadata: tfaplv1beta1.ModuleSetTemplateMeta{
+					Name:        "{{.Parent}}_{{.Dir}}",
+					Labels:      map[string]string{"cloud": "{{.Parent}}"},
+					Annotations: map[string]string{"source": "{{.Path}}"},
+				},
+				Spec: tfaplv1beta1.ModuleSpec{
+					RepoURL:  "git@github.com:org/infra.git",
+					Path:     "{{.Path}}",
+					Schedule: "00 */1 * * *",
+				},
+			},
+		},
+	}
+
+	want := &tfaplv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "aws-vpc",
+			Namespace: "infra",
+			Labels: map[string]string{
+				"team":                         "infra",
+				"cloud":                        "aws",
+				tfaplv1beta1.ModuleSetLabelKey: "prod-aws",
+			},
+			Annotations: map[string]string{"source": "prod/aws/vpc"},
+		},
+		Spec: tfaplv1beta1.ModuleSpec{
+			RepoURL:  "git@github.com:org/infra.git",
+			Path:     "prod/aws/vpc",
+			Schedule: "00 */1 * * *",
+		},
+	}
+
+	got, err := renderModule(set, "prod/aws/vpc")
+	if err != nil {
+		t.Fatalf("renderModule() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("renderModule() mismatch (-want +got):\n%s", diff)
+	}
+
+	set.Spec.Template.Metadata.Name = "{{.Unknown}}"
+	if _, err := renderModule(set, "prod/aws/vpc"); err == nil {
+		t.Errorf("renderModule() expected error for invalid template")
+	}
+}
+
+func Test_renderModules(t *testing.T) {
+	newSet := func(name, path string) *tfaplv1beta1.ModuleSet {
+		return &tfaplv1beta1.ModuleSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "prod-aws", Namespace: "infra"},
+			Spec: tfaplv1beta1.ModuleSetSpec{
+				Template: tfaplv1beta1.ModuleSetTemplate{
+					Metadata: tfaplv1beta1.ModuleSetTemplateMeta{Name: name},
+					Spec:     tfaplv1beta1.ModuleSpec{Path: path},
+				},
+			},
+		}
+	}
+	paths := []string{"prod/aws/vpc", "prod/gcp/vpc"}
+
+	tests := []struct {
+		name      string
+		set       *tfaplv1beta1.ModuleSet
+		wantPaths []string
+		wantErr   string
+	}{
+		{"unique", newSet("{{.Parent}}-{{.Dir}}", "{{.Path}}"), paths, ""},
+		{"duplicate name", newSet("{{.Dir}}", "{{.Path}}"), nil, "same module name"},
+		{"literal path", newSet("{{.Parent}}-{{.Dir}}", "prod/aws"), nil, "same module path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderModules(tt.set, paths)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("renderModules() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderModules() error = %v", err)
+			}
+			var gotPaths []string
+			for _, m := range got {
+				gotPaths = append(gotPaths, m.Spec.Path)
+			}
+			if diff := cmp.Diff(tt.wantPaths, gotPaths); diff != "" {
+				t.Errorf("renderModules() paths mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
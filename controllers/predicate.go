@@ -6,6 +6,7 @@ import (
 	"log/slog"
 
 	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -16,6 +17,8 @@ type Filter struct {
 	LabelSelectorKey   string
 	LabelSelectorValue string
 	Log                *slog.Logger
+	// Metrics is used to remove series of the deleted or un-selected modules, its optional
+	Metrics metrics.PrometheusInterface
 }
 
 func (f Filter) Create(e event.CreateEvent) bool {
@@ -29,6 +32,7 @@ func (f Filter) Delete(e event.DeleteEvent) bool {
 	if !f.LabelSelectorFilter(e.Object) {
 		return false
 	}
+	f.deleteModuleMetrics(e.Object)
 	// Evaluates to false if the object has been confirmed deleted.
 	return !e.DeleteStateUnknown
 }
@@ -46,6 +50,10 @@ func (f Filter) Update(e event.UpdateEvent) bool {
 	}
 
 	if !f.LabelSelectorFilter(e.ObjectNew) {
+		// module is not managed by this controller anymore
+		if f.LabelSelectorFilter(e.ObjectOld) {
+			f.deleteModuleMetrics(e.ObjectNew)
+		}
 		return false
 	}
 
@@ -67,6 +75,17 @@ func (f Filter) Update(e event.UpdateEvent) bool {
 	return false
 }
 
+// deleteModuleMetrics removes metrics series of the object if its a module
+func (f Filter) deleteModuleMetrics(object client.Object) {
+	if f.Metrics == nil {
+		return
+	}
+	if _, ok := object.(*tfaplv1beta1.Module); !ok {
+		return
+	}
+	f.Metrics.DeleteModule(object.GetName(), object.GetNamespace())
+}
+
 func (f Filter) LabelSelectorFilter(object client.Object) bool {
 	// allow all if selector Labels is not set
 	if f.LabelSelectorKey == "" {
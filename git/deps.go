@@ -0,0 +1,331 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// maxCachedCommits is the number of commits for which dependency graph is cached
+	maxCachedCommits = 50
+	// graphBuildTimeout is the timeout for checking out terraform files and
+	// building dependency graph of a commit. graph is built independently
+	// of callers context as it is shared between all callers
+	graphBuildTimeout = 5 * time.Minute
+)
+
+// moduleSchema is used to only decode 'source' of module blocks
+var moduleSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "module", LabelNames: []string{"name"}}},
+}
+
+var moduleSourceSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "source"}},
+}
+
+// depGraph is the map of module dir to the local module dirs its calling
+type depGraph map[string][]string
+
+// ModuleDeps resolves local module dependencies (i.e. `source = "../modules/vpc"`)
+// of terraform root modules. Dependency graph of the whole repository is
+// built from terraform files at the given commit and cached per commit.
+// concurrent requests for the same commit share single build.
+type ModuleDeps struct {
+	Repos Repositories
+
+	mu    sync.Mutex
+	cache map[string]depGraph
+	// keys keeps cache keys in insertion order for eviction
+	keys []string
+
+	builds singleflight.Group
+}
+
+// Paths returns module path along with the paths of all its local module
+// dependencies (transitive) at the given ref. if d is nil only module path
+// is returned
+func (d *ModuleDeps) Paths(ctx context.Context, remote, ref, modulePath string) ([]string, error) {
+	if d == nil {
+		return []string{modulePath}, nil
+	}
+
+	commit, err := d.Repos.Hash(ctx, remote, ref, "")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get commit hash of ref %s err:%w", ref, err)
+	}
+
+	graph, err := d.graph(ctx, remote, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.paths(path.Clean(modulePath)), nil
+}
+
+// PathsNoWait is same as Paths but it doesn't wait for the dependency graph
+// to be built. if graph of the commit is not cached yet, build is started in
+// background and only module path is returned with ready set to false.
+func (d *ModuleDeps) PathsNoWait(ctx context.Context, remote, ref, modulePath string) (paths []string, ready bool, err error) {
+	if d == nil {
+		return []string{modulePath}, true, nil
+	}
+
+	commit, err := d.Repos.Hash(ctx, remote, ref, "")
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to get commit hash of ref %s err:%w", ref, err)
+	}
+
+	graph, ok := d.cached(remote + "@" + commit)
+	if !ok {
+		d.build(remote, commit)
+		return []string{path.Clean(modulePath)}, false, nil
+	}
+
+	return graph.paths(path.Clean(modulePath)), true, nil
+}
+
+// graph returns cached dependency graph of the commit or waits for it to
+// be built. if ctx is done before graph is ready build is not cancelled.
+func (d *ModuleDeps) graph(ctx context.Context, remote, commit string) (depGraph, error) {
+	if graph, ok := d.cached(remote + "@" + commit); ok {
+		return graph, nil
+	}
+
+	select {
+	case res := <-d.build(remote, commit):
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(depGraph), nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("dependency graph of commit %s is not ready err:%w", commit, ctx.Err())
+	}
+}
+
+func (d *ModuleDeps) cached(key string) (depGraph, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	graph, ok := d.cache[key]
+	return graph, ok
+}
+
+// build starts building dependency graph of the commit unless its already
+// in progress and returns channel which will receive the result
+func (d *ModuleDeps) build(remote, commit string) <-chan singleflight.Result {
+	key := remote + "@" + commit
+
+	return d.builds.DoChan(key, func() (any, error) {
+		// graph might have been cached by the build which just finished
+		if graph, ok := d.cached(key); ok {
+			return graph, nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), graphBuildTimeout)
+		defer cancel()
+
+		graph, err := d.buildGraph(ctx, remote, commit)
+		if err != nil {
+			return nil, err
+		}
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		if d.cache == nil {
+			d.cache = make(map[string]depGraph)
+		}
+		if _, ok := d.cache[key]; !ok {
+			d.keys = append(d.keys, key)
+		}
+		d.cache[key] = graph
+
+		if len(d.keys) > maxCachedCommits {
+			delete(d.cache, d.keys[0])
+			d.keys = d.keys[1:]
+		}
+
+		return graph, nil
+	})
+}
+
+func (d *ModuleDeps) buildGraph(ctx context.Context, remote, commit string) (depGraph, error) {
+	dst, err := os.MkdirTemp("", "tf-deps-")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temp dir err:%w", err)
+	}
+	defer os.RemoveAll(dst)
+
+	// only terraform files are required to build graph
+	if _, err := d.Repos.Clone(ctx, remote, dst, commit, []string{"*.tf", "*.tf.json"}, true); err != nil {
+		return nil, fmt.Errorf("unable to checkout terraform files err:%w", err)
+	}
+
+	return buildDepGraph(dst)
+}
+
+// paths returns given module dir and all its transitive local dependencies
+func (g depGraph) paths(dir string) []string {
+	paths := []string{dir}
+	for i := 0; i < len(paths); i++ {
+		for _, dep := range g[paths[i]] {
+			if !slices.Contains(paths, dep) {
+				paths = append(paths, dep)
+			}
+		}
+	}
+	return paths
+}
+
+// buildDepGraph parses all terraform files under root dir and returns
+// local module dependencies of each dir. paths are relative to root
+func buildDepGraph(root string) (depGraph, error) {
+	parser := hclparse.NewParser()
+	graph := make(depGraph)
+
+	err := filepath.WalkDir(root, func(p string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			if de.Name() == ".git" || de.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		switch {
+		case strings.HasSuffix(p, ".tf"):
+			file, diags = parser.ParseHCLFile(p)
+		case strings.HasSuffix(p, ".tf.json"):
+			file, diags = parser.ParseJSONFile(p)
+		default:
+			return nil
+		}
+		// invalid files are ignored as terraform run will report errors
+		if diags.HasErrors() || file == nil {
+			return nil
+		}
+
+		relDir, err := filepath.Rel(root, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		relDir = filepath.ToSlash(relDir)
+
+		for _, src := range localModuleSources(file.Body) {
+			dep := path.Join(relDir, src)
+			// ignore modules outside of the repository
+			if dep == ".." || strings.HasPrefix(dep, "../") {
+				continue
+			}
+			if !slices.Contains(graph[relDir], dep) {
+				graph[relDir] = append(graph[relDir], dep)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse terraform files err:%w", err)
+	}
+
+	return graph, nil
+}
+
+// localModuleSources returns the 'source' of all module blocks which refers
+// to local path
+func localModuleSources(body hcl.Body) []string {
+	content, _, _ := body.PartialContent(moduleSchema)
+	if content == nil {
+		return nil
+	}
+
+	var sources []string
+	for _, block := range content.Blocks {
+		attrs, _, _ := block.Body.PartialContent(moduleSourceSchema)
+		if attrs == nil {
+			continue
+		}
+		attr, ok := attrs.Attributes["source"]
+		if !ok {
+			continue
+		}
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || val.IsNull() || !val.IsKnown() || val.Type() != cty.String {
+			continue
+		}
+		src := val.AsString()
+		if strings.HasPrefix(src, "./") || strings.HasPrefix(src, "../") {
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+// ModuleHash returns hash of the latest commit on the ref which updated module
+// path, any of its local module dependencies or watch paths
+func ModuleHash(ctx context.Context, repos Repositories, deps *ModuleDeps, remote, ref, modulePath string, watchPaths []string) (string, error) {
+	paths, err := deps.Paths(ctx, remote, ref, modulePath)
+	if err != nil {
+		return "", err
+	}
+	return LatestHash(ctx, repos, remote, ref, append(paths, watchPaths...))
+}
+
+// ModuleHashNoWait is same as ModuleHash but it doesn't wait for dependency
+// graph to be built. if graph is not ready hash is calculated only using
+// module path and watch paths and depsReady is set to false.
+func ModuleHashNoWait(ctx context.Context, repos Repositories, deps *ModuleDeps, remote, ref, modulePath string, watchPaths []string) (hash string, depsReady bool, err error) {
+	paths, depsReady, err := deps.PathsNoWait(ctx, remote, ref, modulePath)
+	if err != nil {
+		return "", false, err
+	}
+	hash, err = LatestHash(ctx, repos, remote, ref, append(paths, watchPaths...))
+	return hash, depsReady, err
+}
+
+// LatestHash returns hash of the latest commit on the ref which updated any
+// of the given paths, paths can be glob patterns. paths are expected to be
+// updated on the same branch, so commit which already contains the change
+// of other path is the latest
+func LatestHash(ctx context.Context, repos Repositories, remote, ref string, paths []string) (string, error) {
+	var latest string
+	for _, p := range paths {
+		p = pathspec(p)
+		hash, err := repos.Hash(ctx, remote, ref, p)
+		if err != nil {
+			return "", err
+		}
+		if hash == "" || hash == latest {
+			continue
+		}
+		if latest == "" {
+			latest = hash
+			continue
+		}
+		// if latest commit already includes this path's last change, then
+		// last change of this path as of latest commit will be same hash
+		h, err := repos.Hash(ctx, remote, latest, p)
+		if err != nil {
+			return "", err
+		}
+		if h != hash {
+			latest = hash
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,279 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_buildDepGraph(t *testing.T) {
+	root := t.TempDir()
+
+	writeFiles(t, root, map[string]string{
+		"dev/app/main.tf": `
+module "vpc" {
+  source = "../../modules/vpc"
+}
+module "db" {
+  source = "./db"
+}
+module "remote" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+}
+module "outside" {
+  source = "../../../other-repo/mod"
+}`,
+		"dev/app/db/main.tf": `
+module "common" {
+  source = "../../../modules/common/"
+}`,
+		"modules/vpc/main.tf": `
+module "subnets" {
+  source = "../subnets"
+}
+resource "null_resource" "foo" {}`,
+		"modules/vpc/variables.tf.json": `{"module": {"common": {"source": "../common"}}}`,
+		"modules/subnets/main.tf":       `resource "null_resource" "foo" {}`,
+		"modules/common/main.tf":        `resource "null_resource" "foo" {}`,
+		"prod/invalid/main.tf":          `module "vpc" {`,
+		"prod/app/main.tf": `
+module "vpc" {
+  source = "../../modules/vpc"
+}`,
+	})
+
+	graph, err := buildDepGraph(root)
+	if err != nil {
+		t.Fatalf("buildDepGraph() error = %v", err)
+	}
+
+	wantGraph := depGraph{
+		"dev/app":     {"modules/vpc", "dev/app/db"},
+		"dev/app/db":  {"modules/common"},
+		"modules/vpc": {"modules/subnets", "modules/common"},
+		"prod/app":    {"modules/vpc"},
+	}
+	sortStrings := cmp.Transformer("sort", func(in []string) map[string]bool {
+		out := make(map[string]bool)
+		for _, s := range in {
+			out[s] = true
+		}
+		return out
+	})
+	if diff := cmp.Diff(wantGraph, graph, sortStrings); diff != "" {
+		t.Errorf("buildDepGraph() mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{"dev/app", []string{"dev/app", "modules/vpc", "dev/app/db", "modules/subnets", "modules/common"}},
+		{"prod/app", []string{"prod/app", "modules/vpc", "modules/subnets", "modules/common"}},
+		{"modules/common", []string{"modules/common"}},
+		{"unknown", []string{"unknown"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, graph.paths(tt.dir), sortStrings); diff != "" {
+				t.Errorf("paths() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestModuleDeps_Paths(t *testing.T) {
+	ctx := context.Background()
+	goMockCtrl := gomock.NewController(t)
+	repos := NewMockRepositories(goMockCtrl)
+
+	remote := "https://host.xy/dummy/repo.git"
+
+	deps := &ModuleDeps{Repos: repos}
+
+	repos.EXPECT().Hash(gomock.Any(), remote, "main", "").Return("commit1", nil).Times(2)
+	// terraform files should only be checked out once per commit
+	repos.EXPECT().Clone(gomock.Any(), remote, gomock.Any(), "commit1", []string{"*.tf", "*.tf.json"}, true).
+		DoAndReturn(func(_ context.Context, _, dst, _ string, _ []string, _ bool) (string, error) {
+			writeFiles(t, dst, map[string]string{
+				"app/main.tf":         `module "vpc" { source = "../modules/vpc" }`,
+				"modules/vpc/main.tf": `resource "null_resource" "foo" {}`,
+			})
+			return "commit1", nil
+		})
+
+	for range 2 {
+		got, err := deps.Paths(ctx, remote, "main", "app/")
+		if err != nil {
+			t.Fatalf("Paths() error = %v", err)
+		}
+		if diff := cmp.Diff([]string{"app", "modules/vpc"}, got); diff != "" {
+			t.Errorf("Paths() mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	// nil deps should only return module path
+	var nilDeps *ModuleDeps
+	got, err := nilDeps.Paths(ctx, remote, "main", "app")
+	if err != nil {
+		t.Fatalf("Paths() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"app"}, got); diff != "" {
+		t.Errorf("Paths() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestModuleDeps_PathsNoWait(t *testing.T) {
+	ctx := context.Background()
+	goMockCtrl := gomock.NewController(t)
+	repos := NewMockRepositories(goMockCtrl)
+
+	remote := "https://host.xy/dummy/repo.git"
+
+	deps := &ModuleDeps{Repos: repos}
+
+	release := make(chan struct{})
+	repos.EXPECT().Hash(gomock.Any(), remote, "main", "").Return("commit1", nil).AnyTimes()
+	// concurrent callers must share single checkout, build should not be
+	// cancelled with the callers context
+	repos.EXPECT().Clone(gomock.Any(), remote, gomock.Any(), "commit1", []string{"*.tf", "*.tf.json"}, true).
+		DoAndReturn(func(ctx context.Context, _, dst, _ string, _ []string, _ bool) (string, error) {
+			<-release
+			if ctx.Err() != nil {
+				t.Errorf("graph build context is done err:%v", ctx.Err())
+			}
+			writeFiles(t, dst, map[string]string{
+				"app/main.tf":         `module "vpc" { source = "../modules/vpc" }`,
+				"modules/vpc/main.tf": `resource "null_resource" "foo" {}`,
+			})
+			return "commit1", nil
+		})
+
+	// graph is not ready, only module path is returned
+	got, ready, err := deps.PathsNoWait(ctx, remote, "main", "app/")
+	if err != nil {
+		t.Fatalf("PathsNoWait() error = %v", err)
+	}
+	if ready {
+		t.Errorf("PathsNoWait() ready = true, want false")
+	}
+	if diff := cmp.Diff([]string{"app"}, got); diff != "" {
+		t.Errorf("PathsNoWait() mismatch (-want +got):\n%s", diff)
+	}
+
+	// caller with short timeout should not wait for the build
+	ctxWto, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := deps.Paths(ctxWto, remote, "main", "app"); err == nil {
+		t.Errorf("Paths() expected error when graph is not ready")
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := deps.Paths(ctx, remote, "main", "app")
+			if err != nil {
+				t.Errorf("Paths() error = %v", err)
+				return
+			}
+			if diff := cmp.Diff([]string{"app", "modules/vpc"}, got); diff != "" {
+				t.Errorf("Paths() mismatch (-want +got):\n%s", diff)
+			}
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	got, ready, err = deps.PathsNoWait(ctx, remote, "main", "app")
+	if err != nil {
+		t.Fatalf("PathsNoWait() error = %v", err)
+	}
+	if !ready {
+		t.Errorf("PathsNoWait() ready = false, want true")
+	}
+	if diff := cmp.Diff([]string{"app", "modules/vpc"}, got); diff != "" {
+		t.Errorf("PathsNoWait() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLatestHash(t *testing.T) {
+	ctx := context.Background()
+	remote := "https://host.xy/dummy/repo.git"
+
+	tests := []struct {
+		name  string
+		paths []string
+		setup func(repos *MockRepositories)
+		want  string
+	}{
+		{
+			name:  "single path",
+			paths: []string{"app"},
+			setup: func(repos *MockRepositories) {
+				repos.EXPECT().Hash(gomock.Any(), remote, "main", "app").Return("c1", nil)
+			},
+			want: "c1",
+		},
+		{
+			name:  "dependency updated after module",
+			paths: []string{"app", "modules/vpc", "modules/common"},
+			setup: func(repos *MockRepositories) {
+				repos.EXPECT().Hash(gomock.Any(), remote, "main", "app").Return("c1", nil)
+				repos.EXPECT().Hash(gomock.Any(), remote, "main", "modules/vpc").Return("c2", nil)
+				// c2 is not in history of c1 so last change of dep as of c1 is different
+				repos.EXPECT().Hash(gomock.Any(), remote, "c1", "modules/vpc").Return("c0", nil)
+				repos.EXPECT().Hash(gomock.Any(), remote, "main", "modules/common").Return("c0", nil)
+				repos.EXPECT().Hash(gomock.Any(), remote, "c2", "modules/common").Return("c0", nil)
+			},
+			want: "c2",
+		},
+		{
+			name:  "module updated after dependency",
+			paths: []string{"app", "modules/vpc", "modules/common"},
+			setup: func(repos *MockRepositories) {
+				repos.EXPECT().Hash(gomock.Any(), remote, "main", "app").Return("c3", nil)
+				repos.EXPECT().Hash(gomock.Any(), remote, "main", "modules/vpc").Return("c2", nil)
+				repos.EXPECT().Hash(gomock.Any(), remote, "c3", "modules/vpc").Return("c2", nil)
+				repos.EXPECT().Hash(gomock.Any(), remote, "main", "modules/common").Return("c3", nil)
+			},
+			want: "c3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goMockCtrl := gomock.NewController(t)
+			repos := NewMockRepositories(goMockCtrl)
+			tt.setup(repos)
+
+			got, err := LatestHash(ctx, repos, remote, "main", tt.paths)
+			if err != nil {
+				t.Fatalf("LatestHash() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LatestHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
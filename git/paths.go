@@ -0,0 +1,55 @@
+package git
+
+import (
+	"path"
+	"strings"
+)
+
+// IsGlob returns true if given path contains glob pattern
+func IsGlob(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// pathspec returns git pathspec of the given path, glob patterns are
+// converted to glob magic pathspec so that '*' doesn't match '/'
+func pathspec(p string) string {
+	if IsGlob(p) {
+		return ":(glob)" + p
+	}
+	return p
+}
+
+// MatchPath returns true if given file path is updated by the watch path.
+// plain path matches the file itself or all files under the directory,
+// glob pattern matches the file path same as git glob pathspec
+func MatchPath(watchPath, file string) bool {
+	if !IsGlob(watchPath) {
+		watchPath = strings.TrimSuffix(path.Clean(watchPath), "/")
+		return file == watchPath || strings.HasPrefix(file, watchPath+"/")
+	}
+	return matchSegments(strings.Split(watchPath, "/"), strings.Split(file, "/"))
+}
+
+// matchSegments matches path segments with pattern segments where '**'
+// segment matches zero or more segments
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+	return len(segments) == 0
+}
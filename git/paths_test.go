@@ -0,0 +1,42 @@
+package git
+
+import "testing"
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		watchPath string
+		file      string
+		want      bool
+	}{
+		{"templates", "templates/foo.tpl", true},
+		{"templates/", "templates/a/foo.tpl", true},
+		{"templates", "templates-old/foo.tpl", false},
+		{"shared.tfvars", "shared.tfvars", true},
+		{"templates/*.tpl", "templates/foo.tpl", true},
+		{"templates/*.tpl", "templates/a/foo.tpl", false},
+		{"templates/*", "templates/a/foo.tpl", false},
+		{"policies/**/*.json", "policies/admin.json", true},
+		{"policies/**/*.json", "policies/iam/roles/admin.json", true},
+		{"policies/**/*.json", "policies/iam/admin.yaml", false},
+		{"**/common.tfvars", "dev/eu/common.tfvars", true},
+		{"policies/**", "policies/iam/admin.json", true},
+		{"env/?/vars.tf", "env/a/vars.tf", true},
+		{"env/[ab]/vars.tf", "env/c/vars.tf", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.watchPath+"|"+tt.file, func(t *testing.T) {
+			if got := MatchPath(tt.watchPath, tt.file); got != tt.want {
+				t.Errorf("MatchPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pathspec(t *testing.T) {
+	if got := pathspec("modules/hello"); got != "modules/hello" {
+		t.Errorf("pathspec() = %v", got)
+	}
+	if got := pathspec("policies/**/*.json"); got != ":(glob)policies/**/*.json" {
+		t.Errorf("pathspec() = %v", got)
+	}
+}
@@ -38,6 +38,7 @@ func TestModuleController_Filter(t *testing.T) {
 			Return(commitMsg, nil).AnyTimes()
 
 		testMetrics.EXPECT().SetRunPending(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		testMetrics.EXPECT().DeleteModule(gomock.Any(), gomock.Any()).AnyTimes()
 
 		// Configure Mock to send to channel
 		testMockRunner1.EXPECT().Start(gomock.Any(), gomock.Any()).
@@ -31,6 +31,7 @@ func TestModuleController_NoRunner(t *testing.T) {
 		testFilter.LabelSelectorValue = ""
 
 		testMetrics.EXPECT().SetRunPending(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		testMetrics.EXPECT().DeleteModule(gomock.Any(), gomock.Any()).AnyTimes()
 
 		// Mock Start to send to channel
 		testMockRunner2.EXPECT().Start(gomock.Any(), gomock.Any()).
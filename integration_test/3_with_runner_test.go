@@ -63,6 +63,12 @@ func TestModuleController_WithRunner(t *testing.T) {
 		testMetrics.EXPECT().UpdateModuleRunDuration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 		testMetrics.EXPECT().UpdateModuleSuccess(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 		testMetrics.EXPECT().SetRunPending(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		testMetrics.EXPECT().DeleteModule(gomock.Any(), gomock.Any()).AnyTimes()
+		testMetrics.EXPECT().ObservePhaseDuration(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		testMetrics.EXPECT().AddResourceChanges(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		testMetrics.EXPECT().SetDriftedResources(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		testMetrics.EXPECT().SetLastApplyTimestamp(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		testMetrics.EXPECT().IncPRPlanCount(gomock.Any()).AnyTimes()
 
 		testCreds.EXPECT().Creds(gomock.Any()).Return("", "token", nil).AnyTimes()
 		os.Remove(testStateFilePath)
@@ -149,6 +155,11 @@ func TestModuleController_WithRunner(t *testing.T) {
 			t.Errorf("Expected Start Time %v, got %v", fakeClock.T.UTC(), fetchedModule.Status.LastDefaultRunStartedAt.UTC())
 		}
 
+		// applied changes are not pending anymore
+		if lp := fetchedModule.Status.LastPlan; lp == nil || lp.Add+lp.Change+lp.Destroy != 0 {
+			t.Errorf("Expected empty last plan after apply, got %+v", lp)
+		}
+
 		if !strings.Contains(lastRun.Output, "Plan:") {
 			t.Error("Expected Plan output")
 		}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -25,10 +27,13 @@ import (
 
 	"github.com/utilitywarehouse/git-mirror/repopool"
 	"github.com/utilitywarehouse/git-mirror/repository"
+	"github.com/utilitywarehouse/terraform-applier/cloudevents"
 	"github.com/utilitywarehouse/terraform-applier/metrics"
+	"github.com/utilitywarehouse/terraform-applier/notifier"
 	"github.com/utilitywarehouse/terraform-applier/prplanner"
 	"github.com/utilitywarehouse/terraform-applier/runner"
 	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"github.com/utilitywarehouse/terraform-applier/tracing"
 	"github.com/utilitywarehouse/terraform-applier/vault"
 	"github.com/utilitywarehouse/terraform-applier/webhook"
 	"github.com/utilitywarehouse/terraform-applier/webserver"
@@ -51,9 +56,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	runTimeMetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
 	"github.com/utilitywarehouse/terraform-applier/controllers"
+	"github.com/utilitywarehouse/terraform-applier/git"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -113,6 +120,13 @@ var (
 			Usage: "Termination grace period in second, is the time given to the running job to finish current run after 1st TERM signal is received. " +
 				"After this timeout runner will be forced to shutdown.",
 		},
+		&cli.IntFlag{
+			Name:    "stale-lock-timeout",
+			EnvVars: []string{"STALE_LOCK_TIMEOUT"},
+			Value:   0,
+			Usage: "The min age in seconds of the terraform state lock created by this controller after which its considered stale and removed automatically. " +
+				"lock age must also be greater then module's run timeout + termination grace period. if its 0 stale locks are not removed",
+		},
 		&cli.StringFlag{
 			Name:        "terraform-path",
 			EnvVars:     []string{"TERRAFORM_PATH"},
@@ -255,10 +269,9 @@ var (
 			Usage: "The address the probe endpoint binds to.",
 		},
 		&cli.StringFlag{
-			Name:     "redis-url",
-			EnvVars:  []string{"REDIS_URL"},
-			Required: true,
-			Usage:    "redis url to store run output and metadata",
+			Name:    "redis-url",
+			EnvVars: []string{"REDIS_URL"},
+			Usage:   "redis url to store run output and metadata, required unless running in aggregator mode",
 		},
 		&cli.BoolFlag{
 			Name:    "disable-plugin-cache",
@@ -284,6 +297,24 @@ var (
 			Value:   ":8083",
 			Usage:   "port used to receive Github webhooks",
 		},
+		&cli.BoolFlag{
+			Name:    "enable-admission-webhook",
+			EnvVars: []string{"ENABLE_ADMISSION_WEBHOOK"},
+			Value:   false,
+			Usage:   "Enable validating and defaulting admission webhook for Module",
+		},
+		&cli.IntFlag{
+			Name:    "admission-webhook-port",
+			EnvVars: []string{"ADMISSION_WEBHOOK_PORT"},
+			Value:   9443,
+			Usage:   "The port admission webhook server listens on",
+		},
+		&cli.StringFlag{
+			Name:    "admission-webhook-cert-dir",
+			EnvVars: []string{"ADMISSION_WEBHOOK_CERT_DIR"},
+			Value:   "/tmp/k8s-webhook-server/serving-certs",
+			Usage:   "The directory that contains the admission webhook server key and certificate (tls.key and tls.crt)",
+		},
 		&cli.StringFlag{
 			Name:    "github-token",
 			EnvVars: []string{"GITHUB_TOKEN"},
@@ -314,12 +345,104 @@ var (
 			EnvVars: []string{"GITHUB_WEBHOOK_SKIP_VALIDATION"},
 			Usage:   "If set github webhook signature validation will be skipped",
 		},
+		&cli.BoolFlag{
+			Name:    "enable-commit-status",
+			EnvVars: []string{"ENABLE_COMMIT_STATUS"},
+			Value:   false,
+			Usage:   "If set GitHub commit status will be published for scheduled and polling runs",
+		},
+		&cli.StringFlag{
+			Name:    "gitlab-url",
+			EnvVars: []string{"GITLAB_URL"},
+			Usage:   "The root url of the GitLab instance, if set MRs of the repositories hosted on it will be planned",
+		},
+		&cli.StringFlag{
+			Name:    "gitlab-token",
+			EnvVars: []string{"GITLAB_TOKEN"},
+			Usage:   "provide GitLab API token with api scope",
+		},
+		&cli.StringFlag{
+			Name:    "gitlab-webhook-secret",
+			EnvVars: []string{"GITLAB_WEBHOOK_SECRET"},
+			Usage:   "secret token used to authorise GitLab webhooks",
+		},
+		&cli.StringFlag{
+			Name:    "gitea-url",
+			EnvVars: []string{"GITEA_URL"},
+			Usage:   "The root url of the Gitea or Forgejo instance, if set PRs of the repositories hosted on it will be planned",
+		},
+		&cli.StringFlag{
+			Name:    "gitea-token",
+			EnvVars: []string{"GITEA_TOKEN"},
+			Usage:   "provide Gitea API token with write to issues and repository access",
+		},
+		&cli.StringFlag{
+			Name:    "gitea-webhook-secret",
+			EnvVars: []string{"GITEA_WEBHOOK_SECRET"},
+			Usage:   "used to sign and authorise Gitea webhooks",
+		},
+		&cli.StringFlag{
+			Name:    "bitbucket-url",
+			EnvVars: []string{"BITBUCKET_URL"},
+			Usage:   "The root url of the Bitbucket Server instance, if set PRs of the repositories hosted on it will be planned",
+		},
+		&cli.StringFlag{
+			Name:    "bitbucket-token",
+			EnvVars: []string{"BITBUCKET_TOKEN"},
+			Usage:   "provide Bitbucket HTTP access token with repository write access",
+		},
+		&cli.StringFlag{
+			Name:    "bitbucket-webhook-secret",
+			EnvVars: []string{"BITBUCKET_WEBHOOK_SECRET"},
+			Usage:   "used to sign and authorise Bitbucket webhooks",
+		},
 		&cli.StringFlag{
 			Name:    "cluster-env-name",
 			EnvVars: []string{"CLUSTER_ENV_NAME"},
 			Value:   "default",
 			Usage:   "cluster-env-name is used as cluster identifier while posting msg on PRs",
 		},
+		&cli.StringFlag{
+			Name:    "cloudevents-sink-url",
+			EnvVars: []string{"CLOUDEVENTS_SINK_URL"},
+			Usage:   "if set, run lifecycle events are sent to this url as CloudEvents in HTTP binary mode",
+		},
+		&cli.IntFlag{
+			Name:    "cloudevents-buffer-size",
+			EnvVars: []string{"CLOUDEVENTS_BUFFER_SIZE"},
+			Value:   1000,
+			Usage:   "max number of CloudEvents buffered in memory while waiting to be sent, new events are dropped if buffer is full",
+		},
+		&cli.BoolFlag{
+			Name:    "enable-tracing",
+			EnvVars: []string{"ENABLE_TRACING"},
+			Value:   false,
+			Usage: "export OpenTelemetry traces of module runs using OTLP over HTTP. " +
+				"exporter is configured with standard OTEL_EXPORTER_OTLP_* env variables",
+		},
+		&cli.BoolFlag{
+			Name:    "aggregator-mode",
+			EnvVars: []string{"AGGREGATOR_MODE"},
+			Value:   false,
+			Usage:   "run as aggregator which receives PR run results from terraform-applier instances and posts consolidated PR comment",
+		},
+		&cli.StringFlag{
+			Name:    "aggregator-bind-address",
+			EnvVars: []string{"AGGREGATOR_BIND_ADDRESS"},
+			Value:   ":8084",
+			Usage:   "The address the aggregator API and dashboard binds to.",
+		},
+		&cli.StringFlag{
+			Name:    "aggregator-url",
+			EnvVars: []string{"AGGREGATOR_URL"},
+			Usage: "url of the aggregator, if set PR run outputs are sent to the aggregator instead of posting PR comments. " +
+				"in aggregator mode its used as dashboard link in PR comments",
+		},
+		&cli.StringFlag{
+			Name:    "aggregator-token",
+			EnvVars: []string{"AGGREGATOR_TOKEN"},
+			Usage:   "token used to authenticate requests to the aggregator",
+		},
 	}
 )
 
@@ -341,6 +464,21 @@ func validate(c *cli.Context) {
 		loggerLevel.Set(v)
 	}
 
+	if c.Bool("aggregator-mode") {
+		if c.String("aggregator-token") == "" {
+			logger.Error("aggregator token is required in aggregator mode")
+			os.Exit(1)
+		}
+	} else if c.String("redis-url") == "" {
+		logger.Error("redis url is required")
+		os.Exit(1)
+	}
+
+	if c.String("aggregator-url") != "" && c.String("aggregator-token") == "" {
+		logger.Error("aggregator token is required if aggregator url is set")
+		os.Exit(1)
+	}
+
 	if c.IsSet("module-label-selector") {
 		labelKV := strings.Split(c.String("module-label-selector"), "=")
 		if len(labelKV) != 2 || labelKV[0] == "" || labelKV[1] == "" {
@@ -581,6 +719,10 @@ func main() {
 			if cCtx.Bool("cleanup-temp-dir") {
 				cleanupTmpDir()
 			}
+			if cCtx.Bool("aggregator-mode") {
+				runAggregator(cCtx)
+				return nil
+			}
 			setupGlobalEnv(cCtx)
 			run(cCtx)
 			return nil
@@ -590,6 +732,65 @@ func main() {
 	app.Run(os.Args)
 }
 
+// runAggregator runs aggregator which only needs git hosting provider
+// credentials to post consolidated PR comments
+func runAggregator(c *cli.Context) {
+	ctx := ctrl.SetupSignalHandler()
+
+	ghCreds, err := sysutil.NewGithubCredProvider(
+		c.String("github-token"),
+		c.String("github-app-id"),
+		c.String("github-app-install-id"),
+		c.String("github-app-key-path"),
+		map[string]string{"pull_requests": "write"},
+		logger.With("logger", "aggregator-github-app"),
+	)
+	if err != nil {
+		logger.Error("unable to create creds provider", "error", err)
+		os.Exit(1)
+	}
+
+	providers := &prplanner.Planner{
+		GitLabURL:      c.String("gitlab-url"),
+		GitLabToken:    c.String("gitlab-token"),
+		GiteaURL:       c.String("gitea-url"),
+		GiteaToken:     c.String("gitea-token"),
+		BitbucketURL:   c.String("bitbucket-url"),
+		BitbucketToken: c.String("bitbucket-token"),
+	}
+	if err := providers.InitProviders(ghCreds); err != nil {
+		logger.Error("unable to init git providers", "err", err)
+		os.Exit(1)
+	}
+
+	aggregator := &prplanner.Aggregator{
+		ListenAddress: c.String("aggregator-bind-address"),
+		Token:         c.String("aggregator-token"),
+		URL:           c.String("aggregator-url"),
+		Planner:       providers,
+		Log:           logger.With("logger", "aggregator"),
+	}
+
+	// dashboard is protected by OIDC if configured otherwise token is required
+	if c.IsSet("oidc-issuer") {
+		aggregator.Authenticator, err = oidc.NewAuthenticator(
+			c.String("oidc-issuer"),
+			c.String("oidc-client-id"),
+			c.String("oidc-client-secret"),
+			c.String("oidc-callback-url"),
+		)
+		if err != nil {
+			logger.Error("could not setup oidc authenticator", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := aggregator.Start(ctx); err != nil {
+		logger.Error("unable to start aggregator", "err", err)
+		os.Exit(1)
+	}
+}
+
 func run(c *cli.Context) {
 	ctx := ctrl.SetupSignalHandler()
 
@@ -644,6 +845,9 @@ func run(c *cli.Context) {
 	// start mirror Loop
 	repos.StartLoop()
 
+	// resolves local module dependencies of the modules to detect changes
+	moduleDeps := &git.ModuleDeps{Repos: repos}
+
 	// Find the requested version of terraform and log the version
 	// information
 	execPath, cleanup, err := findTerraformExecPath(terraformPath, terraformVersion)
@@ -682,6 +886,13 @@ func run(c *cli.Context) {
 		GracefulShutdownTimeout: &gracefulShutdownTimeout,
 	}
 
+	if c.Bool("enable-admission-webhook") {
+		options.WebhookServer = ctrlwebhook.NewServer(ctrlwebhook.Options{
+			Port:    c.Int("admission-webhook-port"),
+			CertDir: c.String("admission-webhook-cert-dir"),
+		})
+	}
+
 	var labelSelector labels.Selector
 	if labelSelectorKey != "" {
 		labelSelector = labels.Set{labelSelectorKey: labelSelectorValue}.AsSelector()
@@ -701,6 +912,9 @@ func run(c *cli.Context) {
 		DefaultLabelSelector: labelSelector,
 		ByObject: map[client.Object]cache.ByObject{
 			&tfaplv1beta1.Module{}: {Namespaces: namespaces},
+			// defaults are referenced by name hence label selector is not applicable
+			&tfaplv1beta1.ModuleDefaults{}: {Namespaces: namespaces, Label: labels.Everything()},
+			&tfaplv1beta1.ModuleSet{}:      {Namespaces: namespaces},
 		},
 	}
 
@@ -708,6 +922,7 @@ func run(c *cli.Context) {
 		Log:                logger.With("logger", "filter"),
 		LabelSelectorKey:   labelSelectorKey,
 		LabelSelectorValue: labelSelectorValue,
+		Metrics:            metrics,
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
@@ -733,6 +948,7 @@ func run(c *cli.Context) {
 		Clock:                  clock,
 		KubeClt:                kubeClient,
 		Repos:                  repos,
+		ModuleDeps:             moduleDeps,
 		GHCredsProvider:        runnerGHCreds,
 		Log:                    logger.With("logger", "runner"),
 		Metrics:                metrics,
@@ -750,6 +966,76 @@ func run(c *cli.Context) {
 		ClusterClt:   mgr.GetClient(),
 		Recorder:     mgr.GetEventRecorderFor("terraform-applier"),
 		DataRootPath: dataRootPath,
+
+		StaleLockTimeout: time.Duration(c.Int("stale-lock-timeout")) * time.Second,
+	}
+
+	if c.Bool("enable-commit-status") {
+		commitStatusGHCreds, err := sysutil.NewGithubCredProvider(
+			c.String("github-token"),
+			c.String("github-app-id"),
+			c.String("github-app-install-id"),
+			c.String("github-app-key-path"),
+			map[string]string{"statuses": "write"},
+			logger.With("logger", "commit-status-github-app"),
+		)
+		if err != nil {
+			logger.Error("unable to create creds provider", "error", err)
+			os.Exit(1)
+		}
+		runner.CommitStatus = prplanner.NewCommitStatusPublisher(
+			c.String("cluster-env-name"),
+			c.String("oidc-callback-url"),
+			commitStatusGHCreds,
+			logger.With("logger", "commit-status"),
+		)
+	}
+
+	notificationSinks, err := conf.Notifications.NewSinks()
+	if err != nil {
+		logger.Error("unable to setup notification sinks", "err", err)
+		os.Exit(1)
+	}
+
+	var runNotifier *notifier.Notifier
+	if len(notificationSinks) > 0 {
+		runNotifier = &notifier.Notifier{
+			Cluster:      c.String("cluster-env-name"),
+			WebserverURL: c.String("oidc-callback-url"),
+			ClusterClt:   mgr.GetClient(),
+			Sinks:        notificationSinks,
+			Log:          logger.With("logger", "notifier"),
+		}
+		runner.Notifier = runNotifier
+	}
+
+	if c.String("cloudevents-sink-url") != "" {
+		emitter := &cloudevents.Emitter{
+			SinkURL:    c.String("cloudevents-sink-url"),
+			Source:     "terraform-applier/" + c.String("cluster-env-name"),
+			Cluster:    c.String("cluster-env-name"),
+			BufferSize: c.Int("cloudevents-buffer-size"),
+			Log:        logger.With("logger", "cloudevents"),
+		}
+		emitter.Init()
+		go emitter.Start(ctx)
+		runner.CloudEvents = emitter
+	}
+
+	if c.Bool("enable-tracing") {
+		shutdown, err := tracing.Init(ctx, c.String("cluster-env-name"))
+		if err != nil {
+			logger.Error("unable to setup tracing", "err", err)
+			os.Exit(1)
+		}
+		defer func() {
+			// use new context as manager context is already done on shutdown
+			sCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := shutdown(sCtx); err != nil {
+				logger.Error("unable to flush traces", "err", err)
+			}
+		}()
 	}
 
 	if err := runner.Init(!c.Bool("disable-plugin-cache"), c.Int("max-concurrent-runs")); err != nil {
@@ -763,15 +1049,41 @@ func run(c *cli.Context) {
 		Recorder:               mgr.GetEventRecorderFor("terraform-applier"),
 		Clock:                  clock,
 		Repos:                  repos,
+		ModuleDeps:             moduleDeps,
 		Log:                    logger.With("logger", "manager"),
 		MinIntervalBetweenRuns: time.Duration(c.Int("min-interval-between-runs")) * time.Second,
 		RunStatus:              runStatus,
 		Metrics:                metrics,
 		Runner:                 &runner,
+		Notifier:               runNotifier,
 	}).SetupWithManager(mgr, filter); err != nil {
 		logger.Error("unable to create module controller", "err", err)
 		os.Exit(1)
 	}
+
+	if err = (&controllers.ModuleSetReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("terraform-applier"),
+		Clock:    clock,
+		Repos:    repos,
+		Log:      logger.With("logger", "module-set"),
+	}).SetupWithManager(mgr, filter); err != nil {
+		logger.Error("unable to create module set controller", "err", err)
+		os.Exit(1)
+	}
+
+	if c.Bool("enable-admission-webhook") {
+		if err = (&controllers.ModuleWebhook{
+			Repos:                  repos,
+			MinIntervalBetweenRuns: time.Duration(c.Int("min-interval-between-runs")) * time.Second,
+			NotificationSinks:      slices.Collect(maps.Keys(notificationSinks)),
+			Log:                    logger.With("logger", "admission-webhook"),
+		}).SetupWithManager(mgr); err != nil {
+			logger.Error("unable to create module admission webhook", "err", err)
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -797,8 +1109,14 @@ func run(c *cli.Context) {
 		logger.Info("OIDC authentication configured", "issuer", c.String("oidc-issuer"), "clientID", c.String("oidc-client-id"))
 	}
 
+	// only modules managed by this controller are collected
+	var moduleListOpts []client.ListOption
+	if labelSelectorKey != "" {
+		moduleListOpts = append(moduleListOpts, client.MatchingLabels{labelSelectorKey: labelSelectorValue})
+	}
+
 	go func(client client.Client) {
-		if err := metrics.CollectModuleInfo(ctx, client); err != nil {
+		if err := metrics.CollectModuleInfo(ctx, client, moduleListOpts...); err != nil {
 			logger.Error("unable to collect module info metrics", "error", err)
 		}
 
@@ -807,7 +1125,7 @@ func run(c *cli.Context) {
 		for {
 			select {
 			case <-ticker.C:
-				if err := metrics.CollectModuleInfo(ctx, client); err != nil {
+				if err := metrics.CollectModuleInfo(ctx, client, moduleListOpts...); err != nil {
 					logger.Error("unable to collect module info metrics", "error", err)
 				}
 			case <-ctx.Done():
@@ -836,15 +1154,26 @@ func run(c *cli.Context) {
 	var prPlanner *prplanner.Planner
 	if !c.Bool("disable-pr-planner") {
 		prPlanner = &prplanner.Planner{
-			ClusterEnvName: c.String("cluster-env-name"),
-			GitMirror:      conf.GitMirror,
-			Interval:       time.Duration(c.Int("pr-planner-interval")) * time.Second,
-			ClusterClt:     mgr.GetClient(),
-			Repos:          repos,
-			RedisClient:    sysutil.Redis{Client: rdb},
-			Runner:         &runner,
-			Log:            logger.With("logger", "pr-planner"),
-			WebserverURL:   c.String("oidc-callback-url"),
+			ClusterEnvName:  c.String("cluster-env-name"),
+			GitMirror:       conf.GitMirror,
+			Interval:        time.Duration(c.Int("pr-planner-interval")) * time.Second,
+			ClusterClt:      mgr.GetClient(),
+			Repos:           repos,
+			ModuleDeps:      moduleDeps,
+			RedisClient:     sysutil.Redis{Client: rdb},
+			Runner:          &runner,
+			RunStatus:       runStatus,
+			Log:             logger.With("logger", "pr-planner"),
+			WebserverURL:    c.String("oidc-callback-url"),
+			Config:          conf.PRPlanner,
+			GitLabURL:       c.String("gitlab-url"),
+			GitLabToken:     c.String("gitlab-token"),
+			GiteaURL:        c.String("gitea-url"),
+			GiteaToken:      c.String("gitea-token"),
+			BitbucketURL:    c.String("bitbucket-url"),
+			BitbucketToken:  c.String("bitbucket-token"),
+			AggregatorURL:   c.String("aggregator-url"),
+			AggregatorToken: c.String("aggregator-token"),
 		}
 
 		// setup subscription for key set
@@ -855,12 +1184,17 @@ func run(c *cli.Context) {
 			os.Exit(1)
 		}
 
+		plannerPermissions := map[string]string{"pull_requests": "write"}
+		if conf.PRPlanner.CheckRunsEnabled() {
+			plannerPermissions["checks"] = "write"
+		}
+
 		plannerGHCreds, err := sysutil.NewGithubCredProvider(
 			c.String("github-token"),
 			c.String("github-app-id"),
 			c.String("github-app-install-id"),
 			c.String("github-app-key-path"),
-			map[string]string{"pull_requests": "write"},
+			plannerPermissions,
 			logger.With("logger", "pr-github-app"),
 		)
 		if err != nil {
@@ -875,12 +1209,16 @@ func run(c *cli.Context) {
 	}
 
 	webhook := webhook.Webhook{
-		ListenAddress:         c.String("pr-planner-webhook-port"),
-		WebhookSecret:         c.String("github-webhook-secret"),
-		SkipWebhookValidation: c.Bool("github-webhook-skip-validation"),
-		Repos:                 repos,
-		PRPlanner:             prPlanner,
-		Log:                   logger.With("logger", "webhook"),
+		ListenAddress:          c.String("pr-planner-webhook-port"),
+		WebhookSecret:          c.String("github-webhook-secret"),
+		GitLabWebhookSecret:    c.String("gitlab-webhook-secret"),
+		GiteaWebhookSecret:     c.String("gitea-webhook-secret"),
+		BitbucketWebhookSecret: c.String("bitbucket-webhook-secret"),
+		BitbucketURL:           c.String("bitbucket-url"),
+		SkipWebhookValidation:  c.Bool("github-webhook-skip-validation"),
+		Repos:                  repos,
+		PRPlanner:              prPlanner,
+		Log:                    logger.With("logger", "webhook"),
 	}
 
 	go webhook.Start()
@@ -6,6 +6,7 @@ package metrics
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -33,6 +34,78 @@ func (m *MockPrometheusInterface) EXPECT() *MockPrometheusInterfaceMockRecorder
 	return m.recorder
 }
 
+// AddResourceChanges mocks base method.
+func (m *MockPrometheusInterface) AddResourceChanges(arg0, arg1 string, arg2, arg3, arg4 int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddResourceChanges", arg0, arg1, arg2, arg3, arg4)
+}
+
+// AddResourceChanges indicates an expected call of AddResourceChanges.
+func (mr *MockPrometheusInterfaceMockRecorder) AddResourceChanges(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddResourceChanges", reflect.TypeOf((*MockPrometheusInterface)(nil).AddResourceChanges), arg0, arg1, arg2, arg3, arg4)
+}
+
+// DeleteModule mocks base method.
+func (m *MockPrometheusInterface) DeleteModule(arg0, arg1 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteModule", arg0, arg1)
+}
+
+// DeleteModule indicates an expected call of DeleteModule.
+func (mr *MockPrometheusInterfaceMockRecorder) DeleteModule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteModule", reflect.TypeOf((*MockPrometheusInterface)(nil).DeleteModule), arg0, arg1)
+}
+
+// IncPRPlanCount mocks base method.
+func (m *MockPrometheusInterface) IncPRPlanCount(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "IncPRPlanCount", arg0)
+}
+
+// IncPRPlanCount indicates an expected call of IncPRPlanCount.
+func (mr *MockPrometheusInterfaceMockRecorder) IncPRPlanCount(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncPRPlanCount", reflect.TypeOf((*MockPrometheusInterface)(nil).IncPRPlanCount), arg0)
+}
+
+// ObservePhaseDuration mocks base method.
+func (m *MockPrometheusInterface) ObservePhaseDuration(arg0, arg1, arg2 string, arg3 float64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ObservePhaseDuration", arg0, arg1, arg2, arg3)
+}
+
+// ObservePhaseDuration indicates an expected call of ObservePhaseDuration.
+func (mr *MockPrometheusInterfaceMockRecorder) ObservePhaseDuration(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObservePhaseDuration", reflect.TypeOf((*MockPrometheusInterface)(nil).ObservePhaseDuration), arg0, arg1, arg2, arg3)
+}
+
+// SetDriftedResources mocks base method.
+func (m *MockPrometheusInterface) SetDriftedResources(arg0, arg1 string, arg2 int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetDriftedResources", arg0, arg1, arg2)
+}
+
+// SetDriftedResources indicates an expected call of SetDriftedResources.
+func (mr *MockPrometheusInterfaceMockRecorder) SetDriftedResources(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDriftedResources", reflect.TypeOf((*MockPrometheusInterface)(nil).SetDriftedResources), arg0, arg1, arg2)
+}
+
+// SetLastApplyTimestamp mocks base method.
+func (m *MockPrometheusInterface) SetLastApplyTimestamp(arg0, arg1 string, arg2 time.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetLastApplyTimestamp", arg0, arg1, arg2)
+}
+
+// SetLastApplyTimestamp indicates an expected call of SetLastApplyTimestamp.
+func (mr *MockPrometheusInterfaceMockRecorder) SetLastApplyTimestamp(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLastApplyTimestamp", reflect.TypeOf((*MockPrometheusInterface)(nil).SetLastApplyTimestamp), arg0, arg1, arg2)
+}
+
 // SetRunPending mocks base method.
 func (m *MockPrometheusInterface) SetRunPending(arg0, arg1 string, arg2 bool) {
 	m.ctrl.T.Helper()
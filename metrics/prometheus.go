@@ -3,10 +3,13 @@ package metrics
 import (
 	"context"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/utilitywarehouse/git-mirror/giturl"
 	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
@@ -15,6 +18,15 @@ const (
 	metricsNamespace = "terraform_applier"
 )
 
+// phases of the module run
+const (
+	PhaseClone       = "clone"
+	PhaseCredentials = "credentials"
+	PhaseInit        = "init"
+	PhasePlan        = "plan"
+	PhaseApply       = "apply"
+)
+
 //go:generate go run github.com/golang/mock/mockgen -package metrics -destination mock_prometheus.go github.com/utilitywarehouse/terraform-applier/metrics PrometheusInterface
 
 // PrometheusInterface allows for mocking out the functionality of Prometheus when testing the full process of an apply run.
@@ -22,6 +34,12 @@ type PrometheusInterface interface {
 	UpdateModuleSuccess(string, string, string, bool)
 	UpdateModuleRunDuration(string, string, string, float64, bool)
 	SetRunPending(string, string, bool)
+	ObservePhaseDuration(string, string, string, float64)
+	AddResourceChanges(string, string, int, int, int)
+	SetDriftedResources(string, string, int)
+	SetLastApplyTimestamp(string, string, time.Time)
+	IncPRPlanCount(string)
+	DeleteModule(string, string)
 }
 
 // Prometheus implements instrumentation of metrics for terraform-applier.
@@ -30,13 +48,26 @@ type PrometheusInterface interface {
 // moduleRunDuration is a Summary vector that keeps track of the duration for runs.
 // moduleRunSuccess is the last run outcome of the module run.
 // moduleRunning is the number of modules currently in running state.
+// modulePhaseDuration is a Histogram vector of the duration of each run phase.
+// moduleResourceChanges is a Counter vector of the resources added, changed and destroyed by applies.
+// moduleDriftedResources is the number of resources with pending changes detected by the last plan.
+// moduleLastApplyTimestamp is the timestamp of the last successful apply of the module.
+// prPlanCount is a Counter vector of PR plan runs for each repository.
 type Prometheus struct {
-	moduleRunCount     *prometheus.CounterVec
-	moduleRunDuration  *prometheus.HistogramVec
-	moduleRunPending   *prometheus.GaugeVec
-	moduleRunSuccess   *prometheus.GaugeVec
-	moduleRunTimestamp *prometheus.GaugeVec
-	moduleInfo         *prometheus.GaugeVec
+	moduleRunCount           *prometheus.CounterVec
+	moduleRunDuration        *prometheus.HistogramVec
+	moduleRunPending         *prometheus.GaugeVec
+	moduleRunSuccess         *prometheus.GaugeVec
+	moduleRunTimestamp       *prometheus.GaugeVec
+	moduleInfo               *prometheus.GaugeVec
+	modulePhaseDuration      *prometheus.HistogramVec
+	moduleResourceChanges    *prometheus.CounterVec
+	moduleDriftedResources   *prometheus.GaugeVec
+	moduleLastApplyTimestamp *prometheus.GaugeVec
+	prPlanCount              *prometheus.CounterVec
+
+	// modules is the list of modules found on last module info collection
+	modules map[types.NamespacedName]struct{}
 }
 
 // Init creates and registers the custom metrics for terraform-applier.
@@ -131,6 +162,67 @@ func (p *Prometheus) Init() {
 		},
 	)
 
+	p.modulePhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "module_run_phase_duration_seconds",
+		Help:      "Duration of each phase of the terraform run for a module",
+
+		Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 900, 1200, 1800},
+	},
+		[]string{
+			"module",
+			// Namespace name of the module that was ran
+			"namespace",
+			// phase of the run: clone, credentials, init, plan or apply
+			"phase",
+		},
+	)
+	p.moduleResourceChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "module_resource_changes_total",
+		Help:      "Number of resources added, changed and destroyed by module applies",
+	},
+		[]string{
+			"module",
+			// Namespace name of the module that was ran
+			"namespace",
+			// action: added, changed or destroyed
+			"action",
+		},
+	)
+	p.moduleDriftedResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "module_drifted_resources",
+		Help:      "Number of resources to add, change or destroy detected by the last plan of the module",
+	},
+		[]string{
+			"module",
+			// Namespace name of the module that was ran
+			"namespace",
+		},
+	)
+	p.moduleLastApplyTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "module_last_apply_timestamp_seconds",
+		Help:      "Timestamp of the last successful apply of the module",
+	},
+		[]string{
+			"module",
+			// Namespace name of the module
+			"namespace",
+		},
+	)
+	p.prPlanCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "pr_plan_count",
+		Help:      "Number of PR plan runs for each repository",
+	},
+		[]string{
+			// repository path of the PR
+			"repo",
+		},
+	)
+
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(
 		p.moduleRunCount,
@@ -139,6 +231,11 @@ func (p *Prometheus) Init() {
 		p.moduleRunPending,
 		p.moduleRunTimestamp,
 		p.moduleInfo,
+		p.modulePhaseDuration,
+		p.moduleResourceChanges,
+		p.moduleDriftedResources,
+		p.moduleLastApplyTimestamp,
+		p.prPlanCount,
 	)
 
 }
@@ -196,14 +293,106 @@ func (p *Prometheus) SetRunPending(module, namespace string, pending bool) {
 	}).Set(as)
 }
 
-// CollectModuleInfo when called resets 'module_info' and collect current state of the modules
-func (p *Prometheus) CollectModuleInfo(ctx context.Context, kc client.Client) error {
+// ObservePhaseDuration adds a data point (duration of the run phase in seconds) to the module_run_phase_duration_seconds Histogram metric
+func (p *Prometheus) ObservePhaseDuration(module, namespace, phase string, seconds float64) {
+	p.modulePhaseDuration.With(prometheus.Labels{
+		"module":    module,
+		"namespace": namespace,
+		"phase":     phase,
+	}).Observe(seconds)
+}
+
+// AddResourceChanges increments module's resource change counters by the numbers from apply summary
+func (p *Prometheus) AddResourceChanges(module, namespace string, added, changed, destroyed int) {
+	for action, count := range map[string]int{
+		"added":     added,
+		"changed":   changed,
+		"destroyed": destroyed,
+	} {
+		p.moduleResourceChanges.With(prometheus.Labels{
+			"module":    module,
+			"namespace": namespace,
+			"action":    action,
+		}).Add(float64(count))
+	}
+}
+
+// SetDriftedResources sets number of resources with pending changes detected by the last plan
+func (p *Prometheus) SetDriftedResources(module, namespace string, count int) {
+	p.moduleDriftedResources.With(prometheus.Labels{
+		"module":    module,
+		"namespace": namespace,
+	}).Set(float64(count))
+}
+
+// SetLastApplyTimestamp sets timestamp of the last successful apply of the module
+func (p *Prometheus) SetLastApplyTimestamp(module, namespace string, t time.Time) {
+	p.moduleLastApplyTimestamp.With(prometheus.Labels{
+		"module":    module,
+		"namespace": namespace,
+	}).Set(float64(t.Unix()))
+}
+
+// IncPRPlanCount increments PR plan counter of the given repository
+func (p *Prometheus) IncPRPlanCount(repoURL string) {
+	p.prPlanCount.With(prometheus.Labels{
+		"repo": repoLabel(repoURL),
+	}).Inc()
+}
+
+// repoLabel returns repository path without host and '.git' suffix
+// ie 'org/repo', raw url is returned if its not valid git url
+func repoLabel(repoURL string) string {
+	u, err := giturl.Parse(repoURL)
+	if err != nil {
+		return repoURL
+	}
+	return u.Path + "/" + strings.TrimSuffix(u.Repo, ".git")
+}
+
+// DeleteModule removes all the series labelled with given module
+func (p *Prometheus) DeleteModule(module, namespace string) {
+	labels := prometheus.Labels{
+		"module":    module,
+		"namespace": namespace,
+	}
+	p.moduleInfo.DeletePartialMatch(labels)
+	p.moduleRunCount.DeletePartialMatch(labels)
+	p.moduleRunDuration.DeletePartialMatch(labels)
+	p.moduleRunPending.DeletePartialMatch(labels)
+	p.moduleRunSuccess.DeletePartialMatch(labels)
+	p.moduleRunTimestamp.DeletePartialMatch(labels)
+	p.modulePhaseDuration.DeletePartialMatch(labels)
+	p.moduleResourceChanges.DeletePartialMatch(labels)
+	p.moduleDriftedResources.DeletePartialMatch(labels)
+	p.moduleLastApplyTimestamp.DeletePartialMatch(labels)
+}
+
+// CollectModuleInfo when called resets 'module_info' and collect current state
+// of the modules. last apply timestamp is set from module status so that its
+// available after restart. series of the modules which were
+// found on previous collection but not anymore are removed.
+// it is not concurrency safe and should only be called from single go routine
+func (p *Prometheus) CollectModuleInfo(ctx context.Context, kc client.Client, opts ...client.ListOption) error {
 
 	kubeModuleList := &tfaplv1beta1.ModuleList{}
-	if err := kc.List(ctx, kubeModuleList); err != nil {
+	if err := kc.List(ctx, kubeModuleList, opts...); err != nil {
 		return err
 	}
 
+	current := make(map[types.NamespacedName]struct{}, len(kubeModuleList.Items))
+	for _, m := range kubeModuleList.Items {
+		current[m.NamespacedName()] = struct{}{}
+	}
+
+	// remove series of the modules which are deleted or not selected anymore
+	for m := range p.modules {
+		if _, ok := current[m]; !ok {
+			p.DeleteModule(m.Name, m.Namespace)
+		}
+	}
+	p.modules = current
+
 	// reset all values and re-set current value
 	p.moduleInfo.Reset()
 
@@ -214,6 +403,10 @@ func (p *Prometheus) CollectModuleInfo(ctx context.Context, kc client.Client) er
 			"state":     m.Status.CurrentState,
 			"reason":    m.Status.StateReason,
 		}).Set(1)
+
+		if m.Status.LastAppliedAt != nil {
+			p.SetLastApplyTimestamp(m.Name, m.Namespace, m.Status.LastAppliedAt.Time)
+		}
 	}
 	return nil
 }
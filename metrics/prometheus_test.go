@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testModule(namespace, name string, labels map[string]string) *tfaplv1beta1.Module {
+	return &tfaplv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Status:     tfaplv1beta1.ModuleStatus{CurrentState: "Ready", StateReason: "Applied"},
+	}
+}
+
+func TestPrometheus_DeleteModule(t *testing.T) {
+	if err := tfaplv1beta1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Prometheus{}
+	p.Init()
+
+	record := func(module, namespace string) {
+		p.UpdateModuleSuccess(module, namespace, tfaplv1beta1.ScheduledRun, true)
+		p.UpdateModuleRunDuration(module, namespace, tfaplv1beta1.ScheduledRun, 60, true)
+		p.SetRunPending(module, namespace, false)
+		p.ObservePhaseDuration(module, namespace, PhasePlan, 10)
+		p.AddResourceChanges(module, namespace, 1, 2, 3)
+		p.SetDriftedResources(module, namespace, 0)
+		p.SetLastApplyTimestamp(module, namespace, time.Unix(1700000000, 0))
+	}
+	record("admins", "foo")
+	record("users", "foo")
+	record("admins", "bar")
+
+	appliedAt := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	barAdmins := testModule("bar", "admins", map[string]string{"team": "bar"})
+	barAdmins.Status.LastAppliedAt = &metav1.Time{Time: appliedAt}
+
+	kubeClient := fake.NewFakeClient(
+		testModule("foo", "admins", map[string]string{"team": "foo"}),
+		testModule("foo", "users", map[string]string{"team": "foo"}),
+		barAdmins,
+	)
+	if err := p.CollectModuleInfo(context.Background(), kubeClient); err != nil {
+		t.Fatalf("CollectModuleInfo() error = %v", err)
+	}
+	if got := testutil.CollectAndCount(p.moduleInfo); got != 3 {
+		t.Fatalf("module_info series = %d, want 3", got)
+	}
+	// last apply timestamp is set from status so that its available after restart
+	if got := testutil.ToFloat64(p.moduleLastApplyTimestamp.WithLabelValues("admins", "bar")); got != float64(appliedAt.Unix()) {
+		t.Errorf("module_last_apply_timestamp_seconds = %v, want %v", got, appliedAt.Unix())
+	}
+
+	// deleted module
+	p.DeleteModule("users", "foo")
+
+	want := map[string]int{
+		"module_info":               2,
+		"module_run_count":          2,
+		"module_run_duration":       2,
+		"module_run_pending":        2,
+		"module_last_run_success":   2,
+		"module_last_run_timestamp": 2,
+		"module_phase_duration":     2,
+		"module_resource_changes":   6,
+		"module_drifted_resources":  2,
+		"module_last_apply":         2,
+	}
+	got := map[string]int{
+		"module_info":               testutil.CollectAndCount(p.moduleInfo),
+		"module_run_count":          testutil.CollectAndCount(p.moduleRunCount),
+		"module_run_duration":       testutil.CollectAndCount(p.moduleRunDuration),
+		"module_run_pending":        testutil.CollectAndCount(p.moduleRunPending),
+		"module_last_run_success":   testutil.CollectAndCount(p.moduleRunSuccess),
+		"module_last_run_timestamp": testutil.CollectAndCount(p.moduleRunTimestamp),
+		"module_phase_duration":     testutil.CollectAndCount(p.modulePhaseDuration),
+		"module_resource_changes":   testutil.CollectAndCount(p.moduleResourceChanges),
+		"module_drifted_resources":  testutil.CollectAndCount(p.moduleDriftedResources),
+		"module_last_apply":         testutil.CollectAndCount(p.moduleLastApplyTimestamp),
+	}
+	for metric, count := range want {
+		if got[metric] != count {
+			t.Errorf("%s series = %d, want %d", metric, got[metric], count)
+		}
+	}
+
+	// module not selected anymore should be removed on next collection
+	err := p.CollectModuleInfo(context.Background(), kubeClient, client.MatchingLabels{"team": "foo"})
+	if err != nil {
+		t.Fatalf("CollectModuleInfo() error = %v", err)
+	}
+	if got := testutil.CollectAndCount(p.moduleInfo); got != 2 {
+		t.Errorf("module_info series = %d, want 2", got)
+	}
+	if got := testutil.CollectAndCount(p.moduleRunCount); got != 1 {
+		t.Errorf("module_run_count series = %d, want 1 after bar/admins is un-selected", got)
+	}
+}
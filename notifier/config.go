@@ -0,0 +1,87 @@
+package notifier
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	SinkTypeSlack   = "slack"
+	SinkTypeTeams   = "teams"
+	SinkTypeWebhook = "webhook"
+	SinkTypeEmail   = "email"
+)
+
+// Config is the notifications section of the controller config file
+type Config struct {
+	Sinks []SinkConfig `yaml:"sinks"`
+}
+
+// SinkConfig is the config of the named notification sink. url, secret and
+// password are expanded with environment variables so that credentials can
+// be set via env e.g. '${SLACK_WEBHOOK_URL}'
+type SinkConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	// URL is the webhook url of slack, teams and webhook sinks
+	URL string `yaml:"url"`
+	// Secret is used to sign webhook sink requests
+	Secret string `yaml:"secret"`
+	// SMTP server config of email sink
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// NewSinks validates config and returns sinks by name
+func (c Config) NewSinks() (map[string]Sink, error) {
+	httpClt := &http.Client{Timeout: 15 * time.Second}
+
+	sinks := make(map[string]Sink)
+	for i, sc := range c.Sinks {
+		if sc.Name == "" {
+			return nil, fmt.Errorf("name is required for sink at index %d", i)
+		}
+		if _, ok := sinks[sc.Name]; ok {
+			return nil, fmt.Errorf("duplicate sink name %q", sc.Name)
+		}
+
+		url := os.ExpandEnv(sc.URL)
+		if sc.Type != SinkTypeEmail && url == "" {
+			return nil, fmt.Errorf("url is required for %s sink %q", sc.Type, sc.Name)
+		}
+
+		switch sc.Type {
+		case SinkTypeSlack:
+			sinks[sc.Name] = &SlackSink{WebhookURL: url, HTTP: httpClt}
+		case SinkTypeTeams:
+			sinks[sc.Name] = &TeamsSink{WebhookURL: url, HTTP: httpClt}
+		case SinkTypeWebhook:
+			sinks[sc.Name] = &WebhookSink{URL: url, Secret: os.ExpandEnv(sc.Secret), HTTP: httpClt}
+		case SinkTypeEmail:
+			if sc.Host == "" || sc.From == "" || len(sc.To) == 0 {
+				return nil, fmt.Errorf("host, from and to are required for email sink %q", sc.Name)
+			}
+			port := sc.Port
+			if port == 0 {
+				port = 587
+			}
+			sinks[sc.Name] = &EmailSink{
+				Host:     sc.Host,
+				Port:     port,
+				Username: os.ExpandEnv(sc.Username),
+				Password: os.ExpandEnv(sc.Password),
+				From:     sc.From,
+				To:       sc.To,
+			}
+		default:
+			return nil, fmt.Errorf("unknown type %q of sink %q", sc.Type, sc.Name)
+		}
+	}
+	return sinks, nil
+}
@@ -0,0 +1,184 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sendTimeout is the max duration to send notification to all the sinks
+const sendTimeout = 30 * time.Second
+
+// Sink sends notification to the external service
+type Sink interface {
+	Send(ctx context.Context, n *Notification) error
+}
+
+// Notification is the module run event sent to the sinks
+type Notification struct {
+	Event      tfaplv1beta1.NotificationEvent `json:"event"`
+	Cluster    string                         `json:"cluster"`
+	Module     types.NamespacedName           `json:"module"`
+	Path       string                         `json:"path,omitempty"`
+	RunType    string                         `json:"runType,omitempty"`
+	CommitHash string                         `json:"commitHash,omitempty"`
+	CommitMsg  string                         `json:"commitMsg,omitempty"`
+	Reason     string                         `json:"reason"`
+	Message    string                         `json:"message,omitempty"`
+	Summary    string                         `json:"summary,omitempty"`
+	// URL is the link to the module on terraform-applier web UI
+	URL  string    `json:"url,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// Title returns short description of the notification
+func (n *Notification) Title() string {
+	switch n.Event {
+	case tfaplv1beta1.NotificationEventApplied:
+		return fmt.Sprintf("✅ %s applied on %s", n.Module, n.Cluster)
+	case tfaplv1beta1.NotificationEventFailed:
+		return fmt.Sprintf("⛔ %s run failed on %s", n.Module, n.Cluster)
+	case tfaplv1beta1.NotificationEventDriftDetected:
+		return fmt.Sprintf("⚠️ %s drift detected on %s", n.Module, n.Cluster)
+	}
+	return fmt.Sprintf("%s %s on %s", n.Module, n.Event, n.Cluster)
+}
+
+// Fields returns notification details as ordered key value pairs,
+// empty values are skipped
+func (n *Notification) Fields() [][2]string {
+	var fields [][2]string
+	for _, f := range [][2]string{
+		{"Reason", n.Reason},
+		{"Message", n.Message},
+		{"Summary", n.Summary},
+		{"Run Type", n.RunType},
+		{"Path", n.Path},
+		{"Commit", strings.TrimSpace(n.CommitHash + " " + n.CommitMsg)},
+	} {
+		if f[1] != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Text returns plain text body of the notification
+func (n *Notification) Text() string {
+	var b strings.Builder
+	for _, f := range n.Fields() {
+		fmt.Fprintf(&b, "%s: %s\n", f[0], f[1])
+	}
+	if n.URL != "" {
+		fmt.Fprintf(&b, "\n%s\n", n.URL)
+	}
+	return b.String()
+}
+
+// Notifier sends module run events to the sinks as per module's
+// notification rules. Notifier is optional and nil Notifier is no-op.
+type Notifier struct {
+	Cluster      string
+	WebserverURL string
+	ClusterClt   client.Client
+	Sinks        map[string]Sink
+	Log          *slog.Logger
+}
+
+// Notify sends notification of the event to all the sinks of the module's
+// matching notification rules. its sent in background so that module run is
+// not blocked by the external services. run can be nil if failure happened
+// before run is started.
+func (n *Notifier) Notify(module *tfaplv1beta1.Module, run *tfaplv1beta1.Run, event tfaplv1beta1.NotificationEvent, reason, msg string) {
+	if n == nil || len(n.Sinks) == 0 {
+		return
+	}
+
+	// PR runs are reported on the PR
+	if run != nil && run.Request != nil && run.Request.IsPRRun() {
+		return
+	}
+
+	notification := n.notification(module, run, event, reason, msg)
+
+	// copy module as it might be modified by caller
+	module = module.DeepCopy()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+		n.send(ctx, n.sinks(ctx, module, event), notification)
+	}()
+}
+
+func (n *Notifier) notification(module *tfaplv1beta1.Module, run *tfaplv1beta1.Run, event tfaplv1beta1.NotificationEvent, reason, msg string) *Notification {
+	notification := &Notification{
+		Event:   event,
+		Cluster: n.Cluster,
+		Module:  module.NamespacedName(),
+		Path:    module.Spec.Path,
+		Reason:  reason,
+		Message: msg,
+		Time:    time.Now(),
+	}
+	if n.WebserverURL != "" {
+		notification.URL = sysutil.ModuleWebURL(n.WebserverURL, module.NamespacedName())
+	}
+	if run != nil {
+		notification.CommitHash = run.CommitHash
+		notification.CommitMsg = run.CommitMsg
+		notification.Summary = run.Summary
+		if run.Request != nil {
+			notification.RunType = run.Request.Type
+		}
+	}
+	// on failure run summary is the same failure message
+	if notification.Summary == notification.Message {
+		notification.Summary = ""
+	}
+	return notification
+}
+
+// sinks returns names of the sinks which should receive the event based on
+// module's and its defaults notification rules
+func (n *Notifier) sinks(ctx context.Context, module *tfaplv1beta1.Module, event tfaplv1beta1.NotificationEvent) []string {
+	// run might have failed before defaults are merged, merge is
+	// idempotent so its safe to merge again
+	if n.ClusterClt != nil {
+		if err := sysutil.MergeModuleDefaults(ctx, n.ClusterClt, module); err != nil {
+			n.Log.Error("unable to get module defaults for notifications", "module", module.NamespacedName(), "err", err)
+		}
+	}
+
+	var sinks []string
+	for _, rule := range module.Spec.Notifications {
+		if slices.Contains(rule.Events, event) && !slices.Contains(sinks, rule.Sink) {
+			sinks = append(sinks, rule.Sink)
+		}
+	}
+	return sinks
+}
+
+// send sends notification to the given sinks
+func (n *Notifier) send(ctx context.Context, sinks []string, notification *Notification) {
+	for _, name := range sinks {
+		sink, ok := n.Sinks[name]
+		if !ok {
+			n.Log.Warn("notification sink not configured", "module", notification.Module, "sink", name)
+			continue
+		}
+		if err := sink.Send(ctx, notification); err != nil {
+			n.Log.Error("unable to send notification", "module", notification.Module, "sink", name, "event", notification.Event, "err", err)
+			continue
+		}
+		n.Log.Debug("notification sent", "module", notification.Module, "sink", name, "event", notification.Event)
+	}
+}
@@ -0,0 +1,116 @@
+package notifier
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type chanSink chan *Notification
+
+func (s chanSink) Send(ctx context.Context, n *Notification) error {
+	s <- n
+	return nil
+}
+
+func testModule() *tfaplv1beta1.Module {
+	return &tfaplv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Name: "admins", Namespace: "foo"},
+		Spec: tfaplv1beta1.ModuleSpec{
+			Path: "dev/admins",
+			Notifications: []tfaplv1beta1.NotificationRule{
+				{Sink: "slack", Events: []tfaplv1beta1.NotificationEvent{tfaplv1beta1.NotificationEventFailed, tfaplv1beta1.NotificationEventDriftDetected}},
+				{Sink: "webhook", Events: []tfaplv1beta1.NotificationEvent{tfaplv1beta1.NotificationEventApplied, tfaplv1beta1.NotificationEventFailed}},
+			},
+		},
+	}
+}
+
+func TestNotifier_sinks(t *testing.T) {
+	n := &Notifier{Log: slog.Default()}
+	module := testModule()
+
+	tests := []struct {
+		event tfaplv1beta1.NotificationEvent
+		want  []string
+	}{
+		{tfaplv1beta1.NotificationEventFailed, []string{"slack", "webhook"}},
+		{tfaplv1beta1.NotificationEventApplied, []string{"webhook"}},
+		{tfaplv1beta1.NotificationEventDriftDetected, []string{"slack"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.event), func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, n.sinks(context.Background(), module, tt.event)); diff != "" {
+				t.Errorf("sinks() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	slack := make(chanSink, 1)
+	webhook := make(chanSink, 1)
+
+	n := &Notifier{
+		Cluster:      "prod",
+		WebserverURL: "https://tf-applier.io",
+		Sinks:        map[string]Sink{"slack": slack, "webhook": webhook},
+		Log:          slog.Default(),
+	}
+
+	run := &tfaplv1beta1.Run{
+		Request:    &tfaplv1beta1.Request{Type: tfaplv1beta1.ScheduledRun},
+		CommitHash: "abc123",
+		CommitMsg:  "update admins",
+		Summary:    "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.",
+	}
+
+	n.Notify(testModule(), run, tfaplv1beta1.NotificationEventApplied, tfaplv1beta1.ReasonApplied, "Apply complete!")
+
+	want := &Notification{
+		Event:      tfaplv1beta1.NotificationEventApplied,
+		Cluster:    "prod",
+		Module:     types.NamespacedName{Namespace: "foo", Name: "admins"},
+		Path:       "dev/admins",
+		RunType:    tfaplv1beta1.ScheduledRun,
+		CommitHash: "abc123",
+		CommitMsg:  "update admins",
+		Reason:     tfaplv1beta1.ReasonApplied,
+		Message:    "Apply complete!",
+		Summary:    "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.",
+		URL:        "https://tf-applier.io/#foo_admins",
+	}
+
+	select {
+	case got := <-webhook:
+		if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(Notification{}, "Time")); diff != "" {
+			t.Errorf("Notify() mismatch (-want +got):\n%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not sent to webhook sink")
+	}
+
+	if len(slack) != 0 {
+		t.Errorf("applied event should not be sent to slack sink")
+	}
+
+	// PR runs are not notified
+	run.Request.Type = tfaplv1beta1.PRPlan
+	n.Notify(testModule(), run, tfaplv1beta1.NotificationEventFailed, tfaplv1beta1.ReasonPlanFailed, "unable to plan module")
+	select {
+	case <-slack:
+		t.Errorf("PR run failure should not be notified")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// nil notifier is no-op
+	var nilNotifier *Notifier
+	nilNotifier.Notify(testModule(), run, tfaplv1beta1.NotificationEventFailed, tfaplv1beta1.ReasonPlanFailed, "unable to plan module")
+}
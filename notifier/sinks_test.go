@@ -0,0 +1,260 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func testNotification() *Notification {
+	return &Notification{
+		Event:      tfaplv1beta1.NotificationEventFailed,
+		Cluster:    "prod",
+		Module:     types.NamespacedName{Namespace: "foo", Name: "admins"},
+		Path:       "dev/admins",
+		RunType:    tfaplv1beta1.ScheduledRun,
+		CommitHash: "abc123",
+		Reason:     tfaplv1beta1.ReasonPlanFailed,
+		Message:    "unable to plan module <foo>",
+		URL:        "https://tf-applier.io/#foo_admins",
+		Time:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+// receiver returns test server which records request body and headers
+func receiver(t *testing.T, status int) (*httptest.Server, chan *http.Request, chan []byte) {
+	reqs := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs <- r
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, reqs, bodies
+}
+
+func TestWebhookSink(t *testing.T) {
+	server, reqs, bodies := receiver(t, http.StatusNoContent)
+
+	sink := &WebhookSink{URL: server.URL, Secret: "secret", HTTP: server.Client()}
+	if err := sink.Send(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	req, body := <-reqs, <-bodies
+
+	if got := req.Header.Get(EventHeader); got != "failed" {
+		t.Errorf("event header = %q, want failed", got)
+	}
+	if got, want := req.Header.Get(SignatureHeader), "sha256="+sign("secret", body); got != want {
+		t.Errorf("signature header = %q, want %q", got, want)
+	}
+
+	var got Notification
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unable to decode body %v", err)
+	}
+	if diff := cmp.Diff(testNotification(), &got); diff != "" {
+		t.Errorf("body mismatch (-want +got):\n%s", diff)
+	}
+
+	t.Run("non-ok status", func(t *testing.T) {
+		server, _, _ := receiver(t, http.StatusForbidden)
+		sink := &WebhookSink{URL: server.URL, HTTP: server.Client()}
+		if err := sink.Send(context.Background(), testNotification()); err == nil {
+			t.Errorf("Send() error = nil, want error")
+		}
+	})
+}
+
+func TestSlackSink(t *testing.T) {
+	server, _, bodies := receiver(t, http.StatusOK)
+
+	sink := &SlackSink{WebhookURL: server.URL, HTTP: server.Client()}
+	if err := sink.Send(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	var got slackPayload
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatalf("unable to decode body %v", err)
+	}
+	if got.Text != "⛔ foo/admins run failed on prod" {
+		t.Errorf("unexpected text %q", got.Text)
+	}
+	if len(got.Blocks) != 2 {
+		t.Fatalf("unexpected blocks %+v", got.Blocks)
+	}
+	for _, want := range []string{
+		"*Reason:* PlanFailed",
+		"*Message:* unable to plan module &lt;foo&gt;",
+		"<https://tf-applier.io/#foo_admins|View in terraform-applier web UI>",
+	} {
+		if !strings.Contains(got.Blocks[1].Text.Text, want) {
+			t.Errorf("slack details missing %q in %q", want, got.Blocks[1].Text.Text)
+		}
+	}
+}
+
+func TestTeamsSink(t *testing.T) {
+	server, _, bodies := receiver(t, http.StatusAccepted)
+
+	sink := &TeamsSink{WebhookURL: server.URL, HTTP: server.Client()}
+	if err := sink.Send(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	body := string(<-bodies)
+	for _, want := range []string{
+		`"contentType":"application/vnd.microsoft.card.adaptive"`,
+		`"text":"⛔ foo/admins run failed on prod"`,
+		`{"title":"Reason","value":"PlanFailed"}`,
+		`"url":"https://tf-applier.io/#foo_admins"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("teams payload missing %q in %s", want, body)
+		}
+	}
+}
+
+// smtpServer is minimal SMTP server which records received message
+func smtpServer(t *testing.T) (host string, port int, msgs chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	msgs = make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM"), strings.HasPrefix(cmd, "RCPT TO"):
+				data.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				msgs <- data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	addr := l.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, msgs
+}
+
+func TestEmailSink(t *testing.T) {
+	host, port, msgs := smtpServer(t)
+
+	sink := &EmailSink{
+		Host: host,
+		Port: port,
+		From: "tf-applier@example.com",
+		To:   []string{"team@example.com", "oncall@example.com"},
+	}
+	if err := sink.Send(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	msg := <-msgs
+	for _, want := range []string{
+		"MAIL FROM:<tf-applier@example.com>",
+		"RCPT TO:<team@example.com>",
+		"RCPT TO:<oncall@example.com>",
+		"To: team@example.com, oncall@example.com\r\n",
+		"Subject: =?utf-8?q?=E2=9B=94_foo/admins_run_failed_on_prod?=\r\n",
+		"Reason: PlanFailed\r\nMessage: unable to plan module <foo>\r\n",
+		"https://tf-applier.io/#foo_admins",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("email missing %q in:\n%s", want, msg)
+		}
+	}
+}
+
+func TestConfig_NewSinks(t *testing.T) {
+	t.Setenv("TEST_SLACK_URL", "https://hooks.slack.com/services/xyz")
+
+	conf := Config{Sinks: []SinkConfig{
+		{Name: "slack", Type: SinkTypeSlack, URL: "${TEST_SLACK_URL}"},
+		{Name: "teams", Type: SinkTypeTeams, URL: "https://teams.example.com"},
+		{Name: "webhook", Type: SinkTypeWebhook, URL: "https://example.com", Secret: "secret"},
+		{Name: "email", Type: SinkTypeEmail, Host: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}},
+	}}
+
+	sinks, err := conf.NewSinks()
+	if err != nil {
+		t.Fatalf("NewSinks() error = %v", err)
+	}
+	if got := sinks["slack"].(*SlackSink).WebhookURL; got != "https://hooks.slack.com/services/xyz" {
+		t.Errorf("slack url = %q, env should be expanded", got)
+	}
+	if got := sinks["email"].(*EmailSink).Port; got != 587 {
+		t.Errorf("email port = %d, want default 587", got)
+	}
+
+	for i, invalid := range []SinkConfig{
+		{Type: SinkTypeSlack, URL: "https://example.com"},
+		{Name: "slack", Type: SinkTypeSlack},
+		{Name: "email", Type: SinkTypeEmail, Host: "smtp.example.com"},
+		{Name: "foo", Type: "pagerduty", URL: "https://example.com"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if _, err := (Config{Sinks: []SinkConfig{invalid}}).NewSinks(); err == nil {
+				t.Errorf("NewSinks() error = nil, want error")
+			}
+		})
+	}
+
+	if _, err := (Config{Sinks: []SinkConfig{conf.Sinks[0], conf.Sinks[0]}}).NewSinks(); err == nil {
+		t.Errorf("NewSinks() duplicate name error = nil, want error")
+	}
+}
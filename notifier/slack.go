@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SlackSink posts notification to the Slack incoming webhook
+type SlackSink struct {
+	WebhookURL string
+	HTTP       *http.Client
+}
+
+type slackPayload struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (s *SlackSink) Send(ctx context.Context, n *Notification) error {
+	var details strings.Builder
+	for _, f := range n.Fields() {
+		fmt.Fprintf(&details, "*%s:* %s\n", f[0], slackEscape(f[1]))
+	}
+	if n.URL != "" {
+		fmt.Fprintf(&details, "<%s|View in terraform-applier web UI>", n.URL)
+	}
+
+	payload := slackPayload{
+		// text is used as fallback in notifications
+		Text: n.Title(),
+		Blocks: []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*" + slackEscape(n.Title()) + "*"}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: details.String()}},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal slack payload err:%w", err)
+	}
+	return postJSON(ctx, s.HTTP, s.WebhookURL, body, nil)
+}
+
+// slackEscape escapes control characters of slack mrkdwn
+// https://api.slack.com/reference/surfaces/formatting#escaping
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,90 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// TeamsSink posts notification as adaptive card to the Microsoft Teams
+// workflow (incoming webhook) url
+type TeamsSink struct {
+	WebhookURL string
+	HTTP       *http.Client
+}
+
+type teamsPayload struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []any         `json:"body"`
+	Actions []teamsAction `json:"actions,omitempty"`
+}
+
+type teamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Wrap   bool   `json:"wrap"`
+}
+
+type teamsFactSet struct {
+	Type  string      `json:"type"`
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+func (s *TeamsSink) Send(ctx context.Context, n *Notification) error {
+	facts := teamsFactSet{Type: "FactSet"}
+	for _, f := range n.Fields() {
+		facts.Facts = append(facts.Facts, teamsFact{Title: f[0], Value: f[1]})
+	}
+
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body: []any{
+			teamsTextBlock{Type: "TextBlock", Text: n.Title(), Weight: "Bolder", Size: "Medium", Wrap: true},
+			facts,
+		},
+	}
+	if n.URL != "" {
+		card.Actions = []teamsAction{{Type: "Action.OpenUrl", Title: "View in terraform-applier web UI", URL: n.URL}}
+	}
+
+	payload := teamsPayload{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{ContentType: "application/vnd.microsoft.card.adaptive", Content: card},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal teams payload err:%w", err)
+	}
+	return postJSON(ctx, s.HTTP, s.WebhookURL, body, nil)
+}
@@ -0,0 +1,79 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// SignatureHeader contains hex encoded HMAC SHA256 of the request body
+	// in the form of 'sha256=<signature>'
+	SignatureHeader = "X-Terraform-Applier-Signature-256"
+	// EventHeader contains the event of the notification
+	EventHeader = "X-Terraform-Applier-Event"
+)
+
+// WebhookSink posts notification as JSON to the url. if secret is set
+// request body is signed with HMAC SHA256 and set as SignatureHeader
+type WebhookSink struct {
+	URL    string
+	Secret string
+	HTTP   *http.Client
+}
+
+func (s *WebhookSink) Send(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("unable to marshal notification err:%w", err)
+	}
+
+	headers := map[string]string{EventHeader: string(n.Event)}
+	if s.Secret != "" {
+		headers[SignatureHeader] = "sha256=" + sign(s.Secret, body)
+	}
+
+	return postJSON(ctx, s.HTTP, s.URL, body, headers)
+}
+
+// sign returns hex encoded HMAC SHA256 of the body
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// postJSON posts body to the url and returns error on non 2xx response
+func postJSON(ctx context.Context, clt *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create request err:%w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	if clt == nil {
+		clt = http.DefaultClient
+	}
+
+	resp, err := clt.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send request err:%w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("non-ok status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
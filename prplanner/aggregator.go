@@ -0,0 +1,440 @@
+package prplanner
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/utilitywarehouse/git-mirror/giturl"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/webserver/oidc"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	aggregatorRunsPath = "/api/v1/runs"
+
+	// aggregatedPRExpiry is the duration after which PR results are removed
+	// if no new results are received
+	aggregatedPRExpiry = 7 * 24 * time.Hour
+
+	// maxAggregatedRunSize is the max size of the run result request body
+	maxAggregatedRunSize = 10 << 20
+
+	// maxAggregatedOutputsSize is the max total size of run outputs kept in
+	// memory, outputs of the oldest runs are dropped once its exceeded
+	maxAggregatedOutputsSize = 64 << 20
+)
+
+//go:embed templates/aggregator.html
+var aggregatorHTML string
+
+// AggregatedRun is the result of the PR run sent by terraform-applier
+// instances to the aggregator
+type AggregatedRun struct {
+	Cluster  string               `json:"cluster"`
+	RepoURL  string               `json:"repoURL"`
+	PRNumber int                  `json:"prNumber"`
+	Module   types.NamespacedName `json:"module"`
+	Path     string               `json:"path"`
+	Run      *tfaplv1beta1.Run    `json:"run"`
+	// WebserverURL is the web UI url of the sender, used for module links
+	WebserverURL string    `json:"webserverURL"`
+	ReceivedAt   time.Time `json:"receivedAt"`
+
+	// outputDropped is set if run outputs are removed to limit memory usage
+	outputDropped bool
+}
+
+// outputsSize returns size of the run outputs in bytes
+func (r *AggregatedRun) outputsSize() int {
+	return len(r.Run.InitOutput) + len(r.Run.Output)
+}
+
+// dropOutputs removes run outputs, a copy of the run is used as run
+// might be referenced by comment being rendered
+func (r *AggregatedRun) dropOutputs() {
+	run := *r.Run
+	run.InitOutput = ""
+	run.Output = ""
+	r.Run = &run
+	r.outputDropped = true
+}
+
+func (r *AggregatedRun) validate() error {
+	switch {
+	case r.Cluster == "":
+		return fmt.Errorf("cluster is required")
+	case r.RepoURL == "":
+		return fmt.Errorf("repoURL is required")
+	case r.PRNumber == 0:
+		return fmt.Errorf("prNumber is required")
+	case r.Module.Name == "" || r.Module.Namespace == "":
+		return fmt.Errorf("module namespace and name are required")
+	case r.Run == nil:
+		return fmt.Errorf("run is required")
+	}
+	if _, err := giturl.Parse(r.RepoURL); err != nil {
+		return fmt.Errorf("invalid repoURL err:%w", err)
+	}
+	return nil
+}
+
+// aggregatorClient sends PR run results to the aggregator
+type aggregatorClient struct {
+	url   string
+	token string
+	http  *http.Client
+}
+
+func (c *aggregatorClient) send(ctx context.Context, run *AggregatedRun) error {
+	body, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("unable to marshal run err:%w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+aggregatorRunsPath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create request err:%w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send run err:%w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("aggregator returned non-ok status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// forwardRunOutput sends PR run output to the aggregator
+func (p *Planner) forwardRunOutput(ctx context.Context, repoURL string, prNumber int, path string, run *tfaplv1beta1.Run) error {
+	return p.aggregator.send(ctx, &AggregatedRun{
+		Cluster:      p.ClusterEnvName,
+		RepoURL:      repoURL,
+		PRNumber:     prNumber,
+		Module:       run.Module,
+		Path:         path,
+		Run:          run,
+		WebserverURL: p.WebserverURL,
+	})
+}
+
+// aggregatedPR holds latest run results of all the modules of the PR
+// received from all the clusters
+type aggregatedPR struct {
+	RepoURL   string
+	Owner     string
+	Name      string
+	Number    int
+	CommentID int
+	UpdatedAt time.Time
+	// Runs is keyed by cluster and module
+	Runs map[string]*AggregatedRun
+}
+
+// sortedRuns returns copies of the runs ordered by cluster and module, copies
+// are returned so that they can be used after releasing the lock
+func (a *aggregatedPR) sortedRuns() []*AggregatedRun {
+	var runs []*AggregatedRun
+	for _, r := range a.Runs {
+		c := *r
+		runs = append(runs, &c)
+	}
+	slices.SortFunc(runs, func(x, y *AggregatedRun) int {
+		if c := strings.Compare(x.Cluster, y.Cluster); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Module.String(), y.Module.String())
+	})
+	return runs
+}
+
+// Aggregator receives PR run results from multiple terraform-applier instances
+// and posts single consolidated comment on the PR. it also serves dashboard
+// with latest status and summary of all the PRs, run outputs are never
+// included in the dashboard. results are only kept in memory.
+type Aggregator struct {
+	ListenAddress string
+	// Token is used to authenticate instances sending run results, its also
+	// required to access dashboard if Authenticator is not set
+	Token string
+	// Authenticator is used to authenticate dashboard users, its optional
+	Authenticator *oidc.Authenticator
+	// URL is the external url of the aggregator used for dashboard link
+	URL string
+	// Planner is only used for git hosting provider API clients
+	Planner *Planner
+	Log     *slog.Logger
+
+	mu  sync.Mutex
+	prs map[string]*aggregatedPR
+	// commentMu serialises PR comment updates
+	commentMu sync.Mutex
+}
+
+func (a *Aggregator) Start(ctx context.Context) error {
+	tmpl, err := template.New("aggregator").Parse(aggregatorHTML)
+	if err != nil {
+		return fmt.Errorf("unable to parse dashboard template err:%w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(aggregatorRunsPath, a.handleRun)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		a.handleDashboard(w, r, tmpl)
+	})
+
+	server := &http.Server{
+		Addr:    a.ListenAddress,
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.mu.Lock()
+				a.pruneLocked()
+				a.mu.Unlock()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	a.Log.Info("starting aggregator", "address", a.ListenAddress)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (a *Aggregator) authorised(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || a.Token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) == 1
+}
+
+func (a *Aggregator) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !a.authorised(r) {
+		http.Error(w, "unauthorised", http.StatusUnauthorized)
+		return
+	}
+
+	var run AggregatedRun
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxAggregatedRunSize)).Decode(&run); err != nil {
+		http.Error(w, "unable to decode run", http.StatusBadRequest)
+		return
+	}
+	if err := run.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.addRun(r.Context(), &run); err != nil {
+		a.Log.Error("unable to process run", "cluster", run.Cluster, "module", run.Module, "pr", run.PRNumber, "err", err)
+		http.Error(w, "unable to process run", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// addRun stores run result and updates consolidated PR comment
+func (a *Aggregator) addRun(ctx context.Context, run *AggregatedRun) error {
+	repo, _ := giturl.Parse(run.RepoURL)
+	key := fmt.Sprintf("%s:%d", repoKey(run.RepoURL), run.PRNumber)
+	run.ReceivedAt = time.Now()
+
+	// only the tail of the output fits in the comment
+	run.Run.InitOutput = truncateOutput(run.Run.InitOutput, summaryCharacterLimit)
+	run.Run.Output = truncateOutput(run.Run.Output, summaryCharacterLimit)
+
+	a.mu.Lock()
+	if a.prs == nil {
+		a.prs = make(map[string]*aggregatedPR)
+	}
+	pr, ok := a.prs[key]
+	if !ok {
+		pr = &aggregatedPR{
+			RepoURL: run.RepoURL,
+			Owner:   repo.Path,
+			Name:    strings.TrimSuffix(repo.Repo, ".git"),
+			Number:  run.PRNumber,
+			Runs:    make(map[string]*AggregatedRun),
+		}
+		a.prs[key] = pr
+	}
+	pr.Runs[run.Cluster+"/"+run.Module.String()] = run
+	pr.UpdatedAt = run.ReceivedAt
+	a.pruneLocked()
+	a.mu.Unlock()
+
+	a.Log.Info("run received", "cluster", run.Cluster, "module", run.Module, "pr", run.PRNumber)
+
+	return a.updateComment(ctx, key)
+}
+
+// pruneLocked removes results of inactive PRs and drops outputs of the oldest
+// runs if total size of the outputs is over the limit. a.mu must be held
+func (a *Aggregator) pruneLocked() {
+	var runs []*AggregatedRun
+	total := 0
+	for k, pr := range a.prs {
+		if time.Since(pr.UpdatedAt) > aggregatedPRExpiry {
+			delete(a.prs, k)
+			continue
+		}
+		for _, r := range pr.Runs {
+			runs = append(runs, r)
+			total += r.outputsSize()
+		}
+	}
+
+	if total <= maxAggregatedOutputsSize {
+		return
+	}
+
+	slices.SortFunc(runs, func(x, y *AggregatedRun) int {
+		return x.ReceivedAt.Compare(y.ReceivedAt)
+	})
+	for _, r := range runs {
+		if total <= maxAggregatedOutputsSize {
+			return
+		}
+		total -= r.outputsSize()
+		r.dropOutputs()
+	}
+}
+
+// truncateOutput returns last limit characters of the output
+func truncateOutput(output string, limit int) string {
+	runes := []rune(output)
+	if len(runes) <= limit {
+		return output
+	}
+	return string(runes[len(runes)-limit:])
+}
+
+// updateComment posts or updates consolidated comment of the PR
+func (a *Aggregator) updateComment(ctx context.Context, key string) error {
+	a.commentMu.Lock()
+	defer a.commentMu.Unlock()
+
+	a.mu.Lock()
+	pr := a.prs[key]
+	repoURL, owner, name, number, commentID := pr.RepoURL, pr.Owner, pr.Name, pr.Number, pr.CommentID
+	runs := pr.sortedRuns()
+	a.mu.Unlock()
+
+	provider := a.Planner.provider(repoURL)
+
+	// comment ID is lost on restart so look for existing comment on the PR
+	if commentID == 0 {
+		prInfo, err := provider.PR(ctx, owner, name, number)
+		if err != nil {
+			return fmt.Errorf("unable to get PR err:%w", err)
+		}
+		commentID = aggregatedCommentID(prInfo)
+	}
+
+	id, err := provider.postComment(owner, name, commentID, number, prComment{Body: aggregatedMsg(runs, a.URL)})
+	if err != nil {
+		return fmt.Errorf("unable to post aggregated comment err:%w", err)
+	}
+
+	a.mu.Lock()
+	pr.CommentID = id
+	a.mu.Unlock()
+	return nil
+}
+
+// aggregatedCommentID returns ID of the aggregated comment if its already
+// posted on the PR
+func aggregatedCommentID(pr *pr) int {
+	for i := len(pr.Comments.Nodes) - 1; i >= 0; i-- {
+		if isAggregatedMsg(pr.Comments.Nodes[i].Body) {
+			return pr.Comments.Nodes[i].DatabaseID
+		}
+	}
+	return 0
+}
+
+func (a *Aggregator) handleDashboard(w http.ResponseWriter, r *http.Request, tmpl *template.Template) {
+	if a.Authenticator != nil {
+		_, err := a.Authenticator.Authenticate(r.Context(), w, r)
+		if errors.Is(err, oidc.ErrRedirectRequired) {
+			return
+		}
+		if err != nil {
+			http.Error(w, "Authentication failed", http.StatusInternalServerError)
+			a.Log.Error("Authentication failed", "error", err)
+			return
+		}
+	} else if !a.authorised(r) {
+		http.Error(w, "unauthorised", http.StatusUnauthorized)
+		return
+	}
+
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	type prView struct {
+		RepoURL   string
+		Number    int
+		UpdatedAt time.Time
+		Runs      []*AggregatedRun
+	}
+
+	a.mu.Lock()
+	var prs []prView
+	for _, pr := range a.prs {
+		prs = append(prs, prView{pr.RepoURL, pr.Number, pr.UpdatedAt, pr.sortedRuns()})
+	}
+	a.mu.Unlock()
+
+	// latest updated PRs first
+	slices.SortFunc(prs, func(x, y prView) int {
+		return y.UpdatedAt.Compare(x.UpdatedAt)
+	})
+
+	if err := tmpl.Execute(w, prs); err != nil {
+		a.Log.Error("unable to render dashboard", "err", err)
+	}
+}
@@ -0,0 +1,220 @@
+package prplanner
+
+import (
+	"context"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_aggregatedMsg(t *testing.T) {
+	admins := types.NamespacedName{Namespace: "foo", Name: "admins"}
+
+	runs := []*AggregatedRun{
+		{
+			Cluster: "dev", Module: admins, Path: "dev/admins", WebserverURL: "https://dev.tf-applier.io",
+			Run: &tfaplv1beta1.Run{Status: "Ok", CommitHash: "0123456789abcdef", Summary: "Plan: 1 to add", Output: "dev plan output"},
+		},
+		{
+			Cluster: "prod", Module: admins, Path: "prod/admins", WebserverURL: "https://prod.tf-applier.io",
+			Run: &tfaplv1beta1.Run{Status: tfaplv1beta1.StatusErrored, CommitHash: "0123456789abcdef", InitOutput: "init failed", Output: "prod output"},
+		},
+	}
+
+	got := aggregatedMsg(runs, "https://aggregator.io")
+
+	for _, want := range []string{
+		"| dev | [`foo/admins`](https://dev.tf-applier.io/#foo_admins) | `0123456` | ✅ Ok | Plan: 1 to add |",
+		"| prod | [`foo/admins`](https://prod.tf-applier.io/#foo_admins) | `0123456` | ⛔ Errored |  |",
+		"[View all runs in terraform-applier aggregator dashboard](https://aggregator.io)",
+		"🏷️ **Commit:** 0123456789abcdef | 🔗 [View in dev terraform-applier web UI](https://dev.tf-applier.io/#foo_admins)",
+		"```terraform\ndev plan output\n```",
+		"```terraform\ninit failed\nprod output\n```",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("aggregatedMsg() missing %q in:\n%s", want, got)
+		}
+	}
+
+	if !isAggregatedMsg(got) {
+		t.Errorf("isAggregatedMsg() = false, want true")
+	}
+	// clusters should not consider it as their run output comment
+	if cluster, _, _, _ := parseRunOutputMsg(got); cluster != "" {
+		t.Errorf("parseRunOutputMsg() cluster = %q, want empty", cluster)
+	}
+
+	t.Run("long output is linked", func(t *testing.T) {
+		long := []*AggregatedRun{
+			{Cluster: "dev", Module: admins, WebserverURL: "https://dev.tf-applier.io", Run: &tfaplv1beta1.Run{Status: "Ok", Output: strings.Repeat("a", 40000)}},
+			{Cluster: "prod", Module: admins, WebserverURL: "https://prod.tf-applier.io", Run: &tfaplv1beta1.Run{Status: "Ok", Output: strings.Repeat("b", 40000)}},
+		}
+		got := aggregatedMsg(long, "")
+
+		if l := len([]rune(got)); l > summaryCharacterLimit {
+			t.Errorf("aggregatedMsg() length %d is over the limit", l)
+		}
+		if !strings.Contains(got, strings.Repeat("a", 40000)) {
+			t.Errorf("aggregatedMsg() first output should be included")
+		}
+		if !strings.Contains(got, "please [view it in prod terraform-applier web UI](https://prod.tf-applier.io/#foo_admins)") {
+			t.Errorf("aggregatedMsg() second output should be linked")
+		}
+	})
+}
+
+func TestAggregator(t *testing.T) {
+	ctx := context.Background()
+	goMockCtrl := gomock.NewController(t)
+	testGithub := NewMockProviderInterface(goMockCtrl)
+
+	agg := &Aggregator{
+		Token:   "secret",
+		URL:     "https://aggregator.io",
+		Planner: &Planner{github: testGithub},
+		Log:     slog.Default(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(agg.handleRun))
+	defer server.Close()
+
+	admins := types.NamespacedName{Namespace: "foo", Name: "admins"}
+
+	newPlanner := func(cluster, token string) *Planner {
+		return &Planner{
+			ClusterEnvName: cluster,
+			WebserverURL:   "https://" + cluster + ".tf-applier.io",
+			aggregator:     &aggregatorClient{url: server.URL, token: token, http: server.Client()},
+		}
+	}
+	okRun := &tfaplv1beta1.Run{Module: admins, Status: tfaplv1beta1.StatusOk, CommitHash: "c1", Output: "Ok output"}
+	erroredRun := &tfaplv1beta1.Run{Module: admins, Status: tfaplv1beta1.StatusErrored, CommitHash: "c1", Output: "Errored output"}
+
+	t.Run("invalid token is rejected", func(t *testing.T) {
+		err := newPlanner("dev", "invalid").forwardRunOutput(ctx, "git@github.com:owner-a/repo-a.git", 123, "dev/admins", okRun)
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("forwardRunOutput() error = %v, want unauthorised", err)
+		}
+	})
+
+	t.Run("runs from all clusters are posted on same comment", func(t *testing.T) {
+		existing := generateMockPR(123, "branch1", []string{aggregatedMsg(nil, "")})
+		existing.Comments.Nodes[0].DatabaseID = 555
+
+		// existing comment is looked up only once
+		testGithub.EXPECT().PR(gomock.Any(), "owner-a", "repo-a", 123).Return(existing, nil)
+
+		testGithub.EXPECT().postComment("owner-a", "repo-a", 555, 123, gomock.Any()).
+			DoAndReturn(func(_, _ string, _, _ int, c prComment) (int, error) {
+				if !strings.Contains(c.Body, "| dev | [`foo/admins`](https://dev.tf-applier.io/#foo_admins) | `c1` | ✅ Ok |  |") {
+					t.Errorf("unexpected aggregated comment %s", c.Body)
+				}
+				return 555, nil
+			})
+		testGithub.EXPECT().postComment("owner-a", "repo-a", 555, 123, gomock.Any()).
+			DoAndReturn(func(_, _ string, _, _ int, c prComment) (int, error) {
+				devIdx := strings.Index(c.Body, "Ok output")
+				prodIdx := strings.Index(c.Body, "Errored output")
+				if devIdx == -1 || prodIdx == -1 || devIdx > prodIdx {
+					t.Errorf("unexpected aggregated comment %s", c.Body)
+				}
+				return 555, nil
+			})
+
+		if err := newPlanner("dev", "secret").forwardRunOutput(ctx, "git@github.com:owner-a/repo-a.git", 123, "dev/admins", okRun); err != nil {
+			t.Fatalf("forwardRunOutput() error = %v", err)
+		}
+		if err := newPlanner("prod", "secret").forwardRunOutput(ctx, "git@github.com:owner-a/repo-a.git", 123, "prod/admins", erroredRun); err != nil {
+			t.Fatalf("forwardRunOutput() error = %v", err)
+		}
+	})
+}
+
+func TestAggregator_dashboard(t *testing.T) {
+	agg := &Aggregator{Token: "secret", Log: slog.Default()}
+	agg.prs = map[string]*aggregatedPR{
+		"github.com/owner-a/repo-a:123": {
+			RepoURL:   "git@github.com:owner-a/repo-a.git",
+			Number:    123,
+			UpdatedAt: time.Now(),
+			Runs: map[string]*AggregatedRun{
+				"dev/foo/admins": {
+					Cluster: "dev",
+					Module:  types.NamespacedName{Namespace: "foo", Name: "admins"},
+					Run:     &tfaplv1beta1.Run{Status: tfaplv1beta1.StatusOk, Summary: "Plan: 1 to add", Output: "secret output"},
+				},
+			},
+		},
+	}
+	tmpl, err := template.New("aggregator").Parse(aggregatorHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"invalid", http.StatusUnauthorized},
+		{"secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		agg.handleDashboard(rec, req, tmpl)
+
+		if rec.Code != tt.want {
+			t.Errorf("token %q: status = %d, want %d", tt.token, rec.Code, tt.want)
+		}
+		body := rec.Body.String()
+		if strings.Contains(body, "secret output") {
+			t.Errorf("dashboard must not include run output")
+		}
+		if tt.want == http.StatusOK && !strings.Contains(body, "Plan: 1 to add") {
+			t.Errorf("dashboard missing run summary:\n%s", body)
+		}
+	}
+}
+
+func TestAggregator_pruneLocked(t *testing.T) {
+	now := time.Now()
+	output := strings.Repeat("a", maxAggregatedOutputsSize/2)
+
+	newRun := func(age time.Duration) *AggregatedRun {
+		return &AggregatedRun{ReceivedAt: now.Add(-age), Run: &tfaplv1beta1.Run{Output: output}}
+	}
+	oldest, older, latest := newRun(3*time.Hour), newRun(2*time.Hour), newRun(time.Hour)
+
+	agg := &Aggregator{prs: map[string]*aggregatedPR{
+		"expired": {UpdatedAt: now.Add(-aggregatedPRExpiry - time.Hour), Runs: map[string]*AggregatedRun{"a": newRun(aggregatedPRExpiry)}},
+		"pr-1":    {UpdatedAt: now, Runs: map[string]*AggregatedRun{"a": oldest, "b": latest}},
+		"pr-2":    {UpdatedAt: now, Runs: map[string]*AggregatedRun{"a": older}},
+	}}
+
+	agg.pruneLocked()
+
+	if _, ok := agg.prs["expired"]; ok {
+		t.Errorf("expired PR should be removed")
+	}
+	if !oldest.outputDropped || oldest.Run.Output != "" {
+		t.Errorf("output of the oldest run should be dropped")
+	}
+	if older.outputDropped || latest.outputDropped {
+		t.Errorf("only oldest outputs over the limit should be dropped")
+	}
+
+	msg := aggregatedMsg([]*AggregatedRun{oldest}, "")
+	if !strings.Contains(msg, "Output is no longer available in the aggregator") {
+		t.Errorf("dropped output should be linked:\n%s", msg)
+	}
+}
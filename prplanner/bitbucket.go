@@ -0,0 +1,251 @@
+package prplanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// bitbucketClient implements ProviderInterface for Bitbucket Server (Data Center)
+// pull requests using Bitbucket REST API 1.0
+type bitbucketClient struct {
+	rootURL string
+	http    *http.Client
+	token   string
+	// maxPRAge is the duration since last update after which PRs are not fetched
+	maxPRAge time.Duration
+}
+
+// bbPage is the paged response of Bitbucket REST API
+type bbPage[T any] struct {
+	Values        []T  `json:"values"`
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+type bbPR struct {
+	ID          int    `json:"id"`
+	State       string `json:"state"`
+	Draft       bool   `json:"draft"`
+	UpdatedDate int64  `json:"updatedDate"`
+	Author      struct {
+		User bbUser `json:"user"`
+	} `json:"author"`
+	FromRef struct {
+		DisplayID string `json:"displayId"`
+	} `json:"fromRef"`
+	ToRef struct {
+		DisplayID string `json:"displayId"`
+	} `json:"toRef"`
+	Properties struct {
+		MergeCommit struct {
+			ID string `json:"id"`
+		} `json:"mergeCommit"`
+	} `json:"properties"`
+}
+
+type bbComment struct {
+	ID          int    `json:"id"`
+	Version     int    `json:"version"`
+	Text        string `json:"text"`
+	UpdatedDate int64  `json:"updatedDate"`
+	Author      bbUser `json:"author"`
+}
+
+type bbUser struct {
+	Name string `json:"name"`
+}
+
+type bbActivity struct {
+	Action  string     `json:"action"`
+	Comment *bbComment `json:"comment"`
+}
+
+func (bc *bitbucketClient) openPRs(ctx context.Context, repoOwner, repoName string) ([]*pr, error) {
+	repoOwner, repoName = bitbucketRepo(repoOwner, repoName)
+
+	var prs []*pr
+	reqURL := fmt.Sprintf("%s/pull-requests?state=OPEN&order=NEWEST&limit=50", bc.repoURL(repoOwner, repoName))
+	for start := 0; ; {
+		var result bbPage[bbPR]
+		if err := bc.do(ctx, http.MethodGet, fmt.Sprintf("%s&start=%d", reqURL, start), nil, &result); err != nil {
+			return nil, fmt.Errorf("unable to get PRs, error :%w", err)
+		}
+
+		for _, p := range result.Values {
+			// PRs are not sorted by update time so stale PRs can be on any page
+			if bc.maxPRAge > 0 && time.Since(time.UnixMilli(p.UpdatedDate)) > bc.maxPRAge {
+				continue
+			}
+			pr, err := bc.toPR(ctx, repoOwner, repoName, p)
+			if err != nil {
+				return nil, err
+			}
+			prs = append(prs, pr)
+		}
+
+		if result.IsLastPage || len(result.Values) == 0 {
+			break
+		}
+		start = result.NextPageStart
+	}
+
+	return prs, nil
+}
+
+func (bc *bitbucketClient) PR(ctx context.Context, repoOwner, repoName string, prNumber int) (*pr, error) {
+	repoOwner, repoName = bitbucketRepo(repoOwner, repoName)
+
+	var p bbPR
+	reqURL := fmt.Sprintf("%s/pull-requests/%d", bc.repoURL(repoOwner, repoName), prNumber)
+	if err := bc.do(ctx, http.MethodGet, reqURL, nil, &p); err != nil {
+		return nil, fmt.Errorf("unable to get PR, err:%w", err)
+	}
+
+	return bc.toPR(ctx, repoOwner, repoName, p)
+}
+
+func (bc *bitbucketClient) postComment(repoOwner, repoName string, commentID, prNumber int, commentBody prComment) (int, error) {
+	repoOwner, repoName = bitbucketRepo(repoOwner, repoName)
+	ctx := context.Background()
+	method := http.MethodPost
+	reqURL := fmt.Sprintf("%s/pull-requests/%d/comments", bc.repoURL(repoOwner, repoName), prNumber)
+
+	payload := struct {
+		Text    string `json:"text"`
+		Version *int   `json:"version,omitempty"`
+	}{Text: commentBody.Body}
+
+	// if comment ID provided update same comment, bitbucket requires
+	// current version of the comment for update
+	if commentID != 0 {
+		method = http.MethodPut
+		reqURL = fmt.Sprintf("%s/%d", reqURL, commentID)
+
+		var current bbComment
+		if err := bc.do(ctx, http.MethodGet, reqURL, nil, &current); err != nil {
+			return 0, fmt.Errorf("unable to get current PR comment: %w", err)
+		}
+		payload.Version = &current.Version
+	}
+
+	var comment bbComment
+	if err := bc.do(ctx, method, reqURL, payload, &comment); err != nil {
+		return 0, fmt.Errorf("error posting PR comment: %w", err)
+	}
+
+	return comment.ID, nil
+}
+
+// toPR converts Bitbucket pull request and its comments to pr
+func (bc *bitbucketClient) toPR(ctx context.Context, repoOwner, repoName string, p bbPR) (*pr, error) {
+	result := &pr{
+		Number:      p.ID,
+		BaseRefName: p.ToRef.DisplayID,
+		HeadRefName: p.FromRef.DisplayID,
+		IsDraft:     p.Draft,
+		Closed:      p.State != "OPEN",
+		Merged:      p.State == "MERGED",
+		UpdatedAt:   time.UnixMilli(p.UpdatedDate).UTC(),
+		Author:      author{Login: p.Author.User.Name},
+	}
+	result.BaseRepository.Name = repoName
+	result.BaseRepository.Owner.Login = repoOwner
+	// bitbucket clone urls use lower case project key, its only used for
+	// display as repo urls are compared using giturl which is case insensitive
+	result.BaseRepository.URL = fmt.Sprintf("%s/scm/%s/%s.git", bc.rootURL, strings.ToLower(repoOwner), repoName)
+	result.MergeCommit.Oid = p.Properties.MergeCommit.ID
+
+	// comments are only available via activities which are returned newest first,
+	// pages are fetched until oldest activity or max pages limit is reached
+	// same as GitHub PR comments
+	var activities []bbActivity
+	reqURL := fmt.Sprintf("%s/pull-requests/%d/activities?limit=100", bc.repoURL(repoOwner, repoName), p.ID)
+	start := 0
+	for range maxCommentPages {
+		var page bbPage[bbActivity]
+		if err := bc.do(ctx, http.MethodGet, fmt.Sprintf("%s&start=%d", reqURL, start), nil, &page); err != nil {
+			return nil, fmt.Errorf("unable to get PR activities, err:%w", err)
+		}
+		activities = append(activities, page.Values...)
+
+		if page.IsLastPage || len(page.Values) == 0 {
+			break
+		}
+		start = page.NextPageStart
+	}
+
+	seen := make(map[int]bool)
+	for _, a := range activities {
+		// edited comment will have multiple activities
+		if a.Action != "COMMENTED" || a.Comment == nil || seen[a.Comment.ID] {
+			continue
+		}
+		seen[a.Comment.ID] = true
+		result.Comments.Nodes = append(result.Comments.Nodes, prComment{
+			DatabaseID: a.Comment.ID,
+			Author:     author{Login: a.Comment.Author.Name},
+			Body:       a.Comment.Text,
+			UpdatedAt:  time.UnixMilli(a.Comment.UpdatedDate).UTC(),
+		})
+	}
+	// comments are expected in chronological order
+	slices.Reverse(result.Comments.Nodes)
+
+	return result, nil
+}
+
+func (bc *bitbucketClient) repoURL(projectKey, repoSlug string) string {
+	return fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s", bc.rootURL, url.PathEscape(projectKey), url.PathEscape(repoSlug))
+}
+
+// bitbucketRepo returns project key and repo slug, http clone urls
+// of bitbucket server contains 'scm' prefix
+// i.e. https://bitbucket.example.com/scm/proj/repo.git
+func bitbucketRepo(repoOwner, repoName string) (string, string) {
+	return strings.TrimPrefix(repoOwner, "scm/"), strings.TrimSuffix(repoName, ".git")
+}
+
+func (bc *bitbucketClient) do(ctx context.Context, method, reqURL string, payload, result any) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("error marshalling payload to JSON: %w", err)
+		}
+		body = bytes.NewBuffer(data)
+	}
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return fmt.Errorf("error creating HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+bc.token)
+
+	// Send the HTTP request
+	resp, err := bc.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending HTTP request: %w", err)
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	// Check the response status
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		return fmt.Errorf("HTTP error: %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
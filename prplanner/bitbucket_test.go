@@ -0,0 +1,237 @@
+package prplanner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/utilitywarehouse/git-mirror/giturl"
+)
+
+// newBitbucketStandIn returns test server which serves minimal subset of
+// Bitbucket Server API used by bitbucketClient
+func newBitbucketStandIn(t *testing.T, comments *[]map[string]any) *httptest.Server {
+	t.Helper()
+
+	pull := map[string]any{
+		"id":          5,
+		"state":       "OPEN",
+		"draft":       false,
+		"updatedDate": time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC).UnixMilli(),
+		"author":      map[string]any{"user": map[string]any{"name": "alice"}},
+		"fromRef":     map[string]any{"displayId": "feature"},
+		"toRef":       map[string]any{"displayId": "main"},
+	}
+	stalePull := map[string]any{
+		"id":          3,
+		"state":       "OPEN",
+		"updatedDate": time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC).UnixMilli(),
+		"author":      map[string]any{"user": map[string]any{"name": "bob"}},
+		"fromRef":     map[string]any{"displayId": "stale"},
+		"toRef":       map[string]any{"displayId": "main"},
+	}
+	olderPull := map[string]any{
+		"id":          4,
+		"state":       "OPEN",
+		"updatedDate": time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC).UnixMilli(),
+		"author":      map[string]any{"user": map[string]any{"name": "bob"}},
+		"fromRef":     map[string]any{"displayId": "older"},
+		"toRef":       map[string]any{"displayId": "main"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rest/api/1.0/projects/INFRA/repos/terraform/pull-requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != "OPEN" {
+			t.Errorf("unexpected state query %q", r.URL.Query().Get("state"))
+		}
+		writeBitbucketPage(w, r, []any{pull, stalePull, olderPull})
+	})
+	mux.HandleFunc("GET /rest/api/1.0/projects/INFRA/repos/terraform/pull-requests/5", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(pull)
+	})
+	mux.HandleFunc("GET /rest/api/1.0/projects/INFRA/repos/terraform/pull-requests/4", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(olderPull)
+	})
+	mux.HandleFunc("GET /rest/api/1.0/projects/INFRA/repos/terraform/pull-requests/{id}/activities", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "3" {
+			t.Errorf("activities of stale PR should not be fetched")
+		}
+		// activities are returned newest first
+		activities := []any{map[string]any{"action": "APPROVED"}}
+		for i := len(*comments) - 1; i >= 0; i-- {
+			activities = append(activities, map[string]any{"action": "COMMENTED", "commentAction": "EDITED", "comment": (*comments)[i]})
+			activities = append(activities, map[string]any{"action": "COMMENTED", "commentAction": "ADDED", "comment": (*comments)[i]})
+		}
+		writeBitbucketPage(w, r, activities)
+	})
+	mux.HandleFunc("POST /rest/api/1.0/projects/INFRA/repos/terraform/pull-requests/5/comments", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		c := map[string]any{
+			"id":          100 + len(*comments),
+			"version":     0,
+			"text":        payload["text"],
+			"updatedDate": time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC).UnixMilli(),
+			"author":      map[string]any{"name": "tf-applier"},
+		}
+		*comments = append(*comments, c)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(c)
+	})
+	mux.HandleFunc("GET /rest/api/1.0/projects/INFRA/repos/terraform/pull-requests/5/comments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for _, c := range *comments {
+			if r.PathValue("id") == jsonNumber(c["id"]) {
+				json.NewEncoder(w).Encode(c)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("PUT /rest/api/1.0/projects/INFRA/repos/terraform/pull-requests/5/comments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		for _, c := range *comments {
+			if r.PathValue("id") != jsonNumber(c["id"]) {
+				continue
+			}
+			// bitbucket rejects update of stale version
+			if jsonNumber(payload["version"]) != jsonNumber(c["version"]) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			c["text"] = payload["text"]
+			c["version"] = c["version"].(int) + 1
+			json.NewEncoder(w).Encode(c)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+// writeBitbucketPage writes single item per page so that pagination is always
+// exercised, 'isLastPage' and 'nextPageStart' are set accordingly
+func writeBitbucketPage(w http.ResponseWriter, r *http.Request, items []any) {
+	start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+	page := map[string]any{"values": []any{}, "isLastPage": start >= len(items)-1}
+	if start < len(items) {
+		page["values"] = []any{items[start]}
+	}
+	if start < len(items)-1 {
+		page["nextPageStart"] = start + 1
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+func Test_bitbucketClient(t *testing.T) {
+	ctx := context.Background()
+
+	comments := []map[string]any{
+		{"id": 1, "version": 0, "text": "@terraform-applier plan foo", "updatedDate": time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC).UnixMilli(), "author": map[string]any{"name": "bob"}},
+		{"id": 2, "version": 0, "text": "lgtm", "updatedDate": time.Date(2024, 5, 1, 10, 2, 0, 0, time.UTC).UnixMilli(), "author": map[string]any{"name": "carol"}},
+	}
+	server := newBitbucketStandIn(t, &comments)
+	defer server.Close()
+
+	bc := &bitbucketClient{rootURL: server.URL, http: server.Client(), token: "secret", maxPRAge: time.Since(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))}
+
+	wantPR := &pr{
+		Number:      5,
+		BaseRefName: "main",
+		HeadRefName: "feature",
+		UpdatedAt:   time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Author:      author{Login: "alice"},
+	}
+	wantPR.BaseRepository.Name = "terraform"
+	wantPR.BaseRepository.Owner.Login = "INFRA"
+	wantPR.BaseRepository.URL = server.URL + "/scm/infra/terraform.git"
+	wantPR.Comments.Nodes = []prComment{
+		{DatabaseID: 1, Author: author{Login: "bob"}, Body: "@terraform-applier plan foo", UpdatedAt: time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC)},
+		{DatabaseID: 2, Author: author{Login: "carol"}, Body: "lgtm", UpdatedAt: time.Date(2024, 5, 1, 10, 2, 0, 0, time.UTC)},
+	}
+
+	olderPR := &pr{
+		Number:      4,
+		BaseRefName: "main",
+		HeadRefName: "older",
+		UpdatedAt:   time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC),
+		Author:      author{Login: "bob"},
+	}
+	olderPR.BaseRepository = wantPR.BaseRepository
+	olderPR.Comments = wantPR.Comments
+
+	// PRs are fetched from all the pages and stale PRs are skipped
+	t.Run("open PRs", func(t *testing.T) {
+		// owner and repo parsed from http clone url
+		got, err := bc.openPRs(ctx, "scm/INFRA", "terraform.git")
+		if err != nil {
+			t.Fatalf("openPRs() error = %v", err)
+		}
+		if diff := cmp.Diff([]*pr{wantPR, olderPR}, got); diff != "" {
+			t.Errorf("openPRs() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("get PR", func(t *testing.T) {
+		got, err := bc.PR(ctx, "INFRA", "terraform", 5)
+		if err != nil {
+			t.Fatalf("PR() error = %v", err)
+		}
+		if diff := cmp.Diff(wantPR, got); diff != "" {
+			t.Errorf("PR() mismatch (-want +got):\n%s", diff)
+		}
+
+		// repo url uses lower case project key but it must match module's
+		// repo url with upper case project key
+		gotURL := strings.Replace(got.BaseRepository.URL, server.URL, "https://bitbucket.example.com", 1)
+		if ok, _ := giturl.SameRawURL("https://bitbucket.example.com/scm/INFRA/terraform.git", gotURL); !ok {
+			t.Errorf("PR repo url %q doesn't match module repo url", got.BaseRepository.URL)
+		}
+	})
+
+	t.Run("post and update comment", func(t *testing.T) {
+		id, err := bc.postComment("INFRA", "terraform", 0, 5, prComment{Body: "pending"})
+		if err != nil {
+			t.Fatalf("postComment() error = %v", err)
+		}
+		if id != 102 {
+			t.Errorf("postComment() id = %d, want 102", id)
+		}
+
+		// update twice to verify current version is used
+		for _, body := range []string{"output", "new output"} {
+			gotID, err := bc.postComment("INFRA", "terraform", id, 5, prComment{Body: body})
+			if err != nil {
+				t.Fatalf("postComment() update error = %v", err)
+			}
+			if gotID != id {
+				t.Errorf("postComment() updated id = %d, want %d", gotID, id)
+			}
+		}
+
+		got, err := bc.PR(ctx, "INFRA", "terraform", 5)
+		if err != nil {
+			t.Fatalf("PR() error = %v", err)
+		}
+		last := got.Comments.Nodes[len(got.Comments.Nodes)-1]
+		if last.Body != "new output" {
+			t.Errorf("updated comment body = %q, want %q", last.Body, "new output")
+		}
+	})
+}
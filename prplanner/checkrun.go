@@ -0,0 +1,251 @@
+package prplanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	checkRunQueued     = "queued"
+	checkRunInProgress = "in_progress"
+	checkRunCompleted  = "completed"
+
+	// GitHub API limits
+	checkRunTextLimit        = 65000
+	checkRunAnnotationsLimit = 50
+)
+
+// errorLocationRegex finds terraform error and location of the error in the output
+// │ Error: Unsupported argument
+// │
+// │   on main.tf line 12, in resource "aws_s3_bucket" "b":
+var errorLocationRegex = regexp.MustCompile(`(?m)Error: (.+)\n(?:[│ \t]*\n)*[│ \t]*on (\S+) line (\d+)`)
+
+// checkRunProvider is implemented by providers which supports check runs
+type checkRunProvider interface {
+	createCheckRun(ctx context.Context, repoOwner, repoName string, cr checkRun) (int64, error)
+	updateCheckRun(ctx context.Context, repoOwner, repoName string, id int64, cr checkRun) error
+}
+
+type checkRun struct {
+	Name        string          `json:"name,omitempty"`
+	HeadSHA     string          `json:"head_sha,omitempty"`
+	ExternalID  string          `json:"external_id,omitempty"`
+	DetailsURL  string          `json:"details_url,omitempty"`
+	Status      string          `json:"status,omitempty"`
+	Conclusion  string          `json:"conclusion,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	Output      *checkRunOutput `json:"output,omitempty"`
+}
+
+type checkRunOutput struct {
+	Title       string               `json:"title"`
+	Summary     string               `json:"summary"`
+	Text        string               `json:"text,omitempty"`
+	Annotations []checkRunAnnotation `json:"annotations,omitempty"`
+}
+
+type checkRunAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Title           string `json:"title,omitempty"`
+	Message         string `json:"message"`
+}
+
+func (gc *gitHubClient) createCheckRun(ctx context.Context, repoOwner, repoName string, cr checkRun) (int64, error) {
+	repoName = strings.TrimSuffix(repoName, ".git")
+	reqURL := fmt.Sprintf("%s/repos/%s/%s/check-runs", gc.rootURL, repoOwner, repoName)
+
+	var result struct {
+		ID int64 `json:"id"`
+	}
+	if err := gc.rest(ctx, http.MethodPost, reqURL, cr, &result); err != nil {
+		return 0, fmt.Errorf("unable to create check run: %w", err)
+	}
+	return result.ID, nil
+}
+
+func (gc *gitHubClient) updateCheckRun(ctx context.Context, repoOwner, repoName string, id int64, cr checkRun) error {
+	repoName = strings.TrimSuffix(repoName, ".git")
+	reqURL := fmt.Sprintf("%s/repos/%s/%s/check-runs/%d", gc.rootURL, repoOwner, repoName, id)
+
+	if err := gc.rest(ctx, http.MethodPatch, reqURL, cr, nil); err != nil {
+		return fmt.Errorf("unable to update check run: %w", err)
+	}
+	return nil
+}
+
+func (gc *gitHubClient) rest(ctx context.Context, method, reqURL string, payload, result any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error marshalling payload to JSON: %w", err)
+	}
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("error creating HTTP request: %w", err)
+	}
+
+	// Set headers
+	_, token, err := gc.credsProvider.Creds(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to provide creds err:%w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	// Send the HTTP request
+	resp, err := gc.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending HTTP request: %w", err)
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	// Check the response status
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		return fmt.Errorf("HTTP error: %s", resp.Status)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// statusContext returns unique name of the check run or commit status context
+// for the module in the cluster
+func statusContext(cluster string, module types.NamespacedName) string {
+	return fmt.Sprintf("terraform-applier/%s/%s", cluster, module)
+}
+
+// createCheckRun creates queued check run on PR's head commit if check runs
+// are enabled for the repository
+func (p *Planner) createCheckRun(ctx context.Context, pr *pr, module *tfaplv1beta1.Module) int64 {
+	if !p.repoConfig(pr.BaseRepository.URL).CheckRuns || pr.HeadRefOid == "" {
+		return 0
+	}
+	crp, ok := p.provider(pr.BaseRepository.URL).(checkRunProvider)
+	if !ok {
+		return 0
+	}
+
+	id, err := crp.createCheckRun(ctx, pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, checkRun{
+		Name:       statusContext(p.ClusterEnvName, module.NamespacedName()),
+		HeadSHA:    pr.HeadRefOid,
+		ExternalID: module.NamespacedName().String(),
+		DetailsURL: sysutil.ModuleWebURL(p.WebserverURL, module.NamespacedName()),
+		Status:     checkRunQueued,
+	})
+	if err != nil {
+		p.Log.Error("unable to create check run", "module", module.NamespacedName(), "pr", pr.Number, "err", err)
+		return 0
+	}
+	return id
+}
+
+// startCheckRun marks check run of the request as in progress
+func (p *Planner) startCheckRun(ctx context.Context, pr *pr, req *tfaplv1beta1.Request) {
+	if req.PR == nil || req.PR.CheckRunID == 0 {
+		return
+	}
+	crp, ok := p.provider(pr.BaseRepository.URL).(checkRunProvider)
+	if !ok {
+		return
+	}
+
+	err := crp.updateCheckRun(ctx, pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, req.PR.CheckRunID, checkRun{Status: checkRunInProgress})
+	if err != nil {
+		p.Log.Error("unable to update check run", "pr", pr.Number, "err", err)
+	}
+}
+
+// completeCheckRun completes check run of the PR run with plan output
+func (p *Planner) completeCheckRun(ctx context.Context, repoURL, repoOwner, repoName, modulePath string, run *tfaplv1beta1.Run) {
+	if run.Request == nil || run.Request.PR == nil || run.Request.PR.CheckRunID == 0 {
+		return
+	}
+	crp, ok := p.provider(repoURL).(checkRunProvider)
+	if !ok {
+		return
+	}
+
+	err := crp.updateCheckRun(ctx, repoOwner, repoName, run.Request.PR.CheckRunID, completedCheckRun(p.ClusterEnvName, modulePath, run))
+	if err != nil {
+		p.Log.Error("unable to complete check run", "module", run.Module, "pr", run.Request.PR.Number, "err", err)
+		return
+	}
+	p.Log.Info("check run completed", "module", run.Module, "pr", run.Request.PR.Number)
+}
+
+func completedCheckRun(cluster, modulePath string, run *tfaplv1beta1.Run) checkRun {
+	now := time.Now()
+	conclusion := "success"
+	runOutput := run.Output
+	if run.Status != tfaplv1beta1.StatusOk {
+		conclusion = "failure"
+		// init output may contain reason of the failure
+		runOutput = run.InitOutput + "\n" + run.Output
+	}
+
+	runes := []rune(runOutput)
+	if len(runes) > checkRunTextLimit {
+		runOutput = "Plan output is truncated from the top.\n" + string(runes[(len(runes)-checkRunTextLimit):])
+	}
+
+	return checkRun{
+		Status:      checkRunCompleted,
+		Conclusion:  conclusion,
+		CompletedAt: &now,
+		Output: &checkRunOutput{
+			Title:       fmt.Sprintf("%s: %s", cluster, run.Summary),
+			Summary:     fmt.Sprintf("**Module:** `%s` | **Commit:** %s | **Run Status:** %s\n\n%s", run.Module, run.CommitHash, run.Status, run.Summary),
+			Text:        "```terraform\n" + runOutput + "\n```",
+			Annotations: errorAnnotations(modulePath, runOutput),
+		},
+	}
+}
+
+// errorAnnotations returns annotations for terraform errors with file location.
+// errors from remote modules are skipped
+func errorAnnotations(modulePath, output string) []checkRunAnnotation {
+	var annotations []checkRunAnnotation
+	for _, m := range errorLocationRegex.FindAllStringSubmatch(output, -1) {
+		if len(annotations) == checkRunAnnotationsLimit {
+			break
+		}
+		if strings.HasPrefix(m[2], ".terraform/") {
+			continue
+		}
+		line, _ := strconv.Atoi(m[3])
+		annotations = append(annotations, checkRunAnnotation{
+			Path:            path.Join(modulePath, m[2]),
+			StartLine:       line,
+			EndLine:         line,
+			AnnotationLevel: "failure",
+			Title:           "terraform error",
+			Message:         strings.TrimSpace(m[1]),
+		})
+	}
+	return annotations
+}
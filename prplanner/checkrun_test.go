@@ -0,0 +1,159 @@
+package prplanner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_errorAnnotations(t *testing.T) {
+	output := `aws_s3_bucket.a: Refreshing state...
+╷
+│ Error: Unsupported argument
+│ 
+│   on main.tf line 12, in resource "aws_s3_bucket" "b":
+│   12:   foo = "bar"
+│ 
+│ An argument named "foo" is not expected here.
+╵
+╷
+│ Error: Invalid function argument
+│ 
+│   on ../modules/vpc/vars.tf line 3, in locals:
+╵
+╷
+│ Error: Unsupported argument
+│ 
+│   on .terraform/modules/remote/main.tf line 5, in module "remote":
+╵
+Error: No configuration files
+`
+	want := []checkRunAnnotation{
+		{Path: "dev/aws/main.tf", StartLine: 12, EndLine: 12, AnnotationLevel: "failure", Title: "terraform error", Message: "Unsupported argument"},
+		{Path: "dev/modules/vpc/vars.tf", StartLine: 3, EndLine: 3, AnnotationLevel: "failure", Title: "terraform error", Message: "Invalid function argument"},
+	}
+
+	got := errorAnnotations("dev/aws", output)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("errorAnnotations() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_checkRuns(t *testing.T) {
+	ctx := context.Background()
+	goMockCtrl := gomock.NewController(t)
+
+	testCreds := sysutil.NewMockCredsProvider(goMockCtrl)
+	testCreds.EXPECT().Creds(gomock.Any()).Return("", "secret", nil).AnyTimes()
+
+	var got []checkRun
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/owner-a/repo-a/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		var cr checkRun
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &cr)
+		got = append(got, cr)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 42}`))
+	})
+	mux.HandleFunc("PATCH /repos/owner-a/repo-a/check-runs/42", func(w http.ResponseWriter, r *http.Request) {
+		var cr checkRun
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &cr)
+		// completed at is set to current time
+		cr.CompletedAt = nil
+		got = append(got, cr)
+		w.Write([]byte(`{"id": 42}`))
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	planner := &Planner{
+		ClusterEnvName: "dev",
+		WebserverURL:   "https://applier.example.com",
+		Log:            slog.Default(),
+		github:         &gitHubClient{rootURL: server.URL, http: server.Client(), credsProvider: testCreds},
+		Config: Config{Repositories: []RepoConfig{
+			{Remote: "git@github.com:owner-a/repo-a.git", CheckRuns: true},
+		}},
+	}
+
+	module := &tfaplv1beta1.Module{}
+	module.Name = "one"
+	module.Namespace = "foo"
+
+	p := &pr{Number: 1, HeadRefOid: "abc123"}
+	p.BaseRepository.Name = "repo-a"
+	p.BaseRepository.Owner.Login = "owner-a"
+	p.BaseRepository.URL = "https://github.com/owner-a/repo-a"
+
+	t.Run("check runs disabled for repo", func(t *testing.T) {
+		p := *p
+		p.BaseRepository.URL = "https://github.com/owner-a/repo-b"
+		if id := planner.createCheckRun(ctx, &p, module); id != 0 {
+			t.Errorf("createCheckRun() = %d, want 0", id)
+		}
+	})
+
+	t.Run("check run lifecycle", func(t *testing.T) {
+		id := planner.createCheckRun(ctx, p, module)
+		if id != 42 {
+			t.Fatalf("createCheckRun() = %d, want 42", id)
+		}
+
+		req := &tfaplv1beta1.Request{PR: &tfaplv1beta1.PullRequest{Number: 1, CheckRunID: id}}
+		planner.startCheckRun(ctx, p, req)
+
+		run := &tfaplv1beta1.Run{
+			Module:     types.NamespacedName{Namespace: "foo", Name: "one"},
+			Request:    req,
+			Status:     tfaplv1beta1.StatusErrored,
+			CommitHash: "abc123",
+			Summary:    "unable to plan module",
+			Output:     "Error: Unsupported argument\n\n  on main.tf line 2, in resource:",
+		}
+		planner.completeCheckRun(ctx, p.BaseRepository.URL, "owner-a", "repo-a.git", "dev/one", run)
+
+		want := []checkRun{
+			{
+				Name:       "terraform-applier/dev/foo/one",
+				HeadSHA:    "abc123",
+				ExternalID: "foo/one",
+				DetailsURL: "https://applier.example.com/#foo_one",
+				Status:     "queued",
+			},
+			{Status: "in_progress"},
+			{
+				Status:     "completed",
+				Conclusion: "failure",
+				Output: &checkRunOutput{
+					Title:   "dev: unable to plan module",
+					Summary: "**Module:** `foo/one` | **Commit:** abc123 | **Run Status:** Errored\n\nunable to plan module",
+					Text:    "```terraform\n\nError: Unsupported argument\n\n  on main.tf line 2, in resource:\n```",
+					Annotations: []checkRunAnnotation{
+						{Path: "dev/one/main.tf", StartLine: 2, EndLine: 2, AnnotationLevel: "failure", Title: "terraform error", Message: "Unsupported argument"},
+					},
+				},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("check runs mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package prplanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// processPRCommands handles help, unlock and cancel commands posted as PR comments.
+// reply is posted for every command so that its only processed once
+func (p *Planner) processPRCommands(ctx context.Context, pr *pr, prModules []types.NamespacedName, kubeModules *tfaplv1beta1.ModuleList) {
+	staleAfter := p.plannerConfig(pr.BaseRepository.URL).StaleAfter
+
+	for _, comment := range pr.Comments.Nodes {
+		// skip old comments
+		if time.Since(comment.UpdatedAt) > staleAfter {
+			continue
+		}
+
+		cmd := parseCommandMsg(comment.Body)
+		if cmd == nil {
+			continue
+		}
+
+		if isCommandReplyPosted(p.ClusterEnvName, pr, comment.DatabaseID) {
+			continue
+		}
+
+		var reply string
+		switch cmd.Name {
+		case "help":
+			reply = helpMsg(p.ClusterEnvName, comment.DatabaseID)
+		default:
+			reply = p.runModuleCommand(ctx, pr, comment, cmd, prModules, kubeModules)
+		}
+
+		_, err := p.provider(pr.BaseRepository.URL).postComment(pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, 0, pr.Number, prComment{Body: reply})
+		if err != nil {
+			p.Log.Error("unable to post command reply", "pr", pr.Number, "command", cmd.Name, "err", err)
+		}
+	}
+}
+
+// runModuleCommand runs unlock or cancel command on the PR module and returns reply
+func (p *Planner) runModuleCommand(ctx context.Context, pr *pr, comment prComment, cmd *prCommand, prModules []types.NamespacedName, kubeModules *tfaplv1beta1.ModuleList) string {
+	cmdStr := cmd.Name + " " + cmd.Module
+
+	module := findPRModule(cmd.Module, prModules, kubeModules)
+	if module == nil {
+		return commandReplyMsg(p.ClusterEnvName, comment.DatabaseID, cmdStr, "⛔", fmt.Sprintf("module `%s` is not part of this PR", cmd.Module))
+	}
+
+	// RBAC can be set on module defaults
+	if err := sysutil.MergeModuleDefaults(ctx, p.ClusterClt, module); err != nil {
+		p.Log.Error("unable to get module defaults", "module", module.NamespacedName(), "err", err)
+		return commandReplyMsg(p.ClusterEnvName, comment.DatabaseID, cmdStr, "⛔", "internal error")
+	}
+
+	if !p.canRunPRCommand(pr, comment.Author.Login, module) {
+		p.Log.Info("PR command denied", "module", module.NamespacedName(), "pr", pr.Number, "command", cmd.Name, "author", comment.Author.Login)
+		return commandReplyMsg(p.ClusterEnvName, comment.DatabaseID, cmdStr, "⛔", fmt.Sprintf("`%s` is not an Admin of the module", comment.Author.Login))
+	}
+
+	switch cmd.Name {
+	case "unlock":
+		// state is unlocked as part of the plan run same as force unlock from web UI
+		req := module.NewRunRequest(tfaplv1beta1.ForcedPlan, cmd.LockID)
+		err := sysutil.EnsureRequest(ctx, p.ClusterClt, module.NamespacedName(), req)
+		switch {
+		case err == nil:
+		case errors.Is(err, tfaplv1beta1.ErrRunRequestExist):
+			return commandReplyMsg(p.ClusterEnvName, comment.DatabaseID, cmdStr, "⛔", "another run request is pending, please try again later")
+		default:
+			p.Log.Error("unable to request unlock run", "module", module.NamespacedName(), "err", err)
+			return commandReplyMsg(p.ClusterEnvName, comment.DatabaseID, cmdStr, "⛔", "internal error")
+		}
+		p.Log.Info("unlock requested via comment", "module", module.NamespacedName(), "pr", pr.Number, "author", comment.Author.Login, "lockID", cmd.LockID)
+		return commandReplyMsg(p.ClusterEnvName, comment.DatabaseID, cmdStr, "✅", fmt.Sprintf("plan run with force unlock of `%s` is requested", cmd.LockID))
+
+	case "cancel":
+		// only runs requested by this PR can be cancelled
+		isPRRun := func(req *tfaplv1beta1.Request) bool {
+			return req != nil && req.IsPRRun() && req.PR != nil && req.PR.Number == pr.Number
+		}
+		if p.RunStatus == nil || !p.RunStatus.Cancel(module.NamespacedName().String(), isPRRun) {
+			return commandReplyMsg(p.ClusterEnvName, comment.DatabaseID, cmdStr, "⛔", "module is not running a request of this PR")
+		}
+		p.Log.Info("run cancelled via comment", "module", module.NamespacedName(), "pr", pr.Number, "author", comment.Author.Login)
+		return commandReplyMsg(p.ClusterEnvName, comment.DatabaseID, cmdStr, "✅", "current run is cancelled")
+	}
+
+	return commandReplyMsg(p.ClusterEnvName, comment.DatabaseID, cmdStr, "⛔", "unknown command")
+}
+
+// findPRModule returns copy of the PR module matching given name or path
+func findPRModule(nameOrPath string, prModules []types.NamespacedName, kubeModules *tfaplv1beta1.ModuleList) *tfaplv1beta1.Module {
+	for _, m := range kubeModules.Items {
+		if m.Name != nameOrPath && m.Spec.Path != nameOrPath {
+			continue
+		}
+		for _, name := range prModules {
+			if m.NamespacedName() == name {
+				return m.DeepCopy()
+			}
+		}
+	}
+	return nil
+}
+
+// isCommandReplyPosted loops through all the comments to check if reply
+// is already posted for the given command comment
+func isCommandReplyPosted(cluster string, pr *pr, commentID int) bool {
+	for _, comment := range pr.Comments.Nodes {
+		replyCluster, replyTo := parseCommandReplyMsg(comment.Body)
+		if replyCluster == cluster && replyTo == commentID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package prplanner
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_processPRCommands(t *testing.T) {
+	ctx := context.Background()
+
+	kubeModuleList := &tfaplv1beta1.ModuleList{
+		Items: []tfaplv1beta1.Module{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "one"},
+				Spec: tfaplv1beta1.ModuleSpec{
+					Path: "foo/one",
+					RBAC: []tfaplv1beta1.RBAC{{
+						Role:     "Admin",
+						Subjects: []tfaplv1beta1.Subject{{Kind: "GitHubUser", Name: "admin"}},
+					}},
+				},
+			},
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "two"}, Spec: tfaplv1beta1.ModuleSpec{Path: "foo/two"}},
+		},
+	}
+	prModules := []types.NamespacedName{{Namespace: "foo", Name: "one"}}
+
+	planner := &Planner{
+		ClusterEnvName: "default",
+		RunStatus:      sysutil.NewRunStatus(),
+		Log:            slog.Default(),
+	}
+
+	newPR := func(login, body string) *pr {
+		return &pr{
+			Number:   123,
+			Comments: prComments{Nodes: []prComment{{DatabaseID: 11, Author: author{Login: login}, Body: body, UpdatedAt: time.Now()}}},
+		}
+	}
+
+	// expectReply sets expectation of a single reply to comment 11 containing given string
+	expectReply := func(t *testing.T, contains string) {
+		goMockCtrl := gomock.NewController(t)
+		testGithub := NewMockProviderInterface(goMockCtrl)
+		planner.github = testGithub
+
+		testGithub.EXPECT().postComment(gomock.Any(), gomock.Any(), 0, 123, gomock.Any()).
+			DoAndReturn(func(repoOwner, repoName string, commentID, prNumber int, commentBody prComment) (int, error) {
+				cluster, replyTo := parseCommandReplyMsg(commentBody.Body)
+				if cluster != "default" || replyTo != 11 {
+					t.Errorf("unexpected reply metadata cluster:%s replyTo:%d", cluster, replyTo)
+				}
+				if !strings.Contains(commentBody.Body, contains) {
+					t.Errorf("reply %q doesn't contain %q", commentBody.Body, contains)
+				}
+				return 12, nil
+			})
+	}
+
+	t.Run("help", func(t *testing.T) {
+		expectReply(t, "terraform-applier PR commands")
+		planner.processPRCommands(ctx, newPR("someone", "@terraform-applier help"), prModules, kubeModuleList)
+	})
+
+	t.Run("skip already replied command", func(t *testing.T) {
+		goMockCtrl := gomock.NewController(t)
+		planner.github = NewMockProviderInterface(goMockCtrl)
+
+		p := newPR("someone", "@terraform-applier help")
+		p.Comments.Nodes = append(p.Comments.Nodes, prComment{DatabaseID: 12, Body: helpMsg("default", 11), UpdatedAt: time.Now()})
+		planner.processPRCommands(ctx, p, prModules, kubeModuleList)
+	})
+
+	t.Run("module not part of PR", func(t *testing.T) {
+		expectReply(t, "is not part of this PR")
+		planner.processPRCommands(ctx, newPR("admin", "@terraform-applier cancel two"), prModules, kubeModuleList)
+	})
+
+	t.Run("unlock denied for non admin", func(t *testing.T) {
+		expectReply(t, "`someone` is not an Admin of the module")
+		planner.processPRCommands(ctx, newPR("someone", "@terraform-applier unlock one 1234"), prModules, kubeModuleList)
+	})
+
+	t.Run("cancel module not running", func(t *testing.T) {
+		expectReply(t, "module is not running a request of this PR")
+		planner.processPRCommands(ctx, newPR("admin", "@terraform-applier cancel foo/one"), prModules, kubeModuleList)
+	})
+
+	t.Run("cancel run not requested by PR", func(t *testing.T) {
+		for _, req := range []*tfaplv1beta1.Request{
+			{Type: tfaplv1beta1.PollingRun},
+			{Type: tfaplv1beta1.PRPlan, PR: &tfaplv1beta1.PullRequest{Number: 456}},
+		} {
+			runCtx, cancel := context.WithCancel(ctx)
+			planner.RunStatus.Store("foo/one", &sysutil.ActiveRun{Request: req, Cancel: cancel})
+
+			expectReply(t, "module is not running a request of this PR")
+			planner.processPRCommands(ctx, newPR("admin", "@terraform-applier cancel one"), prModules, kubeModuleList)
+
+			if runCtx.Err() != nil {
+				t.Errorf("%s run context is cancelled", req.Type)
+			}
+			planner.RunStatus.Delete("foo/one")
+			cancel()
+		}
+	})
+
+	t.Run("cancel running module", func(t *testing.T) {
+		runCtx, cancel := context.WithCancel(ctx)
+		req := &tfaplv1beta1.Request{Type: tfaplv1beta1.PRPlan, PR: &tfaplv1beta1.PullRequest{Number: 123}}
+		planner.RunStatus.Store("foo/one", &sysutil.ActiveRun{Request: req, Cancel: cancel})
+		defer planner.RunStatus.Delete("foo/one")
+
+		expectReply(t, "current run is cancelled")
+		planner.processPRCommands(ctx, newPR("admin", "@terraform-applier cancel one"), prModules, kubeModuleList)
+
+		if runCtx.Err() == nil {
+			t.Errorf("run context is not cancelled")
+		}
+	})
+}
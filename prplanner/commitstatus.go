@@ -0,0 +1,83 @@
+package prplanner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/utilitywarehouse/git-mirror/giturl"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+)
+
+// GitHub API limit for commit status description
+const commitStatusDescriptionLimit = 140
+
+// CommitStatusPublisher publishes GitHub commit statuses for module runs
+type CommitStatusPublisher struct {
+	ClusterEnvName string
+	WebserverURL   string
+	Log            *slog.Logger
+	github         *gitHubClient
+}
+
+// NewCommitStatusPublisher returns publisher which uses given creds
+// provider with 'statuses' write permission
+func NewCommitStatusPublisher(cluster, webserverURL string, creds sysutil.CredsProvider, log *slog.Logger) *CommitStatusPublisher {
+	return &CommitStatusPublisher{
+		ClusterEnvName: cluster,
+		WebserverURL:   webserverURL,
+		Log:            log,
+		github: &gitHubClient{
+			rootURL: "https://api.github.com",
+			http: &http.Client{
+				Timeout: 15 * time.Second,
+			},
+			credsProvider: creds,
+		},
+	}
+}
+
+type commitStatus struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+	Context     string `json:"context"`
+}
+
+// PublishCommitStatus sets status of the run on run's commit, only repositories
+// hosted on GitHub are supported
+func (c *CommitStatusPublisher) PublishCommitStatus(ctx context.Context, repoURL string, run *tfaplv1beta1.Run, state, description string) error {
+	repo, err := giturl.Parse(repoURL)
+	if err != nil {
+		return fmt.Errorf("unable to parse repo url err:%w", err)
+	}
+	if repo.Host != "github.com" {
+		return nil
+	}
+
+	runes := []rune(description)
+	if len(runes) > commitStatusDescriptionLimit {
+		description = string(runes[:commitStatusDescriptionLimit-3]) + "..."
+	}
+
+	status := commitStatus{
+		State:       state,
+		TargetURL:   sysutil.ModuleWebURL(c.WebserverURL, run.Module),
+		Description: description,
+		Context:     statusContext(c.ClusterEnvName, run.Module),
+	}
+
+	reqURL := fmt.Sprintf("%s/repos/%s/%s/statuses/%s",
+		c.github.rootURL, repo.Path, strings.TrimSuffix(repo.Repo, ".git"), run.CommitHash)
+
+	if err := c.github.rest(ctx, http.MethodPost, reqURL, status, nil); err != nil {
+		return fmt.Errorf("unable to set commit status: %w", err)
+	}
+
+	c.Log.Debug("commit status published", "module", run.Module, "commit", run.CommitHash, "state", state)
+	return nil
+}
@@ -0,0 +1,67 @@
+package prplanner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_PublishCommitStatus(t *testing.T) {
+	ctx := context.Background()
+	goMockCtrl := gomock.NewController(t)
+
+	testCreds := sysutil.NewMockCredsProvider(goMockCtrl)
+	testCreds.EXPECT().Creds(gomock.Any()).Return("", "secret", nil).AnyTimes()
+
+	var got []commitStatus
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/owner-a/repo-a/statuses/abc123" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var s commitStatus
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &s)
+		got = append(got, s)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	publisher := NewCommitStatusPublisher("dev", "https://applier.example.com", testCreds, slog.Default())
+	publisher.github.rootURL = server.URL
+	publisher.github.http = server.Client()
+
+	run := &tfaplv1beta1.Run{
+		Module:     types.NamespacedName{Namespace: "foo", Name: "one"},
+		CommitHash: "abc123",
+	}
+
+	if err := publisher.PublishCommitStatus(ctx, "git@github.com:owner-a/repo-a.git", run, "pending", "preparing for TF run"); err != nil {
+		t.Fatalf("PublishCommitStatus() error = %v", err)
+	}
+	if err := publisher.PublishCommitStatus(ctx, "https://github.com/owner-a/repo-a.git", run, "failure", strings.Repeat("a", 200)); err != nil {
+		t.Fatalf("PublishCommitStatus() error = %v", err)
+	}
+	// non github repositories are ignored
+	if err := publisher.PublishCommitStatus(ctx, "https://gitlab.example.com/owner-a/repo-a.git", run, "success", "applied"); err != nil {
+		t.Fatalf("PublishCommitStatus() error = %v", err)
+	}
+
+	want := []commitStatus{
+		{State: "pending", TargetURL: "https://applier.example.com/#foo_one", Description: "preparing for TF run", Context: "terraform-applier/dev/foo/one"},
+		{State: "failure", TargetURL: "https://applier.example.com/#foo_one", Description: strings.Repeat("a", 137) + "...", Context: "terraform-applier/dev/foo/one"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("commit statuses mismatch (-want +got):\n%s", diff)
+	}
+}
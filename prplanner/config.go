@@ -0,0 +1,171 @@
+package prplanner
+
+import (
+	"slices"
+	"time"
+
+	"github.com/utilitywarehouse/git-mirror/giturl"
+)
+
+const (
+	defaultMaxModules        = 5
+	defaultStaleAfter        = 24 * time.Hour
+	defaultStaleRequestAfter = 45 * time.Minute
+)
+
+var defaultBaseBranches = []string{"master", "main"}
+
+// Config is the PR planner config
+type Config struct {
+	// Defaults are applied to all repositories
+	Defaults     PlannerConfig `yaml:"defaults"`
+	Repositories []RepoConfig  `yaml:"repositories"`
+}
+
+// PlannerConfig is the planner behaviour config which can be set as
+// default or overridden per repository
+type PlannerConfig struct {
+	// MaxModules is the max number of modules updated by PR before auto plan
+	// is disabled, defaults to 5
+	MaxModules int `yaml:"max_modules"`
+	// StaleAfter is the duration since last update after which PR and
+	// comments are ignored, defaults to 24h
+	StaleAfter time.Duration `yaml:"stale_after"`
+	// StaleRequestAfter is the duration after which incomplete request is
+	// considered failed and can be retried, it should be more than max
+	// module run timeout, defaults to 45m
+	StaleRequestAfter time.Duration `yaml:"stale_request_after"`
+	// BaseBranches are the PR base branches matched with modules using default
+	// repo ref (HEAD), defaults to master and main
+	BaseBranches []string `yaml:"base_branches"`
+	// PlanDrafts enables auto plan for draft PRs
+	PlanDrafts *bool `yaml:"plan_drafts"`
+	// ForcePlanLabels enables auto plan for PR even if its a draft or updates
+	// more than max modules
+	ForcePlanLabels []string `yaml:"force_plan_labels"`
+	// SkipPlanLabels disables auto plan for PR, plan can still be requested
+	// via comment
+	SkipPlanLabels []string `yaml:"skip_plan_labels"`
+	// IgnoredAuthors are the PR authors (e.g. bots) whose PRs are ignored
+	IgnoredAuthors []string `yaml:"ignored_authors"`
+	// SummaryComment enables single summary comment per PR per cluster
+	// with status and output of all the modules instead of separate comments
+	// for each request and output
+	SummaryComment *bool `yaml:"summary_comment"`
+}
+
+// RepoConfig is the PR planner config of a repository
+type RepoConfig struct {
+	Remote string `yaml:"remote"`
+	// CheckRuns enables GitHub Check Run per module and cluster in addition to
+	// PR comments. requires GitHub app with checks write permission
+	CheckRuns     bool `yaml:"check_runs"`
+	PlannerConfig `yaml:",inline"`
+}
+
+// CheckRunsEnabled returns true if check runs are enabled for any repository
+func (c Config) CheckRunsEnabled() bool {
+	for _, r := range c.Repositories {
+		if r.CheckRuns {
+			return true
+		}
+	}
+	return false
+}
+
+// maxStaleAfter returns the longest configured stale duration
+func (c Config) maxStaleAfter() time.Duration {
+	staleAfter := c.plannerConfig(RepoConfig{}).StaleAfter
+	for _, r := range c.Repositories {
+		staleAfter = max(staleAfter, c.plannerConfig(r).StaleAfter)
+	}
+	return staleAfter
+}
+
+// plannerConfig returns repository's planner config merged with defaults
+func (c Config) plannerConfig(repo RepoConfig) PlannerConfig {
+	conf := repo.PlannerConfig
+	def := c.Defaults
+
+	if conf.MaxModules == 0 {
+		conf.MaxModules = def.MaxModules
+	}
+	if conf.MaxModules == 0 {
+		conf.MaxModules = defaultMaxModules
+	}
+	if conf.StaleAfter == 0 {
+		conf.StaleAfter = def.StaleAfter
+	}
+	if conf.StaleAfter == 0 {
+		conf.StaleAfter = defaultStaleAfter
+	}
+	if conf.StaleRequestAfter == 0 {
+		conf.StaleRequestAfter = def.StaleRequestAfter
+	}
+	if conf.StaleRequestAfter == 0 {
+		conf.StaleRequestAfter = defaultStaleRequestAfter
+	}
+	if len(conf.BaseBranches) == 0 {
+		conf.BaseBranches = def.BaseBranches
+	}
+	if len(conf.BaseBranches) == 0 {
+		conf.BaseBranches = defaultBaseBranches
+	}
+	if conf.PlanDrafts == nil {
+		conf.PlanDrafts = def.PlanDrafts
+	}
+	if conf.ForcePlanLabels == nil {
+		conf.ForcePlanLabels = def.ForcePlanLabels
+	}
+	if conf.SkipPlanLabels == nil {
+		conf.SkipPlanLabels = def.SkipPlanLabels
+	}
+	if conf.IgnoredAuthors == nil {
+		conf.IgnoredAuthors = def.IgnoredAuthors
+	}
+	if conf.SummaryComment == nil {
+		conf.SummaryComment = def.SummaryComment
+	}
+
+	return conf
+}
+
+// summaryCommentEnabled returns true if summary comment mode is enabled
+func (c PlannerConfig) summaryCommentEnabled() bool {
+	return c.SummaryComment != nil && *c.SummaryComment
+}
+
+// isAuthorIgnored returns true if PR author is in the ignored list
+func (c PlannerConfig) isAuthorIgnored(login string) bool {
+	return slices.Contains(c.IgnoredAuthors, login)
+}
+
+// skipAutoPlan returns true if plan should not be triggered for new commits
+// of the PR, plan can still be requested via comments
+func (c PlannerConfig) skipAutoPlan(pr *pr, numOfModules int) bool {
+	if pr.hasLabel(c.SkipPlanLabels) {
+		return true
+	}
+	if pr.hasLabel(c.ForcePlanLabels) {
+		return false
+	}
+	if pr.IsDraft && (c.PlanDrafts == nil || !*c.PlanDrafts) {
+		return true
+	}
+	return numOfModules > c.MaxModules
+}
+
+// repoConfig returns config of the given repository
+func (p *Planner) repoConfig(repoURL string) RepoConfig {
+	for _, r := range p.Config.Repositories {
+		if ok, _ := giturl.SameRawURL(r.Remote, repoURL); ok {
+			return r
+		}
+	}
+	return RepoConfig{Remote: repoURL}
+}
+
+// plannerConfig returns planner config of the given repository
+func (p *Planner) plannerConfig(repoURL string) PlannerConfig {
+	return p.Config.plannerConfig(p.repoConfig(repoURL))
+}
@@ -0,0 +1,133 @@
+package prplanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfig_plannerConfig(t *testing.T) {
+	planDrafts := true
+
+	conf := Config{
+		Defaults: PlannerConfig{
+			MaxModules:        10,
+			StaleRequestAfter: 30 * time.Minute,
+			IgnoredAuthors:    []string{"renovate[bot]"},
+		},
+		Repositories: []RepoConfig{
+			{
+				Remote: "git@github.com:owner-a/repo-a.git",
+				PlannerConfig: PlannerConfig{
+					StaleAfter:        48 * time.Hour,
+					StaleRequestAfter: 90 * time.Minute,
+					BaseBranches:      []string{"release"},
+					PlanDrafts:        &planDrafts,
+				},
+			},
+			{
+				Remote: "git@github.com:owner-b/repo-b.git",
+				PlannerConfig: PlannerConfig{
+					MaxModules:     2,
+					IgnoredAuthors: []string{},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		repo RepoConfig
+		want PlannerConfig
+	}{
+		{
+			name: "defaults",
+			repo: RepoConfig{},
+			want: PlannerConfig{
+				MaxModules:        10,
+				StaleAfter:        defaultStaleAfter,
+				StaleRequestAfter: 30 * time.Minute,
+				BaseBranches:      defaultBaseBranches,
+				IgnoredAuthors:    []string{"renovate[bot]"},
+			},
+		},
+		{
+			name: "repo-a",
+			repo: conf.Repositories[0],
+			want: PlannerConfig{
+				MaxModules:        10,
+				StaleAfter:        48 * time.Hour,
+				StaleRequestAfter: 90 * time.Minute,
+				BaseBranches:      []string{"release"},
+				PlanDrafts:        &planDrafts,
+				IgnoredAuthors:    []string{"renovate[bot]"},
+			},
+		},
+		{
+			name: "repo-b",
+			repo: conf.Repositories[1],
+			want: PlannerConfig{
+				MaxModules:        2,
+				StaleAfter:        defaultStaleAfter,
+				StaleRequestAfter: 30 * time.Minute,
+				BaseBranches:      defaultBaseBranches,
+				IgnoredAuthors:    []string{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := conf.plannerConfig(tt.repo)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("plannerConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if got := conf.maxStaleAfter(); got != 48*time.Hour {
+		t.Errorf("maxStaleAfter() = %v, want %v", got, 48*time.Hour)
+	}
+}
+
+func TestPlannerConfig_skipAutoPlan(t *testing.T) {
+	planDrafts := true
+
+	conf := PlannerConfig{
+		MaxModules:      2,
+		ForcePlanLabels: []string{"plan-all"},
+		SkipPlanLabels:  []string{"no-plan"},
+	}
+
+	withLabels := func(isDraft bool, labels ...string) *pr {
+		p := &pr{IsDraft: isDraft}
+		for _, l := range labels {
+			p.Labels.Nodes = append(p.Labels.Nodes, prLabel{Name: l})
+		}
+		return p
+	}
+
+	tests := []struct {
+		name         string
+		conf         PlannerConfig
+		pr           *pr
+		numOfModules int
+		want         bool
+	}{
+		{"within limit", conf, withLabels(false), 2, false},
+		{"over limit", conf, withLabels(false), 3, true},
+		{"draft", conf, withLabels(true), 1, true},
+		{"draft allowed", PlannerConfig{MaxModules: 2, PlanDrafts: &planDrafts}, withLabels(true), 1, false},
+		{"force label over limit", conf, withLabels(false, "plan-all"), 3, false},
+		{"force label draft", conf, withLabels(true, "foo", "plan-all"), 1, false},
+		{"skip label", conf, withLabels(false, "no-plan"), 1, true},
+		{"skip label wins over force label", conf, withLabels(false, "plan-all", "no-plan"), 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.conf.skipAutoPlan(tt.pr, tt.numOfModules); got != tt.want {
+				t.Errorf("skipAutoPlan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package prplanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// giteaClient implements ProviderInterface for Gitea and Forgejo pull requests
+// using Gitea REST API v1
+type giteaClient struct {
+	rootURL string
+	http    *http.Client
+	token   string
+	// maxPRAge is the duration since last update after which PRs are not fetched
+	maxPRAge time.Duration
+}
+
+
+type giteaPR struct {
+	Number         int       `json:"number"`
+	State          string    `json:"state"`
+	Draft          bool      `json:"draft"`
+	Merged         bool      `json:"merged"`
+	MergeCommitSHA string    `json:"merge_commit_sha"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	User           giteaUser `json:"user"`
+	Labels         []prLabel `json:"labels"`
+	Base           struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Head struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+}
+
+type giteaComment struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	UpdatedAt time.Time `json:"updated_at"`
+	User      giteaUser `json:"user"`
+}
+
+type giteaUser struct {
+	Login string `json:"login"`
+}
+
+func (gc *giteaClient) openPRs(ctx context.Context, repoOwner, repoName string) ([]*pr, error) {
+	repoName = strings.TrimSuffix(repoName, ".git")
+
+	var result []*pr
+	reqURL := fmt.Sprintf("%s/pulls?state=open&sort=recentupdate&limit=50", gc.repoURL(repoOwner, repoName))
+	for page := 1; ; page++ {
+		var prs []giteaPR
+		header, err := gc.send(ctx, http.MethodGet, fmt.Sprintf("%s&page=%d", reqURL, page), nil, &prs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get PRs, error :%w", err)
+		}
+
+		for _, p := range prs {
+			// PRs are sorted by updated_at so rest of the PRs are stale
+			if gc.maxPRAge > 0 && time.Since(p.UpdatedAt) > gc.maxPRAge {
+				return result, nil
+			}
+			pr, err := gc.toPR(ctx, repoOwner, repoName, p)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, pr)
+		}
+
+		// server may limit page size so next page is only known from 'Link' header
+		if len(prs) == 0 || !strings.Contains(header.Get("Link"), `rel="next"`) {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+func (gc *giteaClient) PR(ctx context.Context, repoOwner, repoName string, prNumber int) (*pr, error) {
+	repoName = strings.TrimSuffix(repoName, ".git")
+
+	var p giteaPR
+	reqURL := fmt.Sprintf("%s/pulls/%d", gc.repoURL(repoOwner, repoName), prNumber)
+	if err := gc.do(ctx, http.MethodGet, reqURL, nil, &p); err != nil {
+		return nil, fmt.Errorf("unable to get PR, err:%w", err)
+	}
+
+	return gc.toPR(ctx, repoOwner, repoName, p)
+}
+
+func (gc *giteaClient) postComment(repoOwner, repoName string, commentID, prNumber int, commentBody prComment) (int, error) {
+	repoName = strings.TrimSuffix(repoName, ".git")
+	method := http.MethodPost
+	reqURL := fmt.Sprintf("%s/issues/%d/comments", gc.repoURL(repoOwner, repoName), prNumber)
+
+	// if comment ID provided update same comment
+	if commentID != 0 {
+		method = http.MethodPatch
+		reqURL = fmt.Sprintf("%s/issues/comments/%d", gc.repoURL(repoOwner, repoName), commentID)
+	}
+
+	payload := struct {
+		Body string `json:"body"`
+	}{commentBody.Body}
+
+	var comment giteaComment
+	if err := gc.do(context.Background(), method, reqURL, payload, &comment); err != nil {
+		return 0, fmt.Errorf("error posting PR comment: %w", err)
+	}
+
+	return comment.ID, nil
+}
+
+// toPR converts Gitea pull request and its comments to pr
+func (gc *giteaClient) toPR(ctx context.Context, repoOwner, repoName string, p giteaPR) (*pr, error) {
+	result := &pr{
+		Number:      p.Number,
+		BaseRefName: p.Base.Ref,
+		HeadRefName: p.Head.Ref,
+		IsDraft:     p.Draft,
+		Closed:      p.State == "closed",
+		Merged:      p.Merged,
+		UpdatedAt:   p.UpdatedAt,
+		Author:      author{Login: p.User.Login},
+	}
+	result.BaseRepository.Name = repoName
+	result.BaseRepository.Owner.Login = repoOwner
+	result.BaseRepository.URL = fmt.Sprintf("%s/%s/%s", gc.rootURL, repoOwner, repoName)
+	result.MergeCommit.Oid = p.MergeCommitSHA
+	result.Labels.Nodes = p.Labels
+
+	var comments []giteaComment
+	reqURL := fmt.Sprintf("%s/issues/%d/comments", gc.repoURL(repoOwner, repoName), p.Number)
+	if err := gc.do(ctx, http.MethodGet, reqURL, nil, &comments); err != nil {
+		return nil, fmt.Errorf("unable to get PR comments, err:%w", err)
+	}
+
+	// only last 50 comments are processed, same as GitHub PR comments
+	if len(comments) > 50 {
+		comments = comments[len(comments)-50:]
+	}
+	for _, c := range comments {
+		result.Comments.Nodes = append(result.Comments.Nodes, prComment{
+			DatabaseID: c.ID,
+			Author:     author{Login: c.User.Login},
+			Body:       c.Body,
+			UpdatedAt:  c.UpdatedAt,
+		})
+	}
+
+	return result, nil
+}
+
+func (gc *giteaClient) repoURL(repoOwner, repoName string) string {
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s", gc.rootURL, url.PathEscape(repoOwner), url.PathEscape(repoName))
+}
+
+func (gc *giteaClient) do(ctx context.Context, method, reqURL string, payload, result any) error {
+	_, err := gc.send(ctx, method, reqURL, payload, result)
+	return err
+}
+
+// send makes API call and returns response headers which contains
+// pagination details like 'Link'
+func (gc *giteaClient) send(ctx context.Context, method, reqURL string, payload, result any) (http.Header, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling payload to JSON: %w", err)
+		}
+		body = bytes.NewBuffer(data)
+	}
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "token "+gc.token)
+
+	// Send the HTTP request
+	resp, err := gc.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending HTTP request: %w", err)
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	// Check the response status
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		return nil, fmt.Errorf("HTTP error: %s", resp.Status)
+	}
+
+	return resp.Header, json.NewDecoder(resp.Body).Decode(result)
+}
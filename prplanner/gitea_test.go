@@ -0,0 +1,207 @@
+package prplanner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newGiteaStandIn returns test server which serves minimal subset of Gitea API
+// used by giteaClient
+func newGiteaStandIn(t *testing.T, comments *[]map[string]any) *httptest.Server {
+	t.Helper()
+
+	pull := map[string]any{
+		"number":           3,
+		"state":            "open",
+		"merged":           false,
+		"merge_commit_sha": nil,
+		"updated_at":       "2024-05-01T10:00:00Z",
+		"user":             map[string]any{"login": "alice"},
+		"base":             map[string]any{"ref": "main"},
+		"head":             map[string]any{"ref": "feature"},
+	}
+	olderPull := map[string]any{
+		"number":     4,
+		"state":      "open",
+		"updated_at": "2024-04-01T10:00:00Z",
+		"user":       map[string]any{"login": "bob"},
+		"base":       map[string]any{"ref": "main"},
+		"head":       map[string]any{"ref": "older"},
+	}
+	stalePull := map[string]any{
+		"number":     2,
+		"state":      "open",
+		"updated_at": "2024-01-01T10:00:00Z",
+		"user":       map[string]any{"login": "bob"},
+		"base":       map[string]any{"ref": "main"},
+		"head":       map[string]any{"ref": "stale"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/repos/infra/terraform/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != "open" {
+			t.Errorf("unexpected state query %q", r.URL.Query().Get("state"))
+		}
+		writeGiteaPage(w, r, []any{pull, olderPull, stalePull})
+	})
+	mux.HandleFunc("GET /api/v1/repos/infra/terraform/pulls/3", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(pull)
+	})
+	mux.HandleFunc("GET /api/v1/repos/infra/terraform/issues/{num}/comments", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("num") == "2" {
+			t.Errorf("comments of stale PR should not be fetched")
+		}
+		json.NewEncoder(w).Encode(*comments)
+	})
+	mux.HandleFunc("POST /api/v1/repos/infra/terraform/issues/3/comments", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		c := map[string]any{
+			"id":         100 + len(*comments),
+			"body":       payload["body"],
+			"updated_at": "2024-05-01T11:00:00Z",
+			"user":       map[string]any{"login": "tf-applier"},
+		}
+		*comments = append(*comments, c)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(c)
+	})
+	mux.HandleFunc("PATCH /api/v1/repos/infra/terraform/issues/comments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		for _, c := range *comments {
+			if r.PathValue("id") == jsonNumber(c["id"]) {
+				c["body"] = payload["body"]
+				json.NewEncoder(w).Encode(c)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+// writeGiteaPage writes single item per page so that pagination is always
+// exercised, 'Link' header with next page is set if there are more items
+func writeGiteaPage(w http.ResponseWriter, r *http.Request, items []any) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	if page < len(items) {
+		w.Header().Set("Link", `<`+r.URL.Path+`?page=`+strconv.Itoa(page+1)+`>; rel="next"`)
+	}
+	resp := []any{}
+	if page <= len(items) {
+		resp = append(resp, items[page-1])
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func Test_giteaClient(t *testing.T) {
+	ctx := context.Background()
+
+	comments := []map[string]any{
+		{"id": 1, "body": "@terraform-applier plan foo", "updated_at": "2024-05-01T10:01:00Z", "user": map[string]any{"login": "bob"}},
+	}
+	server := newGiteaStandIn(t, &comments)
+	defer server.Close()
+
+	gc := &giteaClient{rootURL: server.URL, http: server.Client(), token: "secret", maxPRAge: time.Since(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))}
+
+	wantPR := &pr{
+		Number:      3,
+		BaseRefName: "main",
+		HeadRefName: "feature",
+		UpdatedAt:   time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Author:      author{Login: "alice"},
+	}
+	wantPR.BaseRepository.Name = "terraform"
+	wantPR.BaseRepository.Owner.Login = "infra"
+	wantPR.BaseRepository.URL = server.URL + "/infra/terraform"
+	wantPR.Comments.Nodes = []prComment{
+		{DatabaseID: 1, Author: author{Login: "bob"}, Body: "@terraform-applier plan foo", UpdatedAt: time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC)},
+	}
+
+	olderPR := &pr{
+		Number:      4,
+		BaseRefName: "main",
+		HeadRefName: "older",
+		UpdatedAt:   time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC),
+		Author:      author{Login: "bob"},
+	}
+	olderPR.BaseRepository = wantPR.BaseRepository
+	olderPR.Comments = wantPR.Comments
+
+	// PRs are fetched from all the pages until stale PR is found
+	t.Run("open PRs", func(t *testing.T) {
+		got, err := gc.openPRs(ctx, "infra", "terraform.git")
+		if err != nil {
+			t.Fatalf("openPRs() error = %v", err)
+		}
+		if diff := cmp.Diff([]*pr{wantPR, olderPR}, got); diff != "" {
+			t.Errorf("openPRs() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("get PR", func(t *testing.T) {
+		got, err := gc.PR(ctx, "infra", "terraform", 3)
+		if err != nil {
+			t.Fatalf("PR() error = %v", err)
+		}
+		if diff := cmp.Diff(wantPR, got); diff != "" {
+			t.Errorf("PR() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("post and update comment", func(t *testing.T) {
+		id, err := gc.postComment("infra", "terraform", 0, 3, prComment{Body: "pending"})
+		if err != nil {
+			t.Fatalf("postComment() error = %v", err)
+		}
+		if id != 101 {
+			t.Errorf("postComment() id = %d, want 101", id)
+		}
+
+		gotID, err := gc.postComment("infra", "terraform", id, 3, prComment{Body: "output"})
+		if err != nil {
+			t.Fatalf("postComment() update error = %v", err)
+		}
+		if gotID != id {
+			t.Errorf("postComment() updated id = %d, want %d", gotID, id)
+		}
+
+		got, err := gc.PR(ctx, "infra", "terraform", 3)
+		if err != nil {
+			t.Fatalf("PR() error = %v", err)
+		}
+		last := got.Comments.Nodes[len(got.Comments.Nodes)-1]
+		if last.Body != "output" {
+			t.Errorf("updated comment body = %q, want %q", last.Body, "output")
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		gc := &giteaClient{rootURL: server.URL, http: server.Client(), token: "invalid"}
+		if _, err := gc.openPRs(ctx, "infra", "terraform"); err == nil {
+			t.Errorf("openPRs() expected error with invalid token")
+		}
+	})
+}
@@ -4,27 +4,49 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/utilitywarehouse/terraform-applier/sysutil"
 )
 
-//go:generate go run github.com/golang/mock/mockgen -package prplanner -destination github_mock.go github.com/utilitywarehouse/terraform-applier/prplanner GithubInterface
+const (
+	// maxCommentPages limits number of comment pages (100 per page) fetched per PR
+	maxCommentPages = 10
+	// minRateLimitRemaining is the number of remaining points below which
+	// queries are skipped until rate limit is reset
+	minRateLimitRemaining = 100
+)
 
-// GithubInterface allows for mocking out the functionality of GitHub API Calls
-type GithubInterface interface {
-	openPRs(ctx context.Context, repoOwner, repoName string) ([]*pr, error)
-	PR(ctx context.Context, repoOwner, repoName string, prNumber int) (*pr, error)
-	postComment(repoOwner, repoName string, commentID, prNumber int, commentBody prComment) (int, error)
-}
+var errRateLimited = errors.New("GitHub API rate limit is low")
 
 type gitHubClient struct {
 	rootURL       string
 	http          *http.Client
 	credsProvider sysutil.CredsProvider
+	// maxPRAge is the duration since last update after which PRs are not fetched
+	maxPRAge time.Duration
+
+	mu sync.Mutex
+	// rateLimit is the last known rate limit status of the app
+	rateLimit rateLimit
+	// prCache stores comments of open PRs by 'owner/repo' and PR number,
+	// comments of the PR are only fetched again if PR is updated since last query
+	prCache map[string]map[int]cachedPR
+}
+
+// cachedPR holds copy of the PR comments, callers are free to modify the
+// returned PRs as they don't share comments with the cache
+type cachedPR struct {
+	updatedAt time.Time
+	comments  []prComment
 }
 
 func (gc *gitHubClient) openPRs(ctx context.Context, repoOwner, repoName string) ([]*pr, error) {
@@ -33,18 +55,68 @@ func (gc *gitHubClient) openPRs(ctx context.Context, repoOwner, repoName string)
 	q.Variables.Owner = repoOwner
 	q.Variables.RepoName = repoName
 
-	var result gitPRsResponse
+	var prs []*pr
+	for {
+		var result gitPRsResponse
+
+		err := gc.query(ctx, q, &result)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get PRs, error :%w", err)
+		}
+
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("api error %+v", result.Errors)
+		}
+
+		gc.updateRateLimit(result.Data.RateLimit)
+
+		stale := false
+		for _, pr := range result.Data.Repository.PullRequests.Nodes {
+			// PRs are sorted by updatedAt so rest of the PRs are stale
+			if gc.maxPRAge > 0 && time.Since(pr.UpdatedAt) > gc.maxPRAge {
+				stale = true
+				break
+			}
+			prs = append(prs, pr)
+		}
+
+		pageInfo := result.Data.Repository.PullRequests.PageInfo
+		if stale || !pageInfo.HasNextPage {
+			break
+		}
+		q.Variables.Cursor = pageInfo.EndCursor
+	}
 
-	err := gc.query(ctx, q, &result)
-	if err != nil {
-		return nil, fmt.Errorf("unable to get PRs, error :%w", err)
+	repoKey := repoOwner + "/" + repoName
+
+	gc.mu.Lock()
+	cache := gc.prCache[repoKey]
+	gc.mu.Unlock()
+
+	newCache := make(map[int]cachedPR)
+	for _, pr := range prs {
+		// reuse comments if PR is not updated since last query
+		if cached, ok := cache[pr.Number]; ok && cached.updatedAt.Equal(pr.UpdatedAt) {
+			pr.Comments.Nodes = slices.Clone(cached.comments)
+		} else {
+			comments, err := gc.comments(ctx, repoOwner, repoName, pr.Number, "")
+			if err != nil {
+				return nil, err
+			}
+			pr.Comments.Nodes = comments
+		}
+		newCache[pr.Number] = cachedPR{updatedAt: pr.UpdatedAt, comments: slices.Clone(pr.Comments.Nodes)}
 	}
 
-	if len(result.Errors) > 0 {
-		return nil, fmt.Errorf("api error %+v", result.Errors)
+	// only keep currently open PRs in the cache
+	gc.mu.Lock()
+	if gc.prCache == nil {
+		gc.prCache = make(map[string]map[int]cachedPR)
 	}
+	gc.prCache[repoKey] = newCache
+	gc.mu.Unlock()
 
-	return result.Data.Repository.PullRequests.Nodes, nil
+	return prs, nil
 }
 
 func (gc *gitHubClient) PR(ctx context.Context, repoOwner, repoName string, prNumber int) (*pr, error) {
@@ -65,10 +137,93 @@ func (gc *gitHubClient) PR(ctx context.Context, repoOwner, repoName string, prNu
 		return nil, fmt.Errorf("api error %+v", result.Errors)
 	}
 
-	return result.Data.Repository.PullRequest, nil
+	gc.updateRateLimit(result.Data.RateLimit)
+
+	pr := result.Data.Repository.PullRequest
+	if pr == nil {
+		return nil, nil
+	}
+
+	// fetch rest of the comments if there are more
+	if pr.Comments.PageInfo.HasPreviousPage {
+		comments, err := gc.comments(ctx, repoOwner, repoName, prNumber, pr.Comments.PageInfo.StartCursor)
+		if err != nil {
+			return nil, err
+		}
+		pr.Comments.Nodes = append(comments, pr.Comments.Nodes...)
+	}
+
+	return pr, nil
+}
+
+// comments returns PR comments from oldest to latest, pages are fetched
+// backwards starting before given cursor
+func (gc *gitHubClient) comments(ctx context.Context, repoOwner, repoName string, prNumber int, cursor string) ([]prComment, error) {
+	q := gitPRRequest{Query: queryPRComments}
+	q.Variables.Owner = repoOwner
+	q.Variables.RepoName = repoName
+	q.Variables.PRNumber = prNumber
+	q.Variables.Cursor = cursor
+
+	var comments []prComment
+	for range maxCommentPages {
+		var result gitPRResponse
+
+		err := gc.query(ctx, q, &result)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get PR comments, err:%w", err)
+		}
+
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("api error %+v", result.Errors)
+		}
+
+		gc.updateRateLimit(result.Data.RateLimit)
+
+		if result.Data.Repository.PullRequest == nil {
+			break
+		}
+
+		page := result.Data.Repository.PullRequest.Comments
+		comments = append(page.Nodes, comments...)
+
+		if !page.PageInfo.HasPreviousPage {
+			break
+		}
+		q.Variables.Cursor = page.PageInfo.StartCursor
+	}
+
+	return comments, nil
+}
+
+func (gc *gitHubClient) updateRateLimit(rl rateLimit) {
+	if rl.ResetAt.IsZero() {
+		return
+	}
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.rateLimit = rl
+}
+
+// checkRateLimit returns error if remaining rate limit is too low
+func (gc *gitHubClient) checkRateLimit() error {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if gc.rateLimit.ResetAt.IsZero() || time.Now().After(gc.rateLimit.ResetAt) {
+		return nil
+	}
+	if gc.rateLimit.Remaining < minRateLimitRemaining {
+		return fmt.Errorf("%w: remaining:%d resetAt:%s", errRateLimited, gc.rateLimit.Remaining, gc.rateLimit.ResetAt.Format(time.RFC3339))
+	}
+	return nil
 }
 
 func (gc *gitHubClient) query(ctx context.Context, q gitPRRequest, result any) error {
+	if err := gc.checkRateLimit(); err != nil {
+		return err
+	}
+
 	payload, err := json.Marshal(q)
 	if err != nil {
 		return err
@@ -99,6 +254,14 @@ func (gc *gitHubClient) query(ctx context.Context, q gitPRRequest, result any) e
 		resp.Body.Close()
 	}()
 
+	// primary rate limit exceeded, record reset time so that
+	// next queries are skipped until then
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			gc.updateRateLimit(rateLimit{Remaining: 0, ResetAt: time.Unix(reset, 0)})
+		}
+	}
+
 	// Check the response status
 	if resp.StatusCode != 200 && resp.StatusCode != 201 {
 		return fmt.Errorf("HTTP error: %s", resp.Status)
@@ -107,6 +270,15 @@ func (gc *gitHubClient) query(ctx context.Context, q gitPRRequest, result any) e
 	return json.NewDecoder(resp.Body).Decode(&result)
 }
 
+// invalidatePRCache removes PR from cache so that its comments are fetched
+// again on next query
+func (gc *gitHubClient) invalidatePRCache(repoOwner, repoName string, prNumber int) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	delete(gc.prCache[repoOwner+"/"+repoName], prNumber)
+}
+
 func (gc *gitHubClient) postComment(repoOwner, repoName string, commentID, prNumber int, commentBody prComment) (int, error) {
 	repoName = strings.TrimSuffix(repoName, ".git")
 	method := "POST"
@@ -153,6 +325,9 @@ func (gc *gitHubClient) postComment(repoOwner, repoName string, commentID, prNum
 		return 0, fmt.Errorf("error posting PR comment: %s", resp.Status)
 	}
 
+	// comment edits may not update PR's updatedAt
+	gc.invalidatePRCache(repoOwner, repoName, prNumber)
+
 	var commentResponse struct {
 		ID int `json:"id"`
 	}
@@ -0,0 +1,180 @@
+package prplanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+)
+
+func Test_gitHubClient_pagination(t *testing.T) {
+	ctx := context.Background()
+	goMockCtrl := gomock.NewController(t)
+	testCreds := sysutil.NewMockCredsProvider(goMockCtrl)
+	testCreds.EXPECT().Creds(gomock.Any()).Return("", "secret", nil).AnyTimes()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	remaining := 5000
+	var prQueries, commentQueries atomic.Int32
+
+	comment := func(id int) map[string]any {
+		return map[string]any{"databaseId": id, "body": "comment", "updatedAt": now}
+	}
+
+	// commentsPage returns comments connection, comments are paginated backwards
+	commentsPage := func(cursor string) map[string]any {
+		if cursor == "" {
+			return map[string]any{
+				"pageInfo": map[string]any{"hasPreviousPage": true, "startCursor": "c1"},
+				"nodes":    []any{comment(3), comment(4)},
+			}
+		}
+		return map[string]any{
+			"pageInfo": map[string]any{"hasPreviousPage": false},
+			"nodes":    []any{comment(1), comment(2)},
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var q gitPRRequest
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		data := map[string]any{
+			"rateLimit": map[string]any{"cost": 1, "remaining": remaining, "resetAt": now.Add(time.Hour)},
+		}
+
+		switch q.Query {
+		case queryRepoPRs:
+			prQueries.Add(1)
+			page := map[string]any{
+				"pageInfo": map[string]any{"hasNextPage": true, "endCursor": "p1"},
+				"nodes":    []any{map[string]any{"number": 1, "updatedAt": now}},
+			}
+			if q.Variables.Cursor == "p1" {
+				// 2nd PR is stale so pagination should stop even if there are more pages
+				page = map[string]any{
+					"pageInfo": map[string]any{"hasNextPage": true, "endCursor": "p2"},
+					"nodes": []any{
+						map[string]any{"number": 2, "updatedAt": now},
+						map[string]any{"number": 3, "updatedAt": now.Add(-48 * time.Hour)},
+					},
+				}
+			}
+			if q.Variables.Cursor == "p2" {
+				t.Errorf("unexpected query for stale PRs page")
+			}
+			data["repository"] = map[string]any{"pullRequests": page}
+		case queryPRComments:
+			commentQueries.Add(1)
+			data["repository"] = map[string]any{"pullRequest": map[string]any{"comments": commentsPage(q.Variables.Cursor)}}
+		case queryRepoPR:
+			data["repository"] = map[string]any{"pullRequest": map[string]any{
+				"number": q.Variables.PRNumber, "updatedAt": now, "comments": commentsPage(""),
+			}}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	gc := &gitHubClient{rootURL: server.URL, http: server.Client(), credsProvider: testCreds, maxPRAge: 24 * time.Hour}
+
+	wantComments := []prComment{
+		{DatabaseID: 1, Body: "comment", UpdatedAt: now},
+		{DatabaseID: 2, Body: "comment", UpdatedAt: now},
+		{DatabaseID: 3, Body: "comment", UpdatedAt: now},
+		{DatabaseID: 4, Body: "comment", UpdatedAt: now},
+	}
+
+	t.Run("open PRs", func(t *testing.T) {
+		prs, err := gc.openPRs(ctx, "owner-a", "repo-a.git")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(prs) != 2 || prs[0].Number != 1 || prs[1].Number != 2 {
+			t.Fatalf("unexpected PRs: %+v", prs)
+		}
+		for _, pr := range prs {
+			if diff := cmp.Diff(wantComments, pr.Comments.Nodes); diff != "" {
+				t.Errorf("comments mismatch (-want +got):\n%s", diff)
+			}
+		}
+		if got := commentQueries.Load(); got != 4 {
+			t.Errorf("comment queries = %d, want 4", got)
+		}
+	})
+
+	t.Run("comments of not updated PRs are cached", func(t *testing.T) {
+		prs, err := gc.openPRs(ctx, "owner-a", "repo-a")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if diff := cmp.Diff(wantComments, prs[0].Comments.Nodes); diff != "" {
+			t.Errorf("comments mismatch (-want +got):\n%s", diff)
+		}
+		if got := commentQueries.Load(); got != 4 {
+			t.Errorf("comment queries = %d, want 4", got)
+		}
+
+		// changes made by callers to the returned PRs must not update cache
+		prs[0].Comments.Nodes[0].Body = "updated"
+		prs[0].Comments.Nodes = append(prs[0].Comments.Nodes, prComment{Body: "new"})
+
+		prs, err = gc.openPRs(ctx, "owner-a", "repo-a")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if diff := cmp.Diff(wantComments, prs[0].Comments.Nodes); diff != "" {
+			t.Errorf("comments mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("PR", func(t *testing.T) {
+		pr, err := gc.PR(ctx, "owner-a", "repo-a", 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if diff := cmp.Diff(wantComments, pr.Comments.Nodes); diff != "" {
+			t.Errorf("comments mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		remaining = 10
+		// first query records low remaining points and
+		// following comments page query is skipped
+		if _, err := gc.PR(ctx, "owner-a", "repo-a", 1); !errors.Is(err, errRateLimited) {
+			t.Fatalf("PR() error = %v, want %v", err, errRateLimited)
+		}
+
+		before := prQueries.Load()
+		_, err := gc.openPRs(ctx, "owner-a", "repo-a")
+		if !errors.Is(err, errRateLimited) {
+			t.Errorf("openPRs() error = %v, want %v", err, errRateLimited)
+		}
+		if !strings.Contains(err.Error(), "remaining:10") {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if prQueries.Load() != before {
+			t.Errorf("query was sent while rate limited")
+		}
+	})
+}
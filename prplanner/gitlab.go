@@ -0,0 +1,277 @@
+package prplanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// gitLabClient implements ProviderInterface for GitLab merge requests
+// using GitLab REST API v4
+type gitLabClient struct {
+	rootURL string
+	http    *http.Client
+	token   string
+	// maxPRAge is the duration since last update after which MRs are not fetched
+	maxPRAge time.Duration
+}
+
+type glMergeRequest struct {
+	IID                 int       `json:"iid"`
+	TargetBranch        string    `json:"target_branch"`
+	SourceBranch        string    `json:"source_branch"`
+	SHA                 string    `json:"sha"`
+	Draft               bool      `json:"draft"`
+	State               string    `json:"state"`
+	MergeStatus         string    `json:"merge_status"`
+	DetailedMergeStatus string    `json:"detailed_merge_status"`
+	MergeCommitSHA      string    `json:"merge_commit_sha"`
+	SquashCommitSHA     string    `json:"squash_commit_sha"`
+	UpdatedAt           time.Time `json:"updated_at"`
+	Author              glUser    `json:"author"`
+	Labels              []string  `json:"labels"`
+}
+
+type glNote struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	System    bool      `json:"system"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Author    glUser    `json:"author"`
+}
+
+type glUser struct {
+	Username string `json:"username"`
+}
+
+type glApprovals struct {
+	Approved   bool `json:"approved"`
+	ApprovedBy []struct {
+		User glUser `json:"user"`
+	} `json:"approved_by"`
+}
+
+func (gc *gitLabClient) openPRs(ctx context.Context, repoOwner, repoName string) ([]*pr, error) {
+	repoName = strings.TrimSuffix(repoName, ".git")
+
+	var prs []*pr
+	reqURL := fmt.Sprintf("%s/merge_requests?state=opened&order_by=updated_at&sort=desc&per_page=50", gc.projectURL(repoOwner, repoName))
+	for page := "1"; page != ""; {
+		var mrs []glMergeRequest
+		header, err := gc.send(ctx, http.MethodGet, reqURL+"&page="+page, nil, &mrs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get MRs, error :%w", err)
+		}
+
+		for _, mr := range mrs {
+			// MRs are sorted by updated_at so rest of the MRs are stale
+			if gc.maxPRAge > 0 && time.Since(mr.UpdatedAt) > gc.maxPRAge {
+				return prs, nil
+			}
+			pr, err := gc.toPR(ctx, repoOwner, repoName, mr)
+			if err != nil {
+				return nil, err
+			}
+			prs = append(prs, pr)
+		}
+
+		page = header.Get("X-Next-Page")
+	}
+
+	return prs, nil
+}
+
+func (gc *gitLabClient) PR(ctx context.Context, repoOwner, repoName string, prNumber int) (*pr, error) {
+	repoName = strings.TrimSuffix(repoName, ".git")
+
+	var mr glMergeRequest
+	reqURL := fmt.Sprintf("%s/merge_requests/%d", gc.projectURL(repoOwner, repoName), prNumber)
+	if err := gc.do(ctx, http.MethodGet, reqURL, nil, &mr); err != nil {
+		return nil, fmt.Errorf("unable to get MR, err:%w", err)
+	}
+
+	return gc.toPR(ctx, repoOwner, repoName, mr)
+}
+
+func (gc *gitLabClient) postComment(repoOwner, repoName string, commentID, prNumber int, commentBody prComment) (int, error) {
+	repoName = strings.TrimSuffix(repoName, ".git")
+	method := http.MethodPost
+	reqURL := fmt.Sprintf("%s/merge_requests/%d/notes", gc.projectURL(repoOwner, repoName), prNumber)
+
+	// if comment ID provided update same note
+	if commentID != 0 {
+		method = http.MethodPut
+		reqURL = fmt.Sprintf("%s/%d", reqURL, commentID)
+	}
+
+	payload := struct {
+		Body string `json:"body"`
+	}{commentBody.Body}
+
+	var note glNote
+	if err := gc.do(context.Background(), method, reqURL, payload, &note); err != nil {
+		return 0, fmt.Errorf("error posting MR note: %w", err)
+	}
+
+	return note.ID, nil
+}
+
+// toPR converts GitLab merge request and its notes to pr
+func (gc *gitLabClient) toPR(ctx context.Context, repoOwner, repoName string, mr glMergeRequest) (*pr, error) {
+	p := &pr{
+		Number:      mr.IID,
+		BaseRefName: mr.TargetBranch,
+		HeadRefName: mr.SourceBranch,
+		IsDraft:     mr.Draft,
+		Closed:      mr.State == "closed" || mr.State == "merged",
+		Merged:      mr.State == "merged",
+		UpdatedAt:   mr.UpdatedAt,
+		Author:      author{Login: mr.Author.Username},
+	}
+	p.BaseRepository.Name = repoName
+	p.BaseRepository.Owner.Login = repoOwner
+	p.BaseRepository.URL = fmt.Sprintf("%s/%s/%s", gc.rootURL, repoOwner, repoName)
+
+	for _, l := range mr.Labels {
+		p.Labels.Nodes = append(p.Labels.Nodes, prLabel{Name: l})
+	}
+
+	p.MergeCommit.Oid = mr.MergeCommitSHA
+	if p.MergeCommit.Oid == "" {
+		p.MergeCommit.Oid = mr.SquashCommitSHA
+	}
+
+	p.HeadRefOid = mr.SHA
+	p.Mergeable = glMergeable(mr.MergeStatus)
+	p.MergeStateStatus = glMergeStateStatus(mr.DetailedMergeStatus)
+
+	var approvals glApprovals
+	reqURL := fmt.Sprintf("%s/merge_requests/%d/approvals", gc.projectURL(repoOwner, repoName), mr.IID)
+	if err := gc.do(ctx, http.MethodGet, reqURL, nil, &approvals); err != nil {
+		return nil, fmt.Errorf("unable to get MR approvals, err:%w", err)
+	}
+	// approved is also true if project doesn't require any approvals
+	p.ReviewDecision = "REVIEW_REQUIRED"
+	if approvals.Approved && len(approvals.ApprovedBy) > 0 {
+		p.ReviewDecision = "APPROVED"
+	}
+
+	// notes are returned latest first, pages are fetched until oldest note
+	// or max pages limit is reached same as GitHub PR comments
+	var notes []glNote
+	reqURL = fmt.Sprintf("%s/merge_requests/%d/notes?order_by=created_at&sort=desc&per_page=100", gc.projectURL(repoOwner, repoName), mr.IID)
+	page := "1"
+	for range maxCommentPages {
+		var pageNotes []glNote
+		header, err := gc.send(ctx, http.MethodGet, reqURL+"&page="+page, nil, &pageNotes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get MR notes, err:%w", err)
+		}
+		notes = append(notes, pageNotes...)
+
+		if page = header.Get("X-Next-Page"); page == "" {
+			break
+		}
+	}
+
+	// notes are expected in chronological order
+	slices.Reverse(notes)
+	for _, n := range notes {
+		// skip system generated notes like 'added 1 commit'
+		if n.System {
+			continue
+		}
+		p.Comments.Nodes = append(p.Comments.Nodes, prComment{
+			DatabaseID: n.ID,
+			Author:     author{Login: n.Author.Username},
+			Body:       n.Body,
+			UpdatedAt:  n.UpdatedAt,
+		})
+	}
+
+	return p, nil
+}
+
+// glMergeable converts MR merge_status to GitHub's mergeable state
+func glMergeable(mergeStatus string) string {
+	switch mergeStatus {
+	case "can_be_merged":
+		return "MERGEABLE"
+	case "cannot_be_merged", "cannot_be_merged_recheck":
+		return "CONFLICTING"
+	}
+	return "UNKNOWN"
+}
+
+// glMergeStateStatus converts MR detailed_merge_status to GitHub's merge state status
+func glMergeStateStatus(detailedMergeStatus string) string {
+	switch detailedMergeStatus {
+	case "mergeable":
+		return "CLEAN"
+	case "need_rebase":
+		return "BEHIND"
+	case "conflict", "broken_status":
+		return "DIRTY"
+	case "draft_status":
+		return "DRAFT"
+	case "", "unchecked", "checking", "preparing", "approvals_syncing":
+		return "UNKNOWN"
+	}
+	return "BLOCKED"
+}
+
+// projectURL returns API url of the project, project path can contain sub groups
+func (gc *gitLabClient) projectURL(repoOwner, repoName string) string {
+	return fmt.Sprintf("%s/api/v4/projects/%s", gc.rootURL, url.PathEscape(repoOwner+"/"+repoName))
+}
+
+func (gc *gitLabClient) do(ctx context.Context, method, reqURL string, payload, result any) error {
+	_, err := gc.send(ctx, method, reqURL, payload, result)
+	return err
+}
+
+// send makes API call and returns response headers which contains
+// pagination details like 'X-Next-Page'
+func (gc *gitLabClient) send(ctx context.Context, method, reqURL string, payload, result any) (http.Header, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling payload to JSON: %w", err)
+		}
+		body = bytes.NewBuffer(data)
+	}
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PRIVATE-TOKEN", gc.token)
+
+	// Send the HTTP request
+	resp, err := gc.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending HTTP request: %w", err)
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	// Check the response status
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		return nil, fmt.Errorf("HTTP error: %s", resp.Status)
+	}
+
+	return resp.Header, json.NewDecoder(resp.Body).Decode(result)
+}
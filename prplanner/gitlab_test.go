@@ -0,0 +1,264 @@
+package prplanner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newGitLabStandIn returns test server which serves minimal subset of GitLab API
+// used by gitLabClient
+func newGitLabStandIn(t *testing.T, notes *[]map[string]any) *httptest.Server {
+	t.Helper()
+
+	mr := map[string]any{
+		"iid":                   7,
+		"target_branch":         "main",
+		"source_branch":         "feature",
+		"sha":                   "abc123",
+		"draft":                 false,
+		"state":                 "opened",
+		"merge_status":          "can_be_merged",
+		"detailed_merge_status": "mergeable",
+		"merge_commit_sha":      nil,
+		"updated_at":            "2024-05-01T10:00:00Z",
+		"author":                map[string]any{"username": "alice"},
+	}
+	olderMR := map[string]any{
+		"iid":           8,
+		"target_branch": "main",
+		"source_branch": "older",
+		"sha":           "def456",
+		"state":         "opened",
+		"merge_status":  "cannot_be_merged",
+		"updated_at":    "2024-04-01T10:00:00Z",
+		"author":        map[string]any{"username": "bob"},
+	}
+	staleMR := map[string]any{
+		"iid":           6,
+		"target_branch": "main",
+		"source_branch": "stale",
+		"state":         "opened",
+		"updated_at":    "2024-01-01T10:00:00Z",
+		"author":        map[string]any{"username": "alice"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/{id}/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != "opened" {
+			t.Errorf("unexpected state query %q", r.URL.Query().Get("state"))
+		}
+		writeGitLabPage(w, r, []map[string]any{mr, olderMR, staleMR})
+	})
+	mux.HandleFunc("GET /api/v4/projects/{id}/merge_requests/{iid}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(mr)
+	})
+	mux.HandleFunc("GET /api/v4/projects/{id}/merge_requests/{iid}/approvals", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"approved":    true,
+			"approved_by": []any{map[string]any{"user": map[string]any{"username": "carol"}}},
+		})
+	})
+	mux.HandleFunc("GET /api/v4/projects/{id}/merge_requests/{iid}/notes", func(w http.ResponseWriter, r *http.Request) {
+		// notes are returned in desc order
+		var resp []map[string]any
+		for i := len(*notes) - 1; i >= 0; i-- {
+			resp = append(resp, (*notes)[i])
+		}
+		writeGitLabPage(w, r, resp)
+	})
+	mux.HandleFunc("POST /api/v4/projects/{id}/merge_requests/{iid}/notes", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		note := map[string]any{
+			"id":         100 + len(*notes),
+			"body":       payload["body"],
+			"updated_at": "2024-05-01T11:00:00Z",
+			"author":     map[string]any{"username": "tf-applier"},
+		}
+		*notes = append(*notes, note)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(note)
+	})
+	mux.HandleFunc("PUT /api/v4/projects/{id}/merge_requests/{iid}/notes/{noteID}", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		for _, n := range *notes {
+			if r.PathValue("noteID") == jsonNumber(n["id"]) {
+				n["body"] = payload["body"]
+				json.NewEncoder(w).Encode(n)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// project path with sub groups must be url encoded
+		if !strings.HasPrefix(r.URL.EscapedPath(), "/api/v4/projects/infra%2Fteam%2Fterraform") {
+			t.Errorf("unexpected project path %q", r.URL.EscapedPath())
+		}
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+// writeGitLabPage writes single item per page so that pagination is always
+// exercised, 'X-Next-Page' is set if there are more items
+func writeGitLabPage(w http.ResponseWriter, r *http.Request, items []map[string]any) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	if page < len(items) {
+		w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+	}
+	resp := []map[string]any{}
+	if page <= len(items) {
+		resp = append(resp, items[page-1])
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func jsonNumber(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func Test_gitLabClient(t *testing.T) {
+	ctx := context.Background()
+
+	notes := []map[string]any{
+		{"id": 1, "body": "@terraform-applier plan foo", "updated_at": "2024-05-01T10:01:00Z", "author": map[string]any{"username": "bob"}},
+		{"id": 2, "body": "added 1 commit", "system": true, "updated_at": "2024-05-01T10:02:00Z", "author": map[string]any{"username": "bob"}},
+	}
+	server := newGitLabStandIn(t, &notes)
+	defer server.Close()
+
+	gc := &gitLabClient{rootURL: server.URL, http: server.Client(), token: "secret", maxPRAge: time.Since(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))}
+
+	wantPR := &pr{
+		Number:           7,
+		BaseRefName:      "main",
+		HeadRefName:      "feature",
+		HeadRefOid:       "abc123",
+		UpdatedAt:        time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Author:           author{Login: "alice"},
+		ReviewDecision:   "APPROVED",
+		Mergeable:        "MERGEABLE",
+		MergeStateStatus: "CLEAN",
+	}
+	wantPR.BaseRepository.Name = "terraform"
+	wantPR.BaseRepository.Owner.Login = "infra/team"
+	wantPR.BaseRepository.URL = server.URL + "/infra/team/terraform"
+	wantPR.Comments.Nodes = []prComment{
+		{DatabaseID: 1, Author: author{Login: "bob"}, Body: "@terraform-applier plan foo", UpdatedAt: time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC)},
+	}
+
+	olderPR := &pr{
+		Number:           8,
+		BaseRefName:      "main",
+		HeadRefName:      "older",
+		HeadRefOid:       "def456",
+		UpdatedAt:        time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC),
+		Author:           author{Login: "bob"},
+		ReviewDecision:   "APPROVED",
+		Mergeable:        "CONFLICTING",
+		MergeStateStatus: "UNKNOWN",
+	}
+	olderPR.BaseRepository = wantPR.BaseRepository
+	olderPR.Comments = wantPR.Comments
+
+	// MRs are fetched from all the pages until stale MR is found
+	t.Run("open MRs", func(t *testing.T) {
+		got, err := gc.openPRs(ctx, "infra/team", "terraform.git")
+		if err != nil {
+			t.Fatalf("openPRs() error = %v", err)
+		}
+		if diff := cmp.Diff([]*pr{wantPR, olderPR}, got); diff != "" {
+			t.Errorf("openPRs() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("get MR", func(t *testing.T) {
+		got, err := gc.PR(ctx, "infra/team", "terraform", 7)
+		if err != nil {
+			t.Fatalf("PR() error = %v", err)
+		}
+		if diff := cmp.Diff(wantPR, got); diff != "" {
+			t.Errorf("PR() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("post and update note", func(t *testing.T) {
+		id, err := gc.postComment("infra/team", "terraform", 0, 7, prComment{Body: "pending"})
+		if err != nil {
+			t.Fatalf("postComment() error = %v", err)
+		}
+		if id != 102 {
+			t.Errorf("postComment() id = %d, want 102", id)
+		}
+
+		gotID, err := gc.postComment("infra/team", "terraform", id, 7, prComment{Body: "output"})
+		if err != nil {
+			t.Fatalf("postComment() update error = %v", err)
+		}
+		if gotID != id {
+			t.Errorf("postComment() updated id = %d, want %d", gotID, id)
+		}
+
+		got, err := gc.PR(ctx, "infra/team", "terraform", 7)
+		if err != nil {
+			t.Fatalf("PR() error = %v", err)
+		}
+		last := got.Comments.Nodes[len(got.Comments.Nodes)-1]
+		if last.Body != "output" {
+			t.Errorf("updated note body = %q, want %q", last.Body, "output")
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		gc := &gitLabClient{rootURL: server.URL, http: server.Client(), token: "invalid"}
+		if _, err := gc.openPRs(ctx, "infra/team", "terraform"); err == nil {
+			t.Errorf("openPRs() expected error with invalid token")
+		}
+	})
+}
+
+func Test_planner_provider(t *testing.T) {
+	github := &gitHubClient{}
+	gitlab := &gitLabClient{}
+	p := &Planner{
+		github:    github,
+		providers: map[string]ProviderInterface{"gitlab.example.com": gitlab},
+	}
+
+	tests := []struct {
+		repoURL string
+		want    ProviderInterface
+	}{
+		{"https://github.com/utilitywarehouse/foo.git", github},
+		{"git@github.com:utilitywarehouse/foo.git", github},
+		{"https://gitlab.example.com/infra/team/foo.git", gitlab},
+		{"ssh://git@gitlab.example.com:2222/infra/foo.git", gitlab},
+		{"git@gitlab.example.com:infra/foo.git", gitlab},
+	}
+	for _, tt := range tests {
+		if got := p.provider(tt.repoURL); got != tt.want {
+			t.Errorf("provider(%q) = %T, want %T", tt.repoURL, got, tt.want)
+		}
+	}
+}
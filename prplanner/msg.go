@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"path"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -20,7 +22,10 @@ const (
 )
 
 var (
-	planReqMsgRegex = regexp.MustCompile("^`?@terraform-applier plan `?([\\w-.\\/]+)`?$")
+	// plan request also supports `all` and glob patterns e.g. `foo/*`
+	planReqMsgRegex  = regexp.MustCompile("^`?@terraform-applier plan `?([\\w-.\\/*?\\[\\]]+)`?$")
+	applyReqMsgRegex = regexp.MustCompile("^`?@terraform-applier apply `?([\\w-.\\/]+)`?$")
+	cmdMsgRegex      = regexp.MustCompile("^`?@terraform-applier (help|unlock|cancel)(?: `?([\\w-.\\/]+)`?)?(?: `?([\\w-]+)`?)?`?$")
 
 	// find our hidden JSON block
 	metadataRegex = regexp.MustCompile(fmt.Sprintf(`(?s)%s(.*?)%s`, regexp.QuoteMeta(metaStart), regexp.QuoteMeta(metaEnd)))
@@ -33,21 +38,93 @@ var (
 		"*(Do not edit this comment. This message will be updated once the plan run is completed.)*\n" +
 		">To manually trigger plan again please post `@terraform-applier plan %s` as comment."
 
+	applyRequestAcknowledgedMsgTml = "### Received terraform apply request for `%s`\n" +
+		"🏷️ **Commit:** %s | 🕒 **Requested At:** %s | 🔗 [View in %s terraform-applier web UI](%s)\n\n" +
+		"*(Do not edit this comment. This message will be updated once the apply run is completed.)*\n" +
+		">PR branch will be applied to `%s`, please merge PR once apply is completed."
+
+	applyRejectedMsgTml = "### Terraform apply request for `%s` rejected\n" +
+		"⛔ **Reason:** %s\n\n" +
+		">PR must be approved, mergeable and up to date with the base branch and " +
+		"the commenter must be an Admin of the module with `allowPRApply` enabled."
+
+	helpMsgTml = "### terraform-applier PR commands\n" +
+		"| Command | Description |\n" +
+		"|---|---|\n" +
+		"| `@terraform-applier plan <module>` | run plan for the module, module can be name or path |\n" +
+		"| `@terraform-applier plan <glob>` | run plan for all the modules of this PR matching glob pattern e.g. `foo/*` |\n" +
+		"| `@terraform-applier plan all` | run plan for all the modules of this PR |\n" +
+		"| `@terraform-applier apply <module>` | apply PR branch, only for module Admins if module's `allowPRApply` is set |\n" +
+		"| `@terraform-applier unlock <module> <lockID>` | force unlock module's state, only for module Admins |\n" +
+		"| `@terraform-applier cancel <module>` | cancel module's current run requested by this PR, only for module Admins |\n" +
+		"| `@terraform-applier help` | show this message |\n\n" +
+		">Module Admins are set via `GitHubUser` subjects of module's `Admin` RBAC role."
+
+	cmdReplyMsgTml = "### `%s` on %s\n%s %s"
+
 	runOutputMsgTml = "### Terraform Plan Output for `%s`\n" +
 		"🏷️ **Commit:** %s | 🔗 [View in %s terraform-applier web UI](%s)\n\n" +
 		"> To manually trigger plan again please post `@terraform-applier plan %s` as comment.\n" +
 		"<details><summary><b>%s Run Status: %s, Run Summary: %s</b></summary>" +
 		"\n\n```terraform\n%s\n```\n</details>\n"
+
+	summaryMsgTml = "### Terraform runs on %s\n" +
+		"*(Do not edit this comment. This message will be updated as runs are requested and completed.)*\n" +
+		">To manually trigger plan again please post `@terraform-applier plan <module_name>` as comment.\n\n" +
+		"| Module | Run | Commit | Status | Summary |\n" +
+		"|---|---|---|---|---|\n"
+
+	summaryRowTml = "| [`%s`](%s) | %s | `%s` | %s | %s |\n"
+
+	summaryOutputTml = "<details><summary><b>%s %s</b></summary>\n\n```terraform\n%s\n```\n</details>\n"
+
+	summaryOutputLinkTml = "<details><summary><b>%s %s</b></summary>\n\n" +
+		"Output is too long for the summary comment, please [view it in %s terraform-applier web UI](%s).\n</details>\n"
+
+	aggregatedMsgTml = "## Terraform runs across clusters\n" +
+		"*(Do not edit this comment. This message will be updated as runs are completed on each cluster.)*\n\n" +
+		"| Cluster | Module | Commit | Status | Summary |\n" +
+		"|---|---|---|---|---|\n"
+
+	aggregatedRowTml = "| %s | [`%s`](%s) | `%s` | %s | %s |\n"
+
+	aggregatedDashboardTml = "\n🔗 [View all runs in terraform-applier aggregator dashboard](%s)\n"
+
+	aggregatedOutputLinkTml = "### Terraform Output for `%s` on %s\n" +
+		"Output is too long for the combined comment, please [view it in %s terraform-applier web UI](%s).\n"
+
+	aggregatedOutputDroppedTml = "### Terraform Output for `%s` on %s\n" +
+		"Output is no longer available in the aggregator, please [view it in %s terraform-applier web UI](%s).\n"
 )
 
+// summaryCharacterLimit is the max size of the summary comment
+// https://github.com/orgs/community/discussions/27190
+const summaryCharacterLimit = 65000
+
 type MsgType string
 
 const (
 	MsgTypePlanRequest      MsgType = "PlanRequest"
+	MsgTypeApplyRequest     MsgType = "ApplyRequest"
 	MsgTypeRunOutput        MsgType = "RunOutput"
 	MsgTypeAutoPlanDisabled MsgType = "AutoPlanDisabled"
+	MsgTypeApplyRejected    MsgType = "ApplyRejected"
+	MsgTypeCommandReply     MsgType = "CommandReply"
+	MsgTypeSummary          MsgType = "Summary"
+	MsgTypeAggregated       MsgType = "Aggregated"
 )
 
+// summaryEntry is the latest request of the module displayed in summary comment
+type summaryEntry struct {
+	Module   types.NamespacedName
+	CommitID string
+	ReqType  string
+	// RunStatus is the status of the completed request
+	RunStatus string
+	// Run is nil if run output is not available yet or expired
+	Run *v1beta1.Run
+}
+
 // CommentMetadata is the hidden JSON structure
 type CommentMetadata struct {
 	Type     MsgType `json:"type"`
@@ -55,7 +132,15 @@ type CommentMetadata struct {
 	Module   string  `json:"module,omitempty"` // Stores "Namespace/Name"
 	Path     string  `json:"path,omitempty"`
 	CommitID string  `json:"commit_id,omitempty"`
-	ReqAt    string  `json:"req_at,omitempty"` // RFC3339 String
+	ReqAt    string  `json:"req_at,omitempty"`   // RFC3339 String
+	ReplyTo  int     `json:"reply_to,omitempty"` // ID of the command comment
+}
+
+// prCommand is the command posted as PR comment other then plan and apply
+type prCommand struct {
+	Name   string
+	Module string
+	LockID string
 }
 
 // embedMetadata serializes the struct into a hidden HTML comment
@@ -99,26 +184,112 @@ func parsePlanReqMsg(commentBody string) string {
 	return ""
 }
 
+func parseApplyReqMsg(commentBody string) string {
+	matches := applyReqMsgRegex.FindStringSubmatch(commentBody)
+
+	if len(matches) == 2 {
+		return matches[1]
+	}
+
+	return ""
+}
+
+func parseCommandMsg(commentBody string) *prCommand {
+	matches := cmdMsgRegex.FindStringSubmatch(commentBody)
+	if len(matches) != 4 {
+		return nil
+	}
+
+	cmd := &prCommand{Name: matches[1], Module: matches[2], LockID: matches[3]}
+
+	// validate required args
+	switch {
+	case cmd.Name == "help" && cmd.Module != "":
+		return nil
+	case cmd.Name == "unlock" && (cmd.Module == "" || cmd.LockID == ""):
+		return nil
+	case cmd.Name == "cancel" && (cmd.Module == "" || cmd.LockID != ""):
+		return nil
+	}
+
+	return cmd
+}
+
+// matchModule returns true if requested name or path matches given module.
+// 'all' matches every module and glob patterns are matched against both
+// name and path of the module
+func matchModule(nameOrPath string, module *v1beta1.Module) bool {
+	if nameOrPath == "" {
+		return false
+	}
+	if nameOrPath == "all" || nameOrPath == module.Name || nameOrPath == module.Spec.Path {
+		return true
+	}
+	if !strings.ContainsAny(nameOrPath, "*?[") {
+		return false
+	}
+	if ok, _ := path.Match(nameOrPath, module.Name); ok {
+		return true
+	}
+	ok, _ := path.Match(nameOrPath, module.Spec.Path)
+	return ok
+}
+
+func commandReplyMsg(cluster string, replyTo int, cmd, status, msg string) string {
+	display := fmt.Sprintf(cmdReplyMsgTml, cmd, cluster, status, msg)
+
+	meta := CommentMetadata{
+		Type:    MsgTypeCommandReply,
+		Cluster: cluster,
+		ReplyTo: replyTo,
+	}
+
+	return display + embedMetadata(meta)
+}
+
+func helpMsg(cluster string, replyTo int) string {
+	return helpMsgTml + embedMetadata(CommentMetadata{Type: MsgTypeCommandReply, Cluster: cluster, ReplyTo: replyTo})
+}
+
+func parseCommandReplyMsg(comment string) (cluster string, replyTo int) {
+	meta := extractMetadata(comment)
+	if meta == nil || meta.Type != MsgTypeCommandReply {
+		return
+	}
+	return meta.Cluster, meta.ReplyTo
+}
+
 func requestAcknowledgedMsg(cluster string, module types.NamespacedName, path, commitID string, reqAt *metav1.Time, webserverURL string) string {
-	moduleURL := webserverURL + "/#" + module.Namespace + "_" + module.Name
+	moduleURL := sysutil.ModuleWebURL(webserverURL, module)
 
 	display := fmt.Sprintf(requestAcknowledgedMsgTml, module.Name, commitID, reqAt.Format(time.RFC3339), cluster, moduleURL, path)
 
-	meta := CommentMetadata{
-		Type:     MsgTypePlanRequest,
+	return display + requestMetadata(MsgTypePlanRequest, cluster, module, path, commitID, reqAt)
+}
+
+func applyRequestAcknowledgedMsg(cluster string, module types.NamespacedName, path, commitID string, reqAt *metav1.Time, webserverURL string) string {
+	moduleURL := sysutil.ModuleWebURL(webserverURL, module)
+
+	display := fmt.Sprintf(applyRequestAcknowledgedMsgTml, module.Name, commitID, reqAt.Format(time.RFC3339), cluster, moduleURL, cluster)
+
+	return display + requestMetadata(MsgTypeApplyRequest, cluster, module, path, commitID, reqAt)
+}
+
+func requestMetadata(msgType MsgType, cluster string, module types.NamespacedName, path, commitID string, reqAt *metav1.Time) string {
+	return embedMetadata(CommentMetadata{
+		Type:     msgType,
 		Cluster:  cluster,
 		Module:   module.String(),
 		Path:     path,
 		CommitID: commitID,
 		ReqAt:    reqAt.Format(time.RFC3339),
-	}
-
-	return display + embedMetadata(meta)
+	})
 }
 
+// parseRequestAcknowledgedMsg parses both plan and apply request acknowledgements
 func parseRequestAcknowledgedMsg(commentBody string) (cluster string, module types.NamespacedName, path string, commID string, ReqAt *time.Time) {
 	meta := extractMetadata(commentBody)
-	if meta == nil || meta.Type != MsgTypePlanRequest {
+	if meta == nil || (meta.Type != MsgTypePlanRequest && meta.Type != MsgTypeApplyRequest) {
 		return
 	}
 
@@ -129,6 +300,32 @@ func parseRequestAcknowledgedMsg(commentBody string) (cluster string, module typ
 	return meta.Cluster, parseNamespaceName(meta.Module), meta.Path, meta.CommitID, ReqAt
 }
 
+func isApplyRequestAcknowledgedMsg(commentBody string) bool {
+	meta := extractMetadata(commentBody)
+	return meta != nil && meta.Type == MsgTypeApplyRequest
+}
+
+func applyRejectedMsg(cluster string, module types.NamespacedName, path, reason string) string {
+	display := fmt.Sprintf(applyRejectedMsgTml, module.Name, reason)
+
+	meta := CommentMetadata{
+		Type:    MsgTypeApplyRejected,
+		Cluster: cluster,
+		Module:  module.String(),
+		Path:    path,
+	}
+
+	return display + embedMetadata(meta)
+}
+
+func parseApplyRejectedMsg(comment string) (cluster string, module types.NamespacedName, path string) {
+	meta := extractMetadata(comment)
+	if meta == nil || meta.Type != MsgTypeApplyRejected {
+		return
+	}
+	return meta.Cluster, parseNamespaceName(meta.Module), meta.Path
+}
+
 func parseRunOutputMsg(comment string) (cluster string, module types.NamespacedName, path string, commit string) {
 	meta := extractMetadata(comment)
 	if meta == nil || meta.Type != MsgTypeRunOutput {
@@ -152,7 +349,7 @@ func runOutputMsg(cluster string, module types.NamespacedName, path string, run
 	}
 
 	msgTml := runOutputMsgTml
-	if strings.Contains(run.Summary, "Apply complete!") {
+	if run.Request != nil && run.Request.Type == v1beta1.PRApply {
 		msgTml = strings.Replace(msgTml, "Terraform Plan Output", "Terraform Apply Output", 1)
 	}
 
@@ -163,7 +360,7 @@ func runOutputMsg(cluster string, module types.NamespacedName, path string, run
 			"The output is truncated from the top.\n" + string(runes[(len(runes)-characterLimit):])
 	}
 
-	moduleURL := webserverURL + "/#" + module.Namespace + "_" + module.Name
+	moduleURL := sysutil.ModuleWebURL(webserverURL, module)
 
 	display := fmt.Sprintf(msgTml, module.Name, run.CommitHash, cluster, moduleURL, path, statusSymbol, run.Status, run.Summary, runOutput)
 
@@ -178,6 +375,150 @@ func runOutputMsg(cluster string, module types.NamespacedName, path string, run
 	return display + embedMetadata(meta)
 }
 
+// summaryMsg returns summary comment with table of all the module requests
+// and collapsible outputs. outputs which doesn't fit in the comment are
+// replaced with the link to web UI
+func summaryMsg(cluster string, entries []summaryEntry, webserverURL string) string {
+	var table, outputs strings.Builder
+
+	table.WriteString(fmt.Sprintf(summaryMsgTml, cluster))
+
+	for _, e := range entries {
+		moduleURL := sysutil.ModuleWebURL(webserverURL, e.Module)
+
+		runType := "Plan"
+		if e.ReqType == v1beta1.PRApply {
+			runType = "Apply"
+		}
+
+		commit := e.CommitID
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+
+		status, summary := "⏳ Pending", ""
+		if e.Run != nil && e.Run.Output != "" {
+			status = "✅ " + string(e.Run.Status)
+			if e.Run.Status == v1beta1.StatusErrored {
+				status = "⛔ " + string(e.Run.Status)
+			}
+			summary = strings.NewReplacer("|", "\\|", "\n", " ").Replace(e.Run.Summary)
+		} else if e.RunStatus != "" {
+			status = e.RunStatus
+		}
+
+		table.WriteString(fmt.Sprintf(summaryRowTml, e.Module, moduleURL, runType, commit, status, summary))
+	}
+
+	meta := embedMetadata(CommentMetadata{Type: MsgTypeSummary, Cluster: cluster})
+
+	// remaining characters for outputs
+	limit := summaryCharacterLimit - len([]rune(table.String())) - len([]rune(meta))
+
+	for _, e := range entries {
+		if e.Run == nil || e.Run.Output == "" {
+			continue
+		}
+
+		statusSymbol := "✅"
+		runOutput := e.Run.Output
+		// when run fails upload init output as well since it may contain
+		// reason of the failure
+		if e.Run.Status == v1beta1.StatusErrored {
+			statusSymbol = "⛔"
+			runOutput = e.Run.InitOutput + "\n" + e.Run.Output
+		}
+
+		section := fmt.Sprintf(summaryOutputTml, statusSymbol, e.Module, runOutput)
+		if len([]rune(section)) > limit {
+			moduleURL := sysutil.ModuleWebURL(webserverURL, e.Module)
+			section = fmt.Sprintf(summaryOutputLinkTml, statusSymbol, e.Module, cluster, moduleURL)
+		}
+		limit -= len([]rune(section))
+		outputs.WriteString(section)
+	}
+
+	if outputs.Len() > 0 {
+		table.WriteString("\n")
+	}
+
+	return table.String() + outputs.String() + meta
+}
+
+// parseSummaryMsg returns cluster of the summary comment
+func parseSummaryMsg(comment string) (cluster string) {
+	meta := extractMetadata(comment)
+	if meta == nil || meta.Type != MsgTypeSummary {
+		return
+	}
+	return meta.Cluster
+}
+
+// aggregatedMsg returns consolidated comment with table of latest runs of all
+// the clusters followed by output of each run in run output comment format.
+// outputs which doesn't fit in the comment are replaced with the link to the
+// web UI of the cluster
+func aggregatedMsg(runs []*AggregatedRun, dashboardURL string) string {
+	var table, outputs strings.Builder
+
+	table.WriteString(aggregatedMsgTml)
+
+	for _, r := range runs {
+		moduleURL := sysutil.ModuleWebURL(r.WebserverURL, r.Module)
+
+		commit := r.Run.CommitHash
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+
+		status := "✅ " + string(r.Run.Status)
+		if r.Run.Status == v1beta1.StatusErrored {
+			status = "⛔ " + string(r.Run.Status)
+		}
+		summary := strings.NewReplacer("|", "\\|", "\n", " ").Replace(r.Run.Summary)
+
+		table.WriteString(fmt.Sprintf(aggregatedRowTml, r.Cluster, r.Module, moduleURL, commit, status, summary))
+	}
+
+	if dashboardURL != "" {
+		table.WriteString(fmt.Sprintf(aggregatedDashboardTml, dashboardURL))
+	}
+	table.WriteString("\n")
+
+	meta := embedMetadata(CommentMetadata{Type: MsgTypeAggregated})
+
+	// remaining characters for outputs
+	limit := summaryCharacterLimit - len([]rune(table.String())) - len([]rune(meta))
+
+	for _, r := range runs {
+		if r.outputDropped {
+			moduleURL := sysutil.ModuleWebURL(r.WebserverURL, r.Module)
+			section := fmt.Sprintf(aggregatedOutputDroppedTml, r.Module.Name, r.Cluster, r.Cluster, moduleURL)
+			limit -= len([]rune(section))
+			outputs.WriteString(section)
+			continue
+		}
+		// metadata of each output is removed so that comment is not
+		// considered as run output comment by the clusters
+		section, _, _ := strings.Cut(runOutputMsg(r.Cluster, r.Module, r.Path, r.Run, r.WebserverURL), "\n\n"+metaStart)
+		section += "\n"
+		if len([]rune(section)) > limit {
+			moduleURL := sysutil.ModuleWebURL(r.WebserverURL, r.Module)
+			section = fmt.Sprintf(aggregatedOutputLinkTml, r.Module.Name, r.Cluster, r.Cluster, moduleURL)
+		}
+		limit -= len([]rune(section))
+		outputs.WriteString(section)
+	}
+
+	return table.String() + outputs.String() + meta
+}
+
+// isAggregatedMsg returns true if comment is aggregator's consolidated comment
+func isAggregatedMsg(comment string) bool {
+	meta := extractMetadata(comment)
+	return meta != nil && meta.Type == MsgTypeAggregated
+}
+
 func parseNamespaceName(str string) types.NamespacedName {
 	namespacedName := strings.Split(str, "/")
 
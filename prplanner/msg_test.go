@@ -92,6 +92,16 @@ func Test_parsePlanReqMsg(t *testing.T) {
 			args:                 args{commentBody: "@terraform-applier plan two please"},
 			wantModuleNameOrPath: "",
 		},
+		{
+			name:                 "plan all",
+			args:                 args{commentBody: "@terraform-applier plan all"},
+			wantModuleNameOrPath: "all",
+		},
+		{
+			name:                 "glob pattern",
+			args:                 args{commentBody: "@terraform-applier plan `foo/*`"},
+			wantModuleNameOrPath: "foo/*",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -103,6 +113,113 @@ func Test_parsePlanReqMsg(t *testing.T) {
 	}
 }
 
+func Test_parseApplyReqMsg(t *testing.T) {
+	tests := []struct {
+		name        string
+		commentBody string
+		want        string
+	}{
+		{"name only", "@terraform-applier apply one", "one"},
+		{"path", "@terraform-applier apply foo/one", "foo/one"},
+		{"with surrounding `", "`@terraform-applier apply foo/one`", "foo/one"},
+		{"plan request", "@terraform-applier plan one", ""},
+		{"random suffix", "@terraform-applier apply one please", ""},
+		{"request made twice", "@terraform-applier apply one\n@terraform-applier apply two", ""},
+		{"apply Acknowledged Msg", applyRequestAcknowledgedMsg("default", types.NamespacedName{Name: "one", Namespace: "foo"}, "foo/one", "hash1", mustParseMetaTime("2023-04-02T15:04:05Z"), "link"), ""},
+		{"apply rejected Msg", applyRejectedMsg("default", types.NamespacedName{Name: "one", Namespace: "foo"}, "foo/one", "PR is not approved"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseApplyReqMsg(tt.commentBody); got != tt.want {
+				t.Errorf("parseApplyReqMsg() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseCommandMsg(t *testing.T) {
+	tests := []struct {
+		name        string
+		commentBody string
+		want        *prCommand
+	}{
+		{"help", "@terraform-applier help", &prCommand{Name: "help"}},
+		{"help with args", "@terraform-applier help me", nil},
+		{"unlock", "@terraform-applier unlock foo/one 8d2a0f1e-6f1c-4b8a-a5b1-1f6d2f1c1a2b", &prCommand{Name: "unlock", Module: "foo/one", LockID: "8d2a0f1e-6f1c-4b8a-a5b1-1f6d2f1c1a2b"}},
+		{"unlock with `", "`@terraform-applier unlock one 1234`", &prCommand{Name: "unlock", Module: "one", LockID: "1234"}},
+		{"unlock without lock ID", "@terraform-applier unlock one", nil},
+		{"cancel", "@terraform-applier cancel one", &prCommand{Name: "cancel", Module: "one"}},
+		{"cancel without module", "@terraform-applier cancel", nil},
+		{"cancel with extra arg", "@terraform-applier cancel one two", nil},
+		{"unknown command", "@terraform-applier destroy one", nil},
+		{"plan", "@terraform-applier plan one", nil},
+		{"command reply", commandReplyMsg("default", 1, "cancel one", "✅", "cancelled"), nil},
+		{"help reply", helpMsg("default", 1), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseCommandMsg(tt.commentBody)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseCommandMsg() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_matchModule(t *testing.T) {
+	module := &v1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Name: "one", Namespace: "foo"},
+		Spec:       v1beta1.ModuleSpec{Path: "dev/foo/one"},
+	}
+
+	tests := []struct {
+		nameOrPath string
+		want       bool
+	}{
+		{"", false},
+		{"all", true},
+		{"one", true},
+		{"dev/foo/one", true},
+		{"two", false},
+		{"dev/foo", false},
+		{"dev/foo/*", true},
+		{"dev/*", false},
+		{"dev/*/one", true},
+		{"o*", true},
+		{"prod/*", false},
+		{"[", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nameOrPath, func(t *testing.T) {
+			if got := matchModule(tt.nameOrPath, module); got != tt.want {
+				t.Errorf("matchModule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_applyRequestAcknowledgedMsg(t *testing.T) {
+	module := types.NamespacedName{Name: "one", Namespace: "foo"}
+	msg := applyRequestAcknowledgedMsg("default", module, "foo/one", "hash1", mustParseMetaTime("2023-04-02T15:04:05Z"), "link")
+
+	// apply ack must be parsable as request ack so that output is uploaded
+	cluster, gotModule, path, commitID, reqAt := parseRequestAcknowledgedMsg(msg)
+	if cluster != "default" || gotModule != module || path != "foo/one" || commitID != "hash1" || reqAt == nil {
+		t.Errorf("parseRequestAcknowledgedMsg() = %s %s %s %s %v", cluster, gotModule, path, commitID, reqAt)
+	}
+	if !isApplyRequestAcknowledgedMsg(msg) {
+		t.Errorf("isApplyRequestAcknowledgedMsg() = false for apply request ack")
+	}
+	if isApplyRequestAcknowledgedMsg(requestAcknowledgedMsg("default", module, "foo/one", "hash1", mustParseMetaTime("2023-04-02T15:04:05Z"), "link")) {
+		t.Errorf("isApplyRequestAcknowledgedMsg() = true for plan request ack")
+	}
+
+	cluster, gotModule, path = parseApplyRejectedMsg(applyRejectedMsg("default", module, "foo/one", "PR is not approved"))
+	if cluster != "default" || gotModule != module || path != "foo/one" {
+		t.Errorf("parseApplyRejectedMsg() = %s %s %s", cluster, gotModule, path)
+	}
+}
+
 func Test_requestAcknowledgedMsg(t *testing.T) {
 	type args struct {
 		cluster  string
@@ -296,7 +413,7 @@ func Test_runOutputMsg(t *testing.T) {
 				}),
 		}, {
 			"3",
-			args{cluster: "default", module: types.NamespacedName{Name: "one", Namespace: "baz"}, path: "path/baz/one", run: &v1beta1.Run{Status: v1beta1.StatusOk, DiffDetected: true, CommitHash: "hash2", Summary: "Apply complete! Resources: x to add, x to change, x to destroy.", Output: "Terraform apply output...."}},
+			args{cluster: "default", module: types.NamespacedName{Name: "one", Namespace: "baz"}, path: "path/baz/one", run: &v1beta1.Run{Request: &v1beta1.Request{Type: v1beta1.PRApply}, Status: v1beta1.StatusOk, DiffDetected: true, CommitHash: "hash2", Summary: "Apply complete! Resources: x to add, x to change, x to destroy.", Output: "Terraform apply output...."}},
 			"### Terraform Apply Output for `one`\n" +
 				"🏷️ **Commit:** hash2 | 🔗 [View in default terraform-applier web UI](https://dashboard-url/#baz_one)\n\n" +
 				"> To manually trigger plan again please post `@terraform-applier plan path/baz/one` as comment.\n" +
@@ -313,6 +430,25 @@ func Test_runOutputMsg(t *testing.T) {
 					Path:     "path/baz/one",
 					CommitID: "hash2",
 				}),
+		}, {
+			"failed apply",
+			args{cluster: "default", module: types.NamespacedName{Name: "one", Namespace: "baz"}, path: "path/baz/one", run: &v1beta1.Run{Request: &v1beta1.Request{Type: v1beta1.PRApply}, Status: v1beta1.StatusErrored, CommitHash: "hash2", Summary: "unable to apply module", InitOutput: "Some Init Output...", Output: "Error: apply failed"}},
+			"### Terraform Apply Output for `one`\n" +
+				"🏷️ **Commit:** hash2 | 🔗 [View in default terraform-applier web UI](https://dashboard-url/#baz_one)\n\n" +
+				"> To manually trigger plan again please post `@terraform-applier plan path/baz/one` as comment.\n" +
+				"<details><summary><b>⛔ Run Status: Errored, Run Summary: unable to apply module</b></summary>\n\n" +
+				"```" +
+				"terraform\n" +
+				"Some Init Output...\nError: apply failed\n" +
+				"```\n" +
+				"</details>\n" +
+				embedMetadata(CommentMetadata{
+					Type:     MsgTypeRunOutput,
+					Cluster:  "default",
+					Module:   "baz/one",
+					Path:     "path/baz/one",
+					CommitID: "hash2",
+				}),
 		},
 	}
 	for _, tt := range tests {
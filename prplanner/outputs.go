@@ -8,9 +8,15 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/utilitywarehouse/git-mirror/giturl"
 	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
 )
 
 func (p *Planner) uploadRequestOutput(ctx context.Context, pr *pr) {
+	if p.plannerConfig(pr.BaseRepository.URL).summaryCommentEnabled() {
+		p.uploadSummaryOutput(ctx, pr)
+		return
+	}
+
 	// Go through PR comments in reverse order
 	for i := len(pr.Comments.Nodes) - 1; i >= 0; i-- {
 		comment := pr.Comments.Nodes[i]
@@ -24,6 +30,12 @@ func (p *Planner) uploadRequestOutput(ctx context.Context, pr *pr) {
 			continue
 		}
 
+		// skip if output is already posted
+		state := p.planState(ctx, pr.BaseRepository.URL, pr.Number, moduleNamespacedName, commitID)
+		if state != nil && state.State == sysutil.PRPlanStateCompleted {
+			continue
+		}
+
 		// ger run output from Redis
 		run, err := p.RedisClient.PRRun(ctx, moduleNamespacedName, pr.Number, commitID)
 		if err != nil {
@@ -34,15 +46,29 @@ func (p *Planner) uploadRequestOutput(ctx context.Context, pr *pr) {
 			continue
 		}
 
+		p.completeCheckRun(ctx, pr.BaseRepository.URL, pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, path, run)
+
+		// aggregator posts consolidated comment of all the clusters
+		if p.aggregator != nil {
+			if err := p.forwardRunOutput(ctx, pr.BaseRepository.URL, pr.Number, path, run); err != nil {
+				p.Log.Error("unable to send run output to aggregator", "module", moduleNamespacedName, "pr", pr.Number, "error", err)
+				continue
+			}
+			p.completePlanState(ctx, pr.BaseRepository.URL, pr.Number, moduleNamespacedName, commitID, comment.DatabaseID, string(run.Status))
+			p.Log.Info("run output sent to aggregator", "module", moduleNamespacedName, "pr", pr.Number)
+			continue
+		}
+
 		payload := prComment{
 			Body: runOutputMsg(p.ClusterEnvName, moduleNamespacedName, path, run, p.WebserverURL),
 		}
 
-		_, err = p.github.postComment(pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, comment.DatabaseID, pr.Number, payload)
+		_, err = p.provider(pr.BaseRepository.URL).postComment(pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, comment.DatabaseID, pr.Number, payload)
 		if err != nil {
 			p.Log.Error("error posting PR comment:", "error", err)
 			continue
 		}
+		p.completePlanState(ctx, pr.BaseRepository.URL, pr.Number, moduleNamespacedName, commitID, comment.DatabaseID, string(run.Status))
 		p.Log.Info("run output uploaded", "module", moduleNamespacedName, "pr", pr.Number)
 	}
 }
@@ -113,12 +139,42 @@ func (p *Planner) processRedisKeySetMsg(ctx context.Context, ch <-chan *redis.Me
 			continue
 		}
 
-		_, err = p.github.postComment(repo.Path, strings.TrimSuffix(repo.Repo, ".git"), CommentID, prNum, comment)
+		p.completeCheckRun(ctx, module.Spec.RepoURL, repo.Path, strings.TrimSuffix(repo.Repo, ".git"), module.Spec.Path, run)
+
+		// in summary mode PR run outputs are added to the summary comment,
+		// apply output of the merged PR is still posted as separate comment
+		if run.Request.PR != nil && p.plannerConfig(module.Spec.RepoURL).summaryCommentEnabled() {
+			p.completePlanState(ctx, module.Spec.RepoURL, prNum, run.Module, requestCommitID(run), CommentID, string(run.Status))
+			if _, err := p.updateSummaryComment(ctx, module.Spec.RepoURL, repo.Path, strings.TrimSuffix(repo.Repo, ".git"), prNum, CommentID); err != nil {
+				p.Log.Error("unable to update summary comment", "module", run.Module, "pr", prNum, "error", err)
+				continue
+			}
+			p.Log.Info("summary comment updated", "module", run.Module, "pr", prNum)
+			continue
+		}
+
+		// aggregator posts consolidated comment of all the clusters, apply
+		// output of the merged PR is still posted as separate comment
+		if run.Request.PR != nil && p.aggregator != nil {
+			if err := p.forwardRunOutput(ctx, module.Spec.RepoURL, prNum, module.Spec.Path, run); err != nil {
+				p.Log.Error("unable to send run output to aggregator", "module", run.Module, "pr", prNum, "error", err)
+				continue
+			}
+			p.completePlanState(ctx, module.Spec.RepoURL, prNum, run.Module, requestCommitID(run), CommentID, string(run.Status))
+			p.Log.Info("run output sent to aggregator", "module", run.Module, "pr", prNum)
+			continue
+		}
+
+		outputCommentID, err := p.provider(module.Spec.RepoURL).postComment(repo.Path, strings.TrimSuffix(repo.Repo, ".git"), CommentID, prNum, comment)
 		if err != nil {
 			p.Log.Error("error posting PR comment:", "module", run.Module, "pr", prNum, "error", err)
 			continue
 		}
 
+		if run.Request.PR != nil {
+			p.completePlanState(ctx, module.Spec.RepoURL, prNum, run.Module, requestCommitID(run), outputCommentID, string(run.Status))
+		}
+
 		p.Log.Info("run output posted", "module", run.Module, "pr", prNum)
 
 		// if apply output is posted then clean up PR runs
@@ -61,7 +61,7 @@ func Test_processRedisKeySetMsg(t *testing.T) {
 
 	goMockCtrl := gomock.NewController(t)
 	testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
-	testGithub := NewMockGithubInterface(goMockCtrl)
+	testGithub := NewMockProviderInterface(goMockCtrl)
 	planner := &Planner{
 		Log:         slog.Default(),
 		RedisClient: testRedis,
@@ -86,6 +86,17 @@ func Test_processRedisKeySetMsg(t *testing.T) {
 		testGithub.EXPECT().postComment("utilitywarehouse", "terraform-applier", 123, 4, gomock.Any()).
 			Return(123, nil)
 
+		// request made before state was stored
+		testRedis.EXPECT().PRPlanState(gomock.Any(), "", "github.com/utilitywarehouse/terraform-applier", 4, types.NamespacedName{Namespace: "foo", Name: "admins"}, "hash1").
+			Return(nil, sysutil.ErrKeyNotFound)
+		testRedis.EXPECT().SetPRPlanState(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, state *sysutil.PRPlanState) error {
+				if state.State != sysutil.PRPlanStateCompleted || state.OutputCommentID != 123 {
+					t.Errorf("unexpected plan state %+v", state)
+				}
+				return nil
+			})
+
 		ch <- &redis.Message{Channel: "__keyevent@0__:set", Payload: key}
 		time.Sleep(2 * time.Second)
 	})
@@ -94,12 +105,23 @@ func Test_processRedisKeySetMsg(t *testing.T) {
 		key := "foo:users:PR:4:d91f6ff"
 
 		testRedis.EXPECT().Run(gomock.Any(), key).
-			Return(&tfaplv1beta1.Run{Module: types.NamespacedName{Namespace: "foo", Name: "users"}, Request: &tfaplv1beta1.Request{PR: &tfaplv1beta1.PullRequest{Number: 4, CommentID: 123}}, CommitHash: "hash1", Output: "terraform plan output"}, nil)
+			Return(&tfaplv1beta1.Run{Module: types.NamespacedName{Namespace: "foo", Name: "users"}, Request: &tfaplv1beta1.Request{PR: &tfaplv1beta1.PullRequest{Number: 4, CommentID: 123, CommitID: "hash0"}}, CommitHash: "hash1", Output: "terraform plan output"}, nil)
 
 		// mock github API Call adding new request info
 		testGithub.EXPECT().postComment("utilitywarehouse", "terraform-applier", 123, 4, gomock.Any()).
 			Return(123, nil)
 
+		// PR branch updated after the request, state is stored for the request's commit
+		testRedis.EXPECT().PRPlanState(gomock.Any(), "", "github.com/utilitywarehouse/terraform-applier", 4, types.NamespacedName{Namespace: "foo", Name: "users"}, "hash0").
+			Return(&sysutil.PRPlanState{CommitID: "hash0", State: sysutil.PRPlanStateRequested}, nil)
+		testRedis.EXPECT().SetPRPlanState(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, state *sysutil.PRPlanState) error {
+				if state.State != sysutil.PRPlanStateCompleted || state.OutputCommentID != 123 || state.CommitID != "hash0" {
+					t.Errorf("unexpected plan state %+v", state)
+				}
+				return nil
+			})
+
 		ch <- &redis.Message{Channel: "__keyevent@0__:set", Payload: key}
 		time.Sleep(2 * time.Second)
 	})
@@ -2,9 +2,12 @@ package prplanner
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -25,28 +28,112 @@ type Planner struct {
 	GitMirror      repopool.Config
 	ClusterClt     client.Client
 	Repos          git.Repositories
+	ModuleDeps     *git.ModuleDeps
 	RedisClient    sysutil.RedisInterface
 	Runner         runner.RunnerInterface
-	github         GithubInterface
+	RunStatus      *sysutil.RunStatus
+	github         ProviderInterface
+	providers      map[string]ProviderInterface
 	Interval       time.Duration
 	Log            *slog.Logger
 	WebserverURL   string
+	Config         Config
+	// GitLabURL, GiteaURL and BitbucketURL are the root urls of the
+	// self-hosted instances hosting repositories
+	GitLabURL      string
+	GitLabToken    string
+	GiteaURL       string
+	GiteaToken     string
+	BitbucketURL   string
+	BitbucketToken string
+	// AggregatorURL is the url of the aggregator, if set PR run outputs are
+	// sent to the aggregator instead of posting them as PR comments
+	AggregatorURL   string
+	AggregatorToken string
+	aggregator      *aggregatorClient
+
+	// summaryMu serialises summary comment updates
+	summaryMu sync.Mutex
 }
 
 func (p *Planner) Init(ctx context.Context, ghApp sysutil.CredsProvider, ch <-chan *redis.Message) error {
+	if err := p.InitProviders(ghApp); err != nil {
+		return err
+	}
+
+	if p.AggregatorURL != "" {
+		p.aggregator = &aggregatorClient{
+			url:   strings.TrimSuffix(p.AggregatorURL, "/"),
+			token: p.AggregatorToken,
+			http: &http.Client{
+				Timeout: 15 * time.Second,
+			},
+		}
+	}
+
+	if ch != nil {
+		go p.processRedisKeySetMsg(ctx, ch)
+	}
+
+	go p.StartPRPoll(ctx)
+
+	return nil
+}
+
+// InitProviders sets up API clients of the git hosting providers
+func (p *Planner) InitProviders(ghApp sysutil.CredsProvider) error {
 	p.github = &gitHubClient{
 		rootURL: "https://api.github.com",
 		http: &http.Client{
 			Timeout: 15 * time.Second,
 		},
 		credsProvider: ghApp,
+		maxPRAge:      p.Config.maxStaleAfter(),
 	}
 
-	if ch != nil {
-		go p.processRedisKeySetMsg(ctx, ch)
+	p.providers = make(map[string]ProviderInterface)
+
+	if p.GitLabURL != "" {
+		err := p.addProvider(p.GitLabURL, &gitLabClient{
+			rootURL: strings.TrimSuffix(p.GitLabURL, "/"),
+			http: &http.Client{
+				Timeout: 15 * time.Second,
+			},
+			token:    p.GitLabToken,
+			maxPRAge: p.Config.maxStaleAfter(),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to add gitlab provider err:%w", err)
+		}
 	}
 
-	go p.StartPRPoll(ctx)
+	if p.GiteaURL != "" {
+		err := p.addProvider(p.GiteaURL, &giteaClient{
+			rootURL: strings.TrimSuffix(p.GiteaURL, "/"),
+			http: &http.Client{
+				Timeout: 15 * time.Second,
+			},
+			token:    p.GiteaToken,
+			maxPRAge: p.Config.maxStaleAfter(),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to add gitea provider err:%w", err)
+		}
+	}
+
+	if p.BitbucketURL != "" {
+		err := p.addProvider(p.BitbucketURL, &bitbucketClient{
+			rootURL: strings.TrimSuffix(p.BitbucketURL, "/"),
+			http: &http.Client{
+				Timeout: 15 * time.Second,
+			},
+			token:    p.BitbucketToken,
+			maxPRAge: p.Config.maxStaleAfter(),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to add bitbucket provider err:%w", err)
+		}
+	}
 
 	return nil
 }
@@ -75,8 +162,8 @@ func (p *Planner) StartPRPoll(ctx context.Context) {
 					continue
 				}
 
-				// Make a GraphQL query to fetch all open Pull Requests from Github
-				prs, err := p.github.openPRs(ctx, repo.Path, repo.Repo)
+				// fetch all open Pull Requests from the git provider
+				prs, err := p.provider(repoConf.Remote).openPRs(ctx, repo.Path, repo.Repo)
 				if err != nil {
 					p.Log.Error("error getting a list of open PRs:", "url", repo.Path, "error", err)
 					continue
@@ -95,8 +182,14 @@ func (p *Planner) processPullRequest(ctx context.Context, pr *pr, kubeModuleList
 	if pr.Closed && !pr.Merged {
 		return
 	}
+	conf := p.plannerConfig(pr.BaseRepository.URL)
+
 	// only process recently updated PR
-	if time.Since(pr.UpdatedAt) > 24*time.Hour {
+	if time.Since(pr.UpdatedAt) > conf.StaleAfter {
+		return
+	}
+
+	if conf.isAuthorIgnored(pr.Author.Login) {
 		return
 	}
 
@@ -108,7 +201,7 @@ func (p *Planner) processPullRequest(ctx context.Context, pr *pr, kubeModuleList
 	}
 
 	// verify if PR belongs to module based on files changed
-	prModules, err := p.getPRModuleList(pr, commitsInfo, kubeModuleList)
+	prModules, err := p.getPRModuleList(ctx, pr, commitsInfo, kubeModuleList)
 	if err != nil {
 		p.Log.Error("error getting a list of modules in PR", "repo", pr.BaseRepository.Name, "pr", pr.Number, "error", err)
 		return
@@ -119,41 +212,44 @@ func (p *Planner) processPullRequest(ctx context.Context, pr *pr, kubeModuleList
 		return
 	}
 
-	// only process manual comment request for draft PRs
-	skipCommitRun := pr.IsDraft
-
-	if len(prModules) > 5 {
-		skipCommitRun = true
-	}
+	// only process manual comment request for draft PRs, PRs updating too many
+	// modules or PRs with skip label
+	skipCommitRun := conf.skipAutoPlan(pr, len(prModules))
 
 	if skipCommitRun {
 		// add limit msg comment if not already added
 		if !isAutoPlanDisabledCommentPosted(pr.Comments.Nodes) {
 			comment := prComment{Body: autoPlanDisabledTml + embedMetadata(CommentMetadata{Type: MsgTypeAutoPlanDisabled})}
-			_, err := p.github.postComment(pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, 0, pr.Number, comment)
+			_, err := p.provider(pr.BaseRepository.URL).postComment(pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, 0, pr.Number, comment)
 			if err != nil {
 				p.Log.Error("unable to post limit reached msg", "err", err)
 			}
 		}
 	}
 
+	p.processPRCommands(ctx, pr, prModules, kubeModuleList)
+
 	// ensure plan requests
 	p.ensurePlanRequests(ctx, pr, commitsInfo, prModules, skipCommitRun)
 
 	p.uploadRequestOutput(ctx, pr)
 }
 
-func (p *Planner) getPRModuleList(pr *pr, commitsInfo []repository.CommitInfo, kubeModules *tfaplv1beta1.ModuleList) ([]types.NamespacedName, error) {
+func (p *Planner) getPRModuleList(ctx context.Context, pr *pr, commitsInfo []repository.CommitInfo, kubeModules *tfaplv1beta1.ModuleList) ([]types.NamespacedName, error) {
 	var modulesUpdated []types.NamespacedName
 
+	baseBranches := p.plannerConfig(pr.BaseRepository.URL).BaseBranches
+
 	for _, kubeModule := range kubeModules.Items {
 		if ok, _ := giturl.SameRawURL(kubeModule.Spec.RepoURL, pr.BaseRepository.URL); !ok {
 			continue
 		}
 
+		deps := p.moduleDeps(ctx, &kubeModule, commitsInfo)
+
 		updated := false
 		for _, commit := range commitsInfo {
-			if isModuleUpdated(&kubeModule, commit) {
+			if isModuleUpdated(&kubeModule, deps, commit) {
 				updated = true
 				break
 			}
@@ -163,9 +259,10 @@ func (p *Planner) getPRModuleList(pr *pr, commitsInfo []repository.CommitInfo, k
 			continue
 		}
 
-		// default value of RepoRef is 'HEAD', which is normally a master branch
+		// default value of RepoRef is 'HEAD', which is normally one of the
+		// configured base branches
 		if kubeModule.Spec.RepoRef != pr.BaseRefName &&
-			pr.BaseRefName != "master" && pr.BaseRefName != "main" {
+			!slices.Contains(baseBranches, pr.BaseRefName) {
 			continue
 		}
 
@@ -175,11 +272,38 @@ func (p *Planner) getPRModuleList(pr *pr, commitsInfo []repository.CommitInfo, k
 	return modulesUpdated, nil
 }
 
-func isModuleUpdated(module *tfaplv1beta1.Module, commit repository.CommitInfo) bool {
+// isModuleUpdated returns true if commit updated files in module path, in
+// any of its local module dependencies or watch paths
+func isModuleUpdated(module *tfaplv1beta1.Module, deps []string, commit repository.CommitInfo) bool {
 	for _, path := range commit.ChangedFiles {
 		if strings.HasPrefix(path, module.Spec.Path) {
 			return true
 		}
+		for _, dep := range deps {
+			if strings.HasPrefix(path, dep+"/") {
+				return true
+			}
+		}
+		for _, wp := range module.Spec.WatchPaths {
+			if git.MatchPath(wp, path) {
+				return true
+			}
+		}
 	}
 	return false
 }
+
+// moduleDeps returns paths of local module dependencies of the module at the
+// latest commit of the given commits
+func (p *Planner) moduleDeps(ctx context.Context, module *tfaplv1beta1.Module, commitsInfo []repository.CommitInfo) []string {
+	if p.ModuleDeps == nil || len(commitsInfo) == 0 {
+		return nil
+	}
+	paths, err := p.ModuleDeps.Paths(ctx, module.Spec.RepoURL, commitsInfo[0].Hash, module.Spec.Path)
+	if err != nil {
+		p.Log.Warn("unable to get module dependencies", "module", module.NamespacedName(), "commit", commitsInfo[0].Hash, "error", err)
+		return nil
+	}
+	// first path is the module path
+	return paths[1:]
+}
@@ -34,7 +34,7 @@ func Test_processPullRequest(t *testing.T) {
 
 	t.Run("skip draft PR", func(t *testing.T) {
 		goMockCtrl := gomock.NewController(t)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		p := generateMockPR(123, "ref1",
 			[]string{"random comment", "random comment", "random comment"},
@@ -63,7 +63,7 @@ func Test_processPullRequest(t *testing.T) {
 
 	t.Run("skip old PR", func(t *testing.T) {
 		goMockCtrl := gomock.NewController(t)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		p := generateMockPR(123, "ref1",
 			[]string{"random comment", "random comment", "random comment"},
@@ -98,7 +98,7 @@ func Test_processPullRequest(t *testing.T) {
 
 	t.Run("len PR modules > 5 + module limit comment not posted", func(t *testing.T) {
 		goMockCtrl := gomock.NewController(t)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 
 		p := generateMockPR(123, "ref1",
@@ -127,3 +127,34 @@ func Test_processPullRequest(t *testing.T) {
 		planner.processPullRequest(ctx, p, kubeModuleList)
 	})
 }
+
+func Test_isModuleUpdated(t *testing.T) {
+	module := &tfaplv1beta1.Module{Spec: tfaplv1beta1.ModuleSpec{
+		Path:       "dev/app",
+		WatchPaths: []string{"templates/app", "policies/**/*.json"},
+	}}
+	deps := []string{"modules/vpc", "modules/common"}
+
+	tests := []struct {
+		name  string
+		files []string
+		want  bool
+	}{
+		{"module path", []string{"README.md", "dev/app/main.tf"}, true},
+		{"dependency", []string{"modules/vpc/main.tf"}, true},
+		{"transitive dependency", []string{"modules/common/variables.tf"}, true},
+		{"dependency prefix", []string{"modules/vpc-peering/main.tf"}, false},
+		{"other module", []string{"prod/app/main.tf", "modules/db/main.tf"}, false},
+		{"watch path", []string{"templates/app/user-data.tpl"}, true},
+		{"watch path glob", []string{"policies/iam/admin.json"}, true},
+		{"watch path glob no match", []string{"policies/iam/admin.yaml", "templates/db/init.sql"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit := repository.CommitInfo{Hash: "c1", ChangedFiles: tt.files}
+			if got := isModuleUpdated(module, deps, commit); got != tt.want {
+				t.Errorf("isModuleUpdated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package prplanner
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/utilitywarehouse/git-mirror/giturl"
+)
+
+//go:generate go run github.com/golang/mock/mockgen -package prplanner -destination provider_mock.go github.com/utilitywarehouse/terraform-applier/prplanner ProviderInterface
+
+// ProviderInterface allows for mocking out the functionality of git hosting
+// provider API Calls. change requests (GitHub PRs, GitLab MRs etc) and its notes
+// are converted to pr and prComment types
+type ProviderInterface interface {
+	openPRs(ctx context.Context, repoOwner, repoName string) ([]*pr, error)
+	PR(ctx context.Context, repoOwner, repoName string, prNumber int) (*pr, error)
+	postComment(repoOwner, repoName string, commentID, prNumber int, commentBody prComment) (int, error)
+}
+
+// addProvider registers provider client for the host of the given url
+func (p *Planner) addProvider(rootURL string, provider ProviderInterface) error {
+	u, err := url.Parse(rootURL)
+	if err != nil {
+		return err
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("host not found in url %q", rootURL)
+	}
+	p.providers[u.Hostname()] = provider
+	return nil
+}
+
+// provider returns API client of the provider hosting given repository.
+// GitHub is used for all the hosts without explicitly configured provider
+func (p *Planner) provider(repoURL string) ProviderInterface {
+	u, err := giturl.Parse(repoURL)
+	if err != nil {
+		return p.github
+	}
+	// ssh urls might contain port
+	host, _, _ := strings.Cut(u.Host, ":")
+	if provider, ok := p.providers[host]; ok {
+		return provider
+	}
+	return p.github
+}
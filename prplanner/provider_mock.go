@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/utilitywarehouse/terraform-applier/prplanner (interfaces: ProviderInterface)
+
+// Package prplanner is a generated GoMock package.
+package prplanner
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockProviderInterface is a mock of ProviderInterface interface.
+type MockProviderInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockProviderInterfaceMockRecorder
+}
+
+// MockProviderInterfaceMockRecorder is the mock recorder for MockProviderInterface.
+type MockProviderInterfaceMockRecorder struct {
+	mock *MockProviderInterface
+}
+
+// NewMockProviderInterface creates a new mock instance.
+func NewMockProviderInterface(ctrl *gomock.Controller) *MockProviderInterface {
+	mock := &MockProviderInterface{ctrl: ctrl}
+	mock.recorder = &MockProviderInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProviderInterface) EXPECT() *MockProviderInterfaceMockRecorder {
+	return m.recorder
+}
+
+// PR mocks base method.
+func (m *MockProviderInterface) PR(arg0 context.Context, arg1, arg2 string, arg3 int) (*pr, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PR", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*pr)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PR indicates an expected call of PR.
+func (mr *MockProviderInterfaceMockRecorder) PR(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PR", reflect.TypeOf((*MockProviderInterface)(nil).PR), arg0, arg1, arg2, arg3)
+}
+
+// openPRs mocks base method.
+func (m *MockProviderInterface) openPRs(arg0 context.Context, arg1, arg2 string) ([]*pr, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "openPRs", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*pr)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// openPRs indicates an expected call of openPRs.
+func (mr *MockProviderInterfaceMockRecorder) openPRs(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "openPRs", reflect.TypeOf((*MockProviderInterface)(nil).openPRs), arg0, arg1, arg2)
+}
+
+// postComment mocks base method.
+func (m *MockProviderInterface) postComment(arg0, arg1 string, arg2, arg3 int, arg4 prComment) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "postComment", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// postComment indicates an expected call of postComment.
+func (mr *MockProviderInterfaceMockRecorder) postComment(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "postComment", reflect.TypeOf((*MockProviderInterface)(nil).postComment), arg0, arg1, arg2, arg3, arg4)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/utilitywarehouse/git-mirror/repository"
 	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/git"
 	"github.com/utilitywarehouse/terraform-applier/sysutil"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -32,6 +33,7 @@ func (p *Planner) ensurePlanRequests(ctx context.Context, pr *pr, commitsInfo []
 			continue
 		}
 		if req != nil {
+			p.startCheckRun(ctx, pr, req)
 			run := tfaplv1beta1.NewRun(module, req)
 			cancelChan := make(chan struct{})
 			go p.Runner.Start(&run, cancelChan)
@@ -56,18 +58,21 @@ func (p *Planner) ensurePlanRequest(ctx context.Context, pr *pr, commitsInfo []r
 }
 
 func (p *Planner) checkPRCommits(ctx context.Context, pr *pr, commitsInfo []repository.CommitInfo, module *tfaplv1beta1.Module) (*tfaplv1beta1.Request, error) {
-	// loop through commits to check if module path is updated
+	deps := p.moduleDeps(ctx, module, commitsInfo)
+
+	// loop through commits to check if module path or its dependencies are updated
 	for _, commit := range commitsInfo {
-		if !isModuleUpdated(module, commit) {
+		if !isModuleUpdated(module, deps, commit) {
 			continue
 		}
 
-		// check if we have already processed (uploaded output) this commit
-		if isPlanOutputPostedForCommit(p.ClusterEnvName, pr, commit.Hash, module.Spec.Path, module.NamespacedName()) {
+		// check if request is already made for this commit
+		if isRequestProcessed(p.planState(ctx, pr.BaseRepository.URL, pr.Number, module.NamespacedName(), commit.Hash), p.plannerConfig(pr.BaseRepository.URL).StaleRequestAfter) {
 			return nil, nil
 		}
 
-		if isPlanRequestAckPostedForCommit(p.ClusterEnvName, pr, commit.Hash, module.Spec.Path, module.NamespacedName()) {
+		// check if we have already processed (uploaded output) this commit
+		if isPlanOutputPostedForCommit(p.ClusterEnvName, pr, commit.Hash, module.Spec.Path, module.NamespacedName()) {
 			return nil, nil
 		}
 
@@ -83,33 +88,26 @@ func (p *Planner) checkPRCommits(ctx context.Context, pr *pr, commitsInfo []repo
 
 		// request run
 		p.Log.Info("triggering plan due to new commit", "module", module.NamespacedName(), "pr", pr.Number, "author", pr.Author.Login)
-		return p.addNewRequest(module, pr, commit.Hash)
+		return p.addNewRequest(module, pr, commit.Hash, tfaplv1beta1.PRPlan, 0)
 	}
 
 	return nil, nil
 }
 
 func (p *Planner) checkPRCommentsForPlanRequests(pr *pr, module *tfaplv1beta1.Module) (*tfaplv1beta1.Request, error) {
+	staleAfter := p.plannerConfig(pr.BaseRepository.URL).StaleAfter
+
 	// Go through PR comments in reverse order
 	for i := len(pr.Comments.Nodes) - 1; i >= 0; i-- {
 		comment := pr.Comments.Nodes[i]
 
 		// skip old comments
-		if time.Since(comment.UpdatedAt) > 24*time.Hour {
-			return nil, nil
-		}
-
-		// Skip if request already acknowledged for module
-		commentCluster, commentModule, commentPath, _, reqAt := parseRequestAcknowledgedMsg(comment.Body)
-		if commentCluster == p.ClusterEnvName &&
-			commentModule == module.NamespacedName() &&
-			commentPath == module.Spec.Path &&
-			reqAt != nil && time.Until(*reqAt) < 10*time.Minute {
+		if time.Since(comment.UpdatedAt) > staleAfter {
 			return nil, nil
 		}
 
 		// Skip if terraform plan output is already posted
-		commentCluster, commentModule, commentPath, _ = parseRunOutputMsg(comment.Body)
+		commentCluster, commentModule, commentPath, _ := parseRunOutputMsg(comment.Body)
 		if commentCluster == p.ClusterEnvName &&
 			commentModule == module.NamespacedName() &&
 			commentPath == module.Spec.Path {
@@ -120,18 +118,39 @@ func (p *Planner) checkPRCommentsForPlanRequests(pr *pr, module *tfaplv1beta1.Mo
 		// '@terraform-applier plan module-name'
 		// or
 		// '@terraform-applier plan path/to/the/module-name'
+		// or
+		// '@terraform-applier plan all' / '@terraform-applier plan path/to/*'
 		requestedModuleNameOrPath := parsePlanReqMsg(comment.Body)
 
-		// match either given name or path
-		if requestedModuleNameOrPath == module.Name || requestedModuleNameOrPath == module.Spec.Path {
+		// match either given name, path, glob or all
+		if matchModule(requestedModuleNameOrPath, module) {
 			// get current hash of the module path to create new plan request
-			modulePathHash, err := p.Repos.Hash(context.Background(), module.Spec.RepoURL, pr.HeadRefName, module.Spec.Path)
+			modulePathHash, err := git.ModuleHash(context.Background(), p.Repos, p.ModuleDeps, module.Spec.RepoURL, pr.HeadRefName, module.Spec.Path, module.Spec.WatchPaths)
 			if err != nil {
 				return nil, err
 			}
 
+			if p.isCommentRequestProcessed(pr, module, comment, modulePathHash) {
+				return nil, nil
+			}
+
 			p.Log.Info("triggering plan requested via comment", "module", module.NamespacedName(), "pr", pr.Number, "author", comment.Author.Login)
-			return p.addNewRequest(module, pr, modulePathHash)
+			return p.addNewRequest(module, pr, modulePathHash, tfaplv1beta1.PRPlan, comment.DatabaseID)
+		}
+
+		// Skip if apply request is already rejected for module
+		commentCluster, commentModule, commentPath = parseApplyRejectedMsg(comment.Body)
+		if commentCluster == p.ClusterEnvName &&
+			commentModule == module.NamespacedName() &&
+			commentPath == module.Spec.Path {
+			return nil, nil
+		}
+
+		// Check if user requested terraform apply run via
+		// '@terraform-applier apply module-name'
+		requestedModuleNameOrPath = parseApplyReqMsg(comment.Body)
+		if requestedModuleNameOrPath == module.Name || requestedModuleNameOrPath == module.Spec.Path {
+			return p.addApplyRequest(module, pr, comment)
 		}
 	}
 
@@ -153,33 +172,114 @@ func isPlanOutputPostedForCommit(cluster string, pr *pr, commitID, modulePath st
 	return false
 }
 
-// isPlanRequestAckPostedForCommit loops through all the comments to check if given commit
-// ids plan request is already acknowledged
-func isPlanRequestAckPostedForCommit(cluster string, pr *pr, commitID, modulePath string, module types.NamespacedName) bool {
-	for i := len(pr.Comments.Nodes) - 1; i >= 0; i-- {
-		comment := pr.Comments.Nodes[i]
+// addApplyRequest validates apply request posted as comment and either creates
+// new PR apply request or posts rejection reason as reply. apply is pinned
+// to the PR head commit so that only planned and approved changes are applied
+func (p *Planner) addApplyRequest(module *tfaplv1beta1.Module, pr *pr, comment prComment) (*tfaplv1beta1.Request, error) {
+	var modulePathHash string
+	var plan *sysutil.PRPlanState
+	if pr.HeadRefOid != "" {
+		// get hash of the module path at the PR head commit to create new apply request
+		var err error
+		modulePathHash, err = git.ModuleHash(context.Background(), p.Repos, p.ModuleDeps, module.Spec.RepoURL, pr.HeadRefOid, module.Spec.Path, module.Spec.WatchPaths)
+		if err != nil {
+			return nil, err
+		}
 
-		commentCluster, commentModule, commentPath, commentCommitID, reqAt := parseRequestAcknowledgedMsg(comment.Body)
-		if commentCluster == cluster &&
-			commentModule == module &&
-			commentPath == modulePath &&
-			commentCommitID == commitID &&
-			reqAt != nil && time.Until(*reqAt) > -10*time.Minute && time.Until(*reqAt) < time.Minute {
-			return true
+		if p.isCommentRequestProcessed(pr, module, comment, modulePathHash) {
+			return nil, nil
 		}
+
+		plan = p.planState(context.Background(), pr.BaseRepository.URL, pr.Number, module.NamespacedName(), modulePathHash)
 	}
 
-	return false
+	if reason := p.applyRejectionReason(pr, module, comment.Author.Login, plan); reason != "" {
+		p.Log.Info("apply requested via comment rejected", "module", module.NamespacedName(), "pr", pr.Number, "author", comment.Author.Login, "reason", reason)
+
+		commentBody := prComment{
+			Body: applyRejectedMsg(p.ClusterEnvName, module.NamespacedName(), module.Spec.Path, reason),
+		}
+		_, err := p.provider(pr.BaseRepository.URL).postComment(pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, 0, pr.Number, commentBody)
+		if err != nil {
+			return nil, fmt.Errorf("unable to post apply rejected comment: %w", err)
+		}
+		return nil, nil
+	}
+
+	p.Log.Info("triggering apply requested via comment", "module", module.NamespacedName(), "pr", pr.Number, "author", comment.Author.Login, "commit", pr.HeadRefOid)
+	return p.addNewRequest(module, pr, modulePathHash, tfaplv1beta1.PRApply, comment.DatabaseID)
+}
+
+// isCommentRequestProcessed returns true if request comment is already processed,
+// there is a newer request for the same commit or there is another pending request
+func (p *Planner) isCommentRequestProcessed(pr *pr, module *tfaplv1beta1.Module, comment prComment, commitID string) bool {
+	state := p.planState(context.Background(), pr.BaseRepository.URL, pr.Number, module.NamespacedName(), commitID)
+	if state == nil {
+		return false
+	}
+	if state.TriggerCommentID == comment.DatabaseID {
+		return true
+	}
+	if state.Request != nil && state.Request.RequestedAt != nil && state.Request.RequestedAt.After(comment.UpdatedAt) {
+		return true
+	}
+	return state.State != sysutil.PRPlanStateCompleted && isRequestProcessed(state, p.plannerConfig(pr.BaseRepository.URL).StaleRequestAfter)
+}
+
+// applyRejectionReason returns reason if PR branch can not be applied by
+// the given user, empty string is returned if apply is allowed. plan is the
+// state of the module request at the current PR head commit
+func (p *Planner) applyRejectionReason(pr *pr, module *tfaplv1beta1.Module, login string, plan *sysutil.PRPlanState) string {
+	switch {
+	case module.IsPlanOnly():
+		return "module is set to plan only"
+	case !module.Spec.AllowPRApply:
+		return "module does not allow apply from PR (`spec.allowPRApply` is not set)"
+	case !module.IsApplyAllowedAt(time.Now()):
+		return "module is outside of its apply windows"
+	case !p.canRunPRCommand(pr, login, module):
+		return fmt.Sprintf("`%s` is not an Admin of the module", login)
+	case pr.ReviewDecision != "APPROVED":
+		return "PR is not approved"
+	case pr.Mergeable != "MERGEABLE":
+		return "PR is not mergeable"
+	case pr.MergeStateStatus == "BEHIND" || pr.MergeStateStatus == "DIRTY":
+		return "PR branch is not up to date with the base branch"
+	case pr.HeadRefOid == "":
+		return "PR head commit is unknown"
+	case plan == nil || plan.Request == nil || plan.Request.Type != tfaplv1beta1.PRPlan ||
+		plan.State != sysutil.PRPlanStateCompleted || plan.RunStatus != string(tfaplv1beta1.StatusOk):
+		return fmt.Sprintf("module is not successfully planned at the PR head commit `%s`, please plan again", pr.HeadRefOid)
+	case plan.HeadCommit != pr.HeadRefOid:
+		return fmt.Sprintf("PR head has moved to `%s` since it was planned, please plan again", pr.HeadRefOid)
+	}
+	return ""
 }
 
-func (p *Planner) addNewRequest(module *tfaplv1beta1.Module, pr *pr, commitID string) (*tfaplv1beta1.Request, error) {
-	req := module.NewRunRequest(tfaplv1beta1.PRPlan, "")
+// canRunPRCommand returns true if comment author is allowed to run privileged
+// PR commands. 'GitHubUser' RBAC subjects can only be matched with GitHub logins
+func (p *Planner) canRunPRCommand(pr *pr, login string, module *tfaplv1beta1.Module) bool {
+	if p.provider(pr.BaseRepository.URL) != p.github {
+		return false
+	}
+	return tfaplv1beta1.CanRunPRCommand(login, module)
+}
+
+func (p *Planner) addNewRequest(module *tfaplv1beta1.Module, pr *pr, commitID string, reqType string, triggerCommentID int) (*tfaplv1beta1.Request, error) {
+	if p.plannerConfig(pr.BaseRepository.URL).summaryCommentEnabled() {
+		return p.addNewSummaryRequest(module, pr, commitID, reqType, triggerCommentID), nil
+	}
+
+	req := module.NewRunRequest(reqType, "")
 
 	commentBody := prComment{
 		Body: requestAcknowledgedMsg(p.ClusterEnvName, module.NamespacedName(), module.Spec.Path, commitID, req.RequestedAt, p.WebserverURL),
 	}
+	if reqType == tfaplv1beta1.PRApply {
+		commentBody.Body = applyRequestAcknowledgedMsg(p.ClusterEnvName, module.NamespacedName(), module.Spec.Path, commitID, req.RequestedAt, p.WebserverURL)
+	}
 
-	commentID, err := p.github.postComment(pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, 0, pr.Number, commentBody)
+	commentID, err := p.provider(pr.BaseRepository.URL).postComment(pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, 0, pr.Number, commentBody)
 	if err != nil {
 		return req, fmt.Errorf("unable to post pending request comment: %w", err)
 	}
@@ -187,8 +287,83 @@ func (p *Planner) addNewRequest(module *tfaplv1beta1.Module, pr *pr, commitID st
 	req.PR = &tfaplv1beta1.PullRequest{
 		Number:     pr.Number,
 		HeadBranch: pr.HeadRefName,
+		HeadCommit: headCommit(pr, reqType),
+		CommitID:   commitID,
 		CommentID:  commentID,
+		CheckRunID: p.createCheckRun(context.Background(), pr, module),
 	}
 
+	p.setPlanState(context.Background(), &sysutil.PRPlanState{
+		Cluster:          p.ClusterEnvName,
+		Repo:             repoKey(pr.BaseRepository.URL),
+		PR:               pr.Number,
+		Module:           module.NamespacedName(),
+		CommitID:         commitID,
+		Request:          req,
+		HeadCommit:       pr.HeadRefOid,
+		TriggerCommentID: triggerCommentID,
+		AckCommentID:     commentID,
+		State:            sysutil.PRPlanStateRequested,
+	})
+
 	return req, nil
 }
+
+// addNewSummaryRequest creates new request and adds it to the PR's summary
+// comment instead of posting acknowledgement comment. summary comment is
+// updated again with the output, so failure to post it doesn't stop the run
+func (p *Planner) addNewSummaryRequest(module *tfaplv1beta1.Module, pr *pr, commitID string, reqType string, triggerCommentID int) *tfaplv1beta1.Request {
+	ctx := context.Background()
+	req := module.NewRunRequest(reqType, "")
+
+	req.PR = &tfaplv1beta1.PullRequest{
+		Number:     pr.Number,
+		HeadBranch: pr.HeadRefName,
+		HeadCommit: headCommit(pr, reqType),
+		CommitID:   commitID,
+		CommentID:  p.summaryCommentID(pr),
+		CheckRunID: p.createCheckRun(ctx, pr, module),
+	}
+
+	state := &sysutil.PRPlanState{
+		Cluster:          p.ClusterEnvName,
+		Repo:             repoKey(pr.BaseRepository.URL),
+		PR:               pr.Number,
+		Module:           module.NamespacedName(),
+		CommitID:         commitID,
+		Request:          req,
+		HeadCommit:       pr.HeadRefOid,
+		TriggerCommentID: triggerCommentID,
+		AckCommentID:     req.PR.CommentID,
+		State:            sysutil.PRPlanStateRequested,
+	}
+	p.setPlanState(ctx, state)
+
+	commentID, err := p.updateSummaryComment(ctx, pr.BaseRepository.URL, pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, pr.Number, req.PR.CommentID)
+	if err != nil {
+		p.Log.Error("unable to update summary comment", "module", module.NamespacedName(), "pr", pr.Number, "error", err)
+		return req
+	}
+
+	if req.PR.CommentID == 0 {
+		req.PR.CommentID = commentID
+		state.AckCommentID = commentID
+		p.setPlanState(ctx, state)
+		// so that next requests of the same PR update the new comment
+		pr.Comments.Nodes = append(pr.Comments.Nodes, prComment{
+			DatabaseID: commentID,
+			Body:       embedMetadata(CommentMetadata{Type: MsgTypeSummary, Cluster: p.ClusterEnvName}),
+		})
+	}
+
+	return req
+}
+
+// headCommit returns the commit apply request is pinned to. plan requests
+// follow the head branch
+func headCommit(pr *pr, reqType string) string {
+	if reqType == tfaplv1beta1.PRApply {
+		return pr.HeadRefOid
+	}
+	return ""
+}
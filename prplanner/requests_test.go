@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,6 +34,13 @@ func generateMockPR(num int, ref string, comments []string) *pr {
 	return p
 }
 
+// expectNoPlanState sets up mock redis with no stored PR plan state
+func expectNoPlanState(testRedis *sysutil.MockRedisInterface) {
+	testRedis.EXPECT().PRPlanState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, sysutil.ErrKeyNotFound).AnyTimes()
+	testRedis.EXPECT().SetPRPlanState(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+}
+
 func TestCheckPRCommits(t *testing.T) {
 	ctx := context.Background()
 	goMockCtrl := gomock.NewController(t)
@@ -49,9 +57,10 @@ func TestCheckPRCommits(t *testing.T) {
 
 	t.Run("generate req for updated module", func(t *testing.T) {
 		testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		planner.RedisClient = testRedis
+		expectNoPlanState(testRedis)
 
 		p := generateMockPR(123, "ref1",
 			[]string{"random comment", "random comment", "random comment"},
@@ -106,6 +115,7 @@ func TestCheckPRCommits(t *testing.T) {
 			PR: &tfaplv1beta1.PullRequest{
 				Number:     123,
 				HeadBranch: "ref1",
+				CommitID:   "hash1",
 				CommentID:  111,
 			},
 		}
@@ -117,9 +127,10 @@ func TestCheckPRCommits(t *testing.T) {
 
 	t.Run("multiple commit updating a module", func(t *testing.T) {
 		testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		planner.RedisClient = testRedis
+		expectNoPlanState(testRedis)
 
 		p := generateMockPR(123, "ref1",
 			[]string{"random comment", "random comment", "random comment"},
@@ -173,6 +184,7 @@ func TestCheckPRCommits(t *testing.T) {
 			PR: &tfaplv1beta1.PullRequest{
 				Number:     123,
 				HeadBranch: "ref1",
+				CommitID:   "hash3",
 				CommentID:  111,
 			},
 		}
@@ -184,9 +196,10 @@ func TestCheckPRCommits(t *testing.T) {
 
 	t.Run("module path is not updated", func(t *testing.T) {
 		testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		planner.RedisClient = testRedis
+		expectNoPlanState(testRedis)
 
 		p := generateMockPR(123, "ref1",
 			[]string{"random comment", runOutputMsg("default", types.NamespacedName{Name: "two", Namespace: "foo"}, "foo/two", &tfaplv1beta1.Run{CommitHash: "hash2", Summary: "Plan: x to add, x to change, x to destroy.", Output: "some output"}, "link"), "random comment"},
@@ -220,9 +233,10 @@ func TestCheckPRCommits(t *testing.T) {
 
 	t.Run("module output is already uploaded", func(t *testing.T) {
 		testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		planner.RedisClient = testRedis
+		expectNoPlanState(testRedis)
 
 		p := generateMockPR(123, "ref1",
 			[]string{"random comment", runOutputMsg("default", types.NamespacedName{Name: "two", Namespace: "foo"}, "foo/two", &tfaplv1beta1.Run{CommitHash: "hash3", Summary: "Plan: x to add, x to change, x to destroy.", Output: "some output"}, "link"), "random comment"},
@@ -256,9 +270,10 @@ func TestCheckPRCommits(t *testing.T) {
 
 	t.Run("module output uploaded by diff cluster", func(t *testing.T) {
 		testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		planner.RedisClient = testRedis
+		expectNoPlanState(testRedis)
 
 		p := generateMockPR(123, "ref1",
 			[]string{"random comment", runOutputMsg("diff-cluster", types.NamespacedName{Name: "two", Namespace: "foo"}, "foo/two", &tfaplv1beta1.Run{CommitHash: "hash3", Summary: "Plan: x to add, x to change, x to destroy.", Output: "some output"}, "link"), "random comment"},
@@ -311,6 +326,7 @@ func TestCheckPRCommits(t *testing.T) {
 			PR: &tfaplv1beta1.PullRequest{
 				Number:     123,
 				HeadBranch: "ref1",
+				CommitID:   "hash3",
 				CommentID:  111,
 			},
 		}
@@ -322,12 +338,15 @@ func TestCheckPRCommits(t *testing.T) {
 
 	t.Run("module run request is pending", func(t *testing.T) {
 		testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		planner.RedisClient = testRedis
 
+		testRedis.EXPECT().PRPlanState(gomock.Any(), "default", gomock.Any(), 123, types.NamespacedName{Namespace: "foo", Name: "two"}, "hash3").
+			Return(&sysutil.PRPlanState{State: sysutil.PRPlanStateRequested, UpdatedAt: time.Now()}, nil)
+
 		p := generateMockPR(123, "ref1",
-			[]string{"random comment", requestAcknowledgedMsg("default", types.NamespacedName{Name: "two", Namespace: "foo"}, "foo/two", "hash3", &metav1.Time{Time: time.Now()}, "link"), "random comment"},
+			[]string{"random comment", "random comment"},
 		)
 		commitsInfo := []repository.CommitInfo{
 			{Hash: "hash3", ChangedFiles: []string{"foo/two", "foo/three"}},
@@ -358,9 +377,10 @@ func TestCheckPRCommits(t *testing.T) {
 
 	t.Run("module run request is pending by diff cluster", func(t *testing.T) {
 		testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		planner.RedisClient = testRedis
+		expectNoPlanState(testRedis)
 
 		p := generateMockPR(123, "ref1",
 			[]string{"random comment", requestAcknowledgedMsg("diff-cluster", types.NamespacedName{Name: "two", Namespace: "foo"}, "foo/two", "hash3", &metav1.Time{Time: time.Now()}, "link"), "random comment"},
@@ -413,6 +433,7 @@ func TestCheckPRCommits(t *testing.T) {
 			PR: &tfaplv1beta1.PullRequest{
 				Number:     123,
 				HeadBranch: "ref1",
+				CommitID:   "hash3",
 				CommentID:  111,
 			},
 		}
@@ -424,9 +445,10 @@ func TestCheckPRCommits(t *testing.T) {
 
 	t.Run("old commit run output uploaded and new commit added", func(t *testing.T) {
 		testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		planner.RedisClient = testRedis
+		expectNoPlanState(testRedis)
 
 		p := generateMockPR(123, "ref1",
 			[]string{"random comment", runOutputMsg("default", types.NamespacedName{Name: "two", Namespace: "foo"}, "foo/two", &tfaplv1beta1.Run{CommitHash: "hash2", Summary: "Plan: x to add, x to change, x to destroy.", Output: "some output"}, "link"), "random comment"},
@@ -479,6 +501,7 @@ func TestCheckPRCommits(t *testing.T) {
 			PR: &tfaplv1beta1.PullRequest{
 				Number:     123,
 				HeadBranch: "ref1",
+				CommitID:   "hash3",
 				CommentID:  111,
 			},
 		}
@@ -490,9 +513,10 @@ func TestCheckPRCommits(t *testing.T) {
 
 	t.Run("module run finished but output is not yet uploaded", func(t *testing.T) {
 		testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 		planner.RedisClient = testRedis
+		expectNoPlanState(testRedis)
 
 		p := generateMockPR(123, "ref1",
 			[]string{"random comment", "random comment", "random comment"},
@@ -535,9 +559,13 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 
 	testGit := git.NewMockRepositories(goMockCtrl)
 
+	testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
+	expectNoPlanState(testRedis)
+
 	planner := &Planner{
 		ClusterEnvName: "default",
 		Repos:          testGit,
+		RedisClient:    testRedis,
 		Log:            slog.Default(),
 	}
 
@@ -569,49 +597,35 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 		}
 	})
 
-	t.Run("request acknowledged for module (using name)", func(t *testing.T) {
-		// avoid generating another request from `@terraform-applier plan` comment
-		// is there's already a request ID posted for the module
-		// module might not be annotated by the time the loop checks it, which in this
-		// case would mean plan out is ready ot be posted and NOT run hasn't been requested yet
-		pr := generateMockPR(123, "ref1",
-			[]string{
-				"@terraform-applier plan two",
-				requestAcknowledgedMsg("default", types.NamespacedName{Name: "two", Namespace: "foo"}, "path/foo/two", "hash2", mustParseMetaTime("2023-04-02T15:04:05Z"), "link"),
-				requestAcknowledgedMsg("default", types.NamespacedName{Name: "three", Namespace: "foo"}, "path/foo/three", "hash3", mustParseMetaTime("2023-04-02T15:04:05Z"), "link"),
-			},
-		)
-
-		gotReq, err := planner.checkPRCommentsForPlanRequests(pr, module)
-		if err != nil {
-			t.Fatalf("unexpected error: %s", err)
-		}
-
-		if gotReq != nil {
-			t.Errorf("checkPRCommentsForPlanRequests() returner non-nil Request")
-		}
-	})
-
-	t.Run("request acknowledged for module (using path)", func(t *testing.T) {
+	t.Run("request already made for module", func(t *testing.T) {
 		// avoid generating another request from `@terraform-applier plan` comment
-		// is there's already a request ID posted for the module
-		// module might not be annotated by the time the loop checks it, which in this
-		// case would mean plan out is ready ot be posted and NOT run hasn't been requested yet
-		pr := generateMockPR(123, "ref1",
-			[]string{
-				"@terraform-applier plan path/foo/two",
-				requestAcknowledgedMsg("default", types.NamespacedName{Name: "two", Namespace: "foo"}, "path/foo/two", "hash2", mustParseMetaTime("2023-04-02T15:04:05Z"), "link"),
-				requestAcknowledgedMsg("default", types.NamespacedName{Name: "three", Namespace: "foo"}, "path/foo/three", "hash3", mustParseMetaTime("2023-04-02T15:04:05Z"), "link"),
-			},
-		)
-
-		gotReq, err := planner.checkPRCommentsForPlanRequests(pr, module)
-		if err != nil {
-			t.Fatalf("unexpected error: %s", err)
-		}
+		// if there's already a newer request for the module at the same commit
+		stateRedis := sysutil.NewMockRedisInterface(goMockCtrl)
+		planner.RedisClient = stateRedis
+		defer func() { planner.RedisClient = testRedis }()
+
+		stateRedis.EXPECT().PRPlanState(gomock.Any(), "default", gomock.Any(), 123, module.NamespacedName(), "hash2").
+			Return(&sysutil.PRPlanState{
+				Request:   &tfaplv1beta1.Request{RequestedAt: &metav1.Time{Time: time.Now()}},
+				State:     sysutil.PRPlanStateCompleted,
+				UpdatedAt: time.Now(),
+			}, nil).Times(2)
+
+		for _, body := range []string{"@terraform-applier plan two", "@terraform-applier plan path/foo/two"} {
+			testGit.EXPECT().Hash(gomock.Any(), "https://github.com/owner-a/repo-a.git", "ref1", "path/foo/two").
+				Return("hash2", nil)
+
+			pr := generateMockPR(123, "ref1", []string{body})
+			pr.Comments.Nodes[0].UpdatedAt = time.Now().Add(-time.Minute)
+
+			gotReq, err := planner.checkPRCommentsForPlanRequests(pr, module)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
 
-		if gotReq != nil {
-			t.Errorf("checkPRCommentsForPlanRequests() returner non-nil Request")
+			if gotReq != nil {
+				t.Errorf("checkPRCommentsForPlanRequests() returner non-nil Request for %q", body)
+			}
 		}
 	})
 
@@ -673,7 +687,7 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 	})
 
 	t.Run("plan run is requested for module using correct module path", func(t *testing.T) {
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 
 		p := generateMockPR(123, "ref1",
@@ -716,6 +730,7 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 			PR: &tfaplv1beta1.PullRequest{
 				Number:     123,
 				HeadBranch: "ref1",
+				CommitID:   "hash1",
 				CommentID:  111,
 			},
 		}
@@ -726,7 +741,7 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 	})
 
 	t.Run("plan run is requested for module using correct Name", func(t *testing.T) {
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 
 		p := generateMockPR(123, "ref1",
@@ -769,6 +784,7 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 			PR: &tfaplv1beta1.PullRequest{
 				Number:     123,
 				HeadBranch: "ref1",
+				CommitID:   "hash1",
 				CommentID:  111,
 			},
 		}
@@ -779,7 +795,7 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 	})
 
 	t.Run("request acknowledged for module by diff cluster", func(t *testing.T) {
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 
 		// avoid generating another request from `@terraform-applier plan` comment
@@ -825,6 +841,7 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 			PR: &tfaplv1beta1.PullRequest{
 				Number:     123,
 				HeadBranch: "ref1",
+				CommitID:   "hash1",
 				CommentID:  111,
 			},
 		}
@@ -835,7 +852,7 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 	})
 
 	t.Run("plan out posted for module by diff cluster", func(t *testing.T) {
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 
 		pr := generateMockPR(123, "ref1",
@@ -877,6 +894,7 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 			PR: &tfaplv1beta1.PullRequest{
 				Number:     123,
 				HeadBranch: "ref1",
+				CommitID:   "hash1",
 				CommentID:  111,
 			},
 		}
@@ -909,7 +927,7 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 	})
 
 	t.Run("plan run is requested for module using correct Name with a random suffix", func(t *testing.T) {
-		testGithub := NewMockGithubInterface(goMockCtrl)
+		testGithub := NewMockProviderInterface(goMockCtrl)
 		planner.github = testGithub
 
 		p := generateMockPR(123, "ref1",
@@ -932,6 +950,210 @@ func Test_checkPRCommentsForPlanRequests(t *testing.T) {
 	})
 }
 
+func Test_checkPRCommentsForApplyRequests(t *testing.T) {
+	goMockCtrl := gomock.NewController(t)
+
+	testGit := git.NewMockRepositories(goMockCtrl)
+
+	testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
+	expectNoPlanState(testRedis)
+
+	planner := &Planner{
+		ClusterEnvName: "default",
+		Repos:          testGit,
+		RedisClient:    testRedis,
+		Log:            slog.Default(),
+	}
+
+	module := &tfaplv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "two"},
+		Spec: tfaplv1beta1.ModuleSpec{
+			RepoURL:      "https://github.com/owner-a/repo-a.git",
+			Path:         "path/foo/two",
+			AllowPRApply: true,
+			RBAC: []tfaplv1beta1.RBAC{{
+				Role:     "Admin",
+				Subjects: []tfaplv1beta1.Subject{{Kind: "GitHubUser", Name: "admin"}},
+			}},
+		},
+	}
+
+	newApplyPR := func(login string) *pr {
+		p := generateMockPR(123, "ref1", []string{"@terraform-applier apply two"})
+		p.Comments.Nodes[0].Author.Login = login
+		p.HeadRefOid = "sha1"
+		p.ReviewDecision = "APPROVED"
+		p.Mergeable = "MERGEABLE"
+		p.MergeStateStatus = "CLEAN"
+		return p
+	}
+
+	// apply is pinned to the PR head commit
+	testGit.EXPECT().Hash(gomock.Any(), gomock.Any(), "sha1", "path/foo/two").
+		Return("hash1", nil).AnyTimes()
+
+	t.Run("apply rejected for non admin", func(t *testing.T) {
+		testGithub := NewMockProviderInterface(goMockCtrl)
+		planner.github = testGithub
+
+		testGithub.EXPECT().postComment(gomock.Any(), gomock.Any(), 0, 123, gomock.Any()).
+			DoAndReturn(func(repoOwner, repoName string, commentID, prNumber int, commentBody prComment) (int, error) {
+				cluster, parsedModule, _ := parseApplyRejectedMsg(commentBody.Body)
+				if cluster != "default" || parsedModule != module.NamespacedName() {
+					return 0, fmt.Errorf("comment body does not contain valid apply rejected metadata")
+				}
+				return 111, nil
+			})
+
+		gotReq, err := planner.checkPRCommentsForPlanRequests(newApplyPR("someone"), module)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if gotReq != nil {
+			t.Errorf("checkPRCommentsForPlanRequests() returner non-nil Request")
+		}
+	})
+
+	t.Run("skip already rejected apply request", func(t *testing.T) {
+		p := newApplyPR("someone")
+		p.Comments.Nodes = append(p.Comments.Nodes, prComment{2, author{}, applyRejectedMsg("default", module.NamespacedName(), module.Spec.Path, "PR is not approved"), time.Now()})
+
+		gotReq, err := planner.checkPRCommentsForPlanRequests(p, module)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if gotReq != nil {
+			t.Errorf("checkPRCommentsForPlanRequests() returner non-nil Request")
+		}
+	})
+
+	t.Run("apply rejected if not planned", func(t *testing.T) {
+		testGithub := NewMockProviderInterface(goMockCtrl)
+		planner.github = testGithub
+
+		testGithub.EXPECT().postComment(gomock.Any(), gomock.Any(), 0, 123, gomock.Any()).
+			DoAndReturn(func(repoOwner, repoName string, commentID, prNumber int, commentBody prComment) (int, error) {
+				if !strings.Contains(commentBody.Body, "please plan again") {
+					return 0, fmt.Errorf("unexpected rejection reason: %s", commentBody.Body)
+				}
+				return 111, nil
+			})
+
+		gotReq, err := planner.checkPRCommentsForPlanRequests(newApplyPR("admin"), module)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if gotReq != nil {
+			t.Errorf("checkPRCommentsForPlanRequests() returner non-nil Request")
+		}
+	})
+
+	t.Run("apply requested by admin", func(t *testing.T) {
+		testGithub := NewMockProviderInterface(goMockCtrl)
+		planner.github = testGithub
+
+		plannedRedis := sysutil.NewMockRedisInterface(goMockCtrl)
+		planner.RedisClient = plannedRedis
+		defer func() { planner.RedisClient = testRedis }()
+
+		plannedRedis.EXPECT().PRPlanState(gomock.Any(), "default", gomock.Any(), 123, module.NamespacedName(), "hash1").
+			Return(&sysutil.PRPlanState{
+				Request:    &tfaplv1beta1.Request{Type: tfaplv1beta1.PRPlan},
+				HeadCommit: "sha1",
+				State:      sysutil.PRPlanStateCompleted,
+				RunStatus:  string(tfaplv1beta1.StatusOk),
+			}, nil).AnyTimes()
+		plannedRedis.EXPECT().SetPRPlanState(gomock.Any(), gomock.Any()).Return(nil)
+
+		testGithub.EXPECT().postComment(gomock.Any(), gomock.Any(), 0, 123, gomock.Any()).
+			DoAndReturn(func(repoOwner, repoName string, commentID, prNumber int, commentBody prComment) (int, error) {
+				_, parsedModule, _, parsedCommitID, _ := parseRequestAcknowledgedMsg(commentBody.Body)
+				if parsedModule != module.NamespacedName() || parsedCommitID != "hash1" || !isApplyRequestAcknowledgedMsg(commentBody.Body) {
+					return 0, fmt.Errorf("comment body does not contain valid request acknowledgement metadata")
+				}
+				return 111, nil
+			})
+
+		gotReq, err := planner.checkPRCommentsForPlanRequests(newApplyPR("admin"), module)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		wantReq := &tfaplv1beta1.Request{
+			Type: "PullRequestApply",
+			PR: &tfaplv1beta1.PullRequest{
+				Number:     123,
+				HeadBranch: "ref1",
+				HeadCommit: "sha1",
+				CommitID:   "hash1",
+				CommentID:  111,
+			},
+		}
+
+		if diff := cmp.Diff(wantReq, gotReq, cmpIgnoreRandFields); diff != "" {
+			t.Errorf("checkPRCommentsForPlanRequests() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func Test_applyRejectionReason(t *testing.T) {
+	goMockCtrl := gomock.NewController(t)
+
+	module := &tfaplv1beta1.Module{
+		Spec: tfaplv1beta1.ModuleSpec{
+			AllowPRApply: true,
+			RBAC: []tfaplv1beta1.RBAC{{
+				Role:     "Admin",
+				Subjects: []tfaplv1beta1.Subject{{Kind: "GitHubUser", Name: "admin"}},
+			}},
+		},
+	}
+
+	planner := &Planner{
+		github:    NewMockProviderInterface(goMockCtrl),
+		providers: map[string]ProviderInterface{"gitlab.com": NewMockProviderInterface(goMockCtrl)},
+	}
+
+	planned := func(headCommit string, runStatus string) *sysutil.PRPlanState {
+		return &sysutil.PRPlanState{
+			Request:    &tfaplv1beta1.Request{Type: tfaplv1beta1.PRPlan},
+			HeadCommit: headCommit,
+			State:      sysutil.PRPlanStateCompleted,
+			RunStatus:  runStatus,
+		}
+	}
+
+	tests := []struct {
+		name             string
+		repoURL          string
+		login            string
+		reviewDecision   string
+		mergeable        string
+		mergeStateStatus string
+		plan             *sysutil.PRPlanState
+		want             string
+	}{
+		{"allowed", "", "admin", "APPROVED", "MERGEABLE", "CLEAN", planned("sha1", string(tfaplv1beta1.StatusOk)), ""},
+		{"not admin", "", "someone", "APPROVED", "MERGEABLE", "CLEAN", planned("sha1", string(tfaplv1beta1.StatusOk)), "`someone` is not an Admin of the module"},
+		{"GitHubUser on gitlab", "https://gitlab.com/owner/repo.git", "admin", "APPROVED", "MERGEABLE", "CLEAN", planned("sha1", string(tfaplv1beta1.StatusOk)), "`admin` is not an Admin of the module"},
+		{"not approved", "", "admin", "REVIEW_REQUIRED", "MERGEABLE", "CLEAN", planned("sha1", string(tfaplv1beta1.StatusOk)), "PR is not approved"},
+		{"conflicting", "", "admin", "APPROVED", "CONFLICTING", "DIRTY", planned("sha1", string(tfaplv1beta1.StatusOk)), "PR is not mergeable"},
+		{"behind base", "", "admin", "APPROVED", "MERGEABLE", "BEHIND", planned("sha1", string(tfaplv1beta1.StatusOk)), "PR branch is not up to date with the base branch"},
+		{"not planned", "", "admin", "APPROVED", "MERGEABLE", "CLEAN", nil, "module is not successfully planned at the PR head commit `sha1`, please plan again"},
+		{"plan failed", "", "admin", "APPROVED", "MERGEABLE", "CLEAN", planned("sha1", string(tfaplv1beta1.StatusErrored)), "module is not successfully planned at the PR head commit `sha1`, please plan again"},
+		{"head moved", "", "admin", "APPROVED", "MERGEABLE", "CLEAN", planned("sha0", string(tfaplv1beta1.StatusOk)), "PR head has moved to `sha1` since it was planned, please plan again"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &pr{HeadRefOid: "sha1", ReviewDecision: tt.reviewDecision, Mergeable: tt.mergeable, MergeStateStatus: tt.mergeStateStatus}
+			p.BaseRepository.URL = tt.repoURL
+			if got := planner.applyRejectionReason(p, module, tt.login, tt.plan); got != tt.want {
+				t.Errorf("applyRejectionReason() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_isPlanOutputPostedForCommit(t *testing.T) {
 	type args struct {
 		cluster    string
@@ -949,9 +1171,7 @@ func Test_isPlanOutputPostedForCommit(t *testing.T) {
 		{
 			name: "Matching NamespacedName and Commit ID",
 			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
+				pr: &pr{Comments: prComments{Nodes: []prComment{
 					{
 						DatabaseID: 01234567,
 						Body:       runOutputMsg("default", types.NamespacedName{Name: "one", Namespace: "foo"}, "foo/one", &tfaplv1beta1.Run{CommitHash: "hash2", Summary: "Plan: x to add, x to change, x to destroy."}, "link"),
@@ -967,9 +1187,7 @@ func Test_isPlanOutputPostedForCommit(t *testing.T) {
 		{
 			name: "Matching NamespacedName and Commit ID - diff cluster",
 			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
+				pr: &pr{Comments: prComments{Nodes: []prComment{
 					{
 						DatabaseID: 01234567,
 						Body:       runOutputMsg("diff-cluster", types.NamespacedName{Name: "one", Namespace: "foo"}, "foo/one", &tfaplv1beta1.Run{CommitHash: "hash2", Summary: "Plan: x to add, x to change, x to destroy."}, "link"),
@@ -985,9 +1203,7 @@ func Test_isPlanOutputPostedForCommit(t *testing.T) {
 		{
 			name: "Matching Name and Commit ID",
 			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
+				pr: &pr{Comments: prComments{Nodes: []prComment{
 					{
 						DatabaseID: 01234567,
 						Body:       runOutputMsg("default", types.NamespacedName{Name: "one", Namespace: ""}, "foo/one", &tfaplv1beta1.Run{CommitHash: "hash2", Summary: "Plan: x to add, x to change, x to destroy."}, "link"),
@@ -1003,9 +1219,7 @@ func Test_isPlanOutputPostedForCommit(t *testing.T) {
 		{
 			name: "Wrong Commit ID",
 			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
+				pr: &pr{Comments: prComments{Nodes: []prComment{
 					{
 						DatabaseID: 01234567,
 						Body:       "Terraform plan output for module `foo/one` Commit ID: `hash2`",
@@ -1020,9 +1234,7 @@ func Test_isPlanOutputPostedForCommit(t *testing.T) {
 		{
 			name: "Wrong Namespace",
 			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
+				pr: &pr{Comments: prComments{Nodes: []prComment{
 					{
 						DatabaseID: 01234567,
 						Body:       "Terraform plan output for module `bar/one` Commit ID: `hash2`",
@@ -1037,9 +1249,7 @@ func Test_isPlanOutputPostedForCommit(t *testing.T) {
 		{
 			name: "Received terraform plan request",
 			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
+				pr: &pr{Comments: prComments{Nodes: []prComment{
 					{
 						DatabaseID: 01234567,
 						Body:       "Received terraform plan request. Module: `foo/one` Request ID: `a1b2c3d4` Commit ID: `hash2`",
@@ -1054,9 +1264,7 @@ func Test_isPlanOutputPostedForCommit(t *testing.T) {
 		{
 			name: "Empty string",
 			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
+				pr: &pr{Comments: prComments{Nodes: []prComment{
 					{
 						DatabaseID: 01234567,
 						Body:       "",
@@ -1077,146 +1285,3 @@ func Test_isPlanOutputPostedForCommit(t *testing.T) {
 		})
 	}
 }
-
-func Test_isPlanRequestAckPostedForCommit(t *testing.T) {
-	type args struct {
-		cluster    string
-		pr         *pr
-		commitID   string
-		modulePath string
-		module     types.NamespacedName
-	}
-	tests := []struct {
-		name string
-		args args
-		want bool
-	}{
-		{
-			name: "Empty string",
-			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
-					{
-						DatabaseID: 01234567,
-						Body:       "",
-					},
-				}}},
-				cluster:    "default",
-				commitID:   "hash2",
-				modulePath: "",
-				module:     types.NamespacedName{Namespace: "foo", Name: "one"},
-			},
-			want: false,
-		}, {
-			name: "Matching NamespacedName and Commit ID and req is current",
-			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
-					{
-						DatabaseID: 01234567,
-						Body:       requestAcknowledgedMsg("default", types.NamespacedName{Name: "one", Namespace: "foo"}, "foo/one", "hash2", &metav1.Time{Time: time.Now()}, "link"),
-					},
-				}}},
-				cluster:    "default",
-				commitID:   "hash2",
-				modulePath: "foo/one",
-				module:     types.NamespacedName{Namespace: "foo", Name: "one"},
-			},
-			want: true,
-		}, {
-			name: "Matching NamespacedName and Commit ID and req is current from diff cluster",
-			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
-					{
-						DatabaseID: 01234567,
-						Body:       requestAcknowledgedMsg("diff-cluster", types.NamespacedName{Name: "one", Namespace: "foo"}, "foo/one", "hash2", &metav1.Time{Time: time.Now()}, "link"),
-					},
-				}}},
-				cluster:    "default",
-				commitID:   "hash2",
-				modulePath: "foo/one",
-				module:     types.NamespacedName{Namespace: "foo", Name: "one"},
-			},
-			want: false,
-		}, {
-			name: "Matching NamespacedName and Commit ID and req is old",
-			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
-					{
-						DatabaseID: 01234567,
-						Body:       requestAcknowledgedMsg("default", types.NamespacedName{Name: "one", Namespace: "foo"}, "foo/one", "hash2", &metav1.Time{Time: time.Now().Add(-30 * time.Minute)}, "link"),
-					},
-				}}},
-				cluster:    "default",
-				commitID:   "hash2",
-				modulePath: "foo/one",
-				module:     types.NamespacedName{Namespace: "foo", Name: "one"},
-			},
-			want: false,
-		}, {
-			name: "Matching NamespacedName and Commit ID and req is future",
-			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
-					{
-						DatabaseID: 01234567,
-						Body:       requestAcknowledgedMsg("default", types.NamespacedName{Name: "one", Namespace: "foo"}, "foo/one", "hash2", &metav1.Time{Time: time.Now().Add(5 * time.Minute)}, "link"),
-					},
-				}}},
-				cluster:    "default",
-				commitID:   "hash2",
-				modulePath: "foo/one",
-				module:     types.NamespacedName{Namespace: "foo", Name: "one"},
-			},
-			want: false,
-		}, {
-			name: "wrong commit id",
-			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
-					{
-						DatabaseID: 01234567,
-						Body:       requestAcknowledgedMsg("default", types.NamespacedName{Name: "one", Namespace: "foo"}, "foo/one", "hash2", &metav1.Time{Time: time.Now()}, "link"),
-					},
-				}}},
-				cluster:    "default",
-				commitID:   "hash3",
-				modulePath: "foo/one",
-				module:     types.NamespacedName{Namespace: "foo", Name: "one"},
-			},
-			want: false,
-		}, {
-			name: "wrong NS",
-			args: args{
-				pr: &pr{Comments: struct {
-					Nodes []prComment `json:"nodes"`
-				}{Nodes: []prComment{
-					{
-						DatabaseID: 01234567,
-						Body:       requestAcknowledgedMsg("default", types.NamespacedName{Name: "one", Namespace: "foo"}, "foo/two", "hash3", &metav1.Time{Time: time.Now()}, "link"),
-					},
-				}}},
-				cluster:    "default",
-				commitID:   "hash3",
-				modulePath: "foo/one",
-				module:     types.NamespacedName{Namespace: "foo", Name: "one"},
-			},
-			want: false,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := isPlanRequestAckPostedForCommit(tt.args.cluster, tt.args.pr, tt.args.commitID, tt.args.modulePath, tt.args.module); got != tt.want {
-				t.Errorf("isPlanRequestAckPostedForCommit() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
@@ -0,0 +1,83 @@
+package prplanner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/utilitywarehouse/git-mirror/giturl"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// repoKey returns normalised repo url so that same state is used regardless
+// of the url format used by git provider or module
+func repoKey(repoURL string) string {
+	u, err := giturl.Parse(repoURL)
+	if err != nil {
+		return repoURL
+	}
+	return u.Host + "/" + u.Path + "/" + strings.TrimSuffix(u.Repo, ".git")
+}
+
+// planState returns stored state of the module's PR request for the commit, nil is
+// returned if state is not found
+func (p *Planner) planState(ctx context.Context, repoURL string, prNumber int, module types.NamespacedName, commitID string) *sysutil.PRPlanState {
+	state, err := p.RedisClient.PRPlanState(ctx, p.ClusterEnvName, repoKey(repoURL), prNumber, module, commitID)
+	if err != nil {
+		if !errors.Is(err, sysutil.ErrKeyNotFound) {
+			p.Log.Error("unable to get PR plan state", "module", module, "pr", prNumber, "commit", commitID, "err", err)
+		}
+		return nil
+	}
+	return state
+}
+
+func (p *Planner) setPlanState(ctx context.Context, state *sysutil.PRPlanState) {
+	if err := p.RedisClient.SetPRPlanState(ctx, state); err != nil {
+		p.Log.Error("unable to set PR plan state", "module", state.Module, "pr", state.PR, "commit", state.CommitID, "err", err)
+	}
+}
+
+// completePlanState marks request as completed once run output is posted
+func (p *Planner) completePlanState(ctx context.Context, repoURL string, prNumber int, module types.NamespacedName, commitID string, outputCommentID int, runStatus string) {
+	state := p.planState(ctx, repoURL, prNumber, module, commitID)
+	if state == nil {
+		// request was made before state was stored
+		state = &sysutil.PRPlanState{
+			Cluster:  p.ClusterEnvName,
+			Repo:     repoKey(repoURL),
+			PR:       prNumber,
+			Module:   module,
+			CommitID: commitID,
+		}
+	}
+	state.State = sysutil.PRPlanStateCompleted
+	state.OutputCommentID = outputCommentID
+	state.RunStatus = runStatus
+	p.setPlanState(ctx, state)
+}
+
+// requestCommitID returns the commit PR request state is stored for, commit
+// hash of the run can be different if PR branch is updated before the run
+func requestCommitID(run *tfaplv1beta1.Run) string {
+	if run.Request != nil && run.Request.PR != nil && run.Request.PR.CommitID != "" {
+		return run.Request.PR.CommitID
+	}
+	return run.CommitHash
+}
+
+// isRequestProcessed returns true if request is either completed
+// or still pending and not older than given stale duration
+func isRequestProcessed(state *sysutil.PRPlanState, staleRequestAfter time.Duration) bool {
+	if state == nil {
+		return false
+	}
+	if state.State == sysutil.PRPlanStateCompleted {
+		return true
+	}
+	// pending requests are retried if they are not completed in time
+	return time.Since(state.UpdatedAt) < staleRequestAfter
+}
@@ -0,0 +1,112 @@
+package prplanner
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/utilitywarehouse/git-mirror/repository"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/git"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_repoKey(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://github.com/owner-a/repo-a", "github.com/owner-a/repo-a"},
+		{"https://github.com/owner-a/repo-a.git", "github.com/owner-a/repo-a"},
+		{"git@github.com:owner-a/repo-a.git", "github.com/owner-a/repo-a"},
+		{"ssh://git@github.com/owner-a/repo-a.git", "github.com/owner-a/repo-a"},
+		{"invalid", "invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := repoKey(tt.url); got != tt.want {
+				t.Errorf("repoKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isRequestProcessed(t *testing.T) {
+	tests := []struct {
+		name  string
+		state *sysutil.PRPlanState
+		want  bool
+	}{
+		{"no state", nil, false},
+		{"completed", &sysutil.PRPlanState{State: sysutil.PRPlanStateCompleted, UpdatedAt: time.Now().Add(-time.Hour)}, true},
+		{"pending", &sysutil.PRPlanState{State: sysutil.PRPlanStateRequested, UpdatedAt: time.Now().Add(-time.Minute)}, true},
+		{"stale pending", &sysutil.PRPlanState{State: sysutil.PRPlanStateRequested, UpdatedAt: time.Now().Add(-time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRequestProcessed(tt.state, 45*time.Minute); got != tt.want {
+				t.Errorf("isRequestProcessed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_planState_dedup(t *testing.T) {
+	ctx := context.Background()
+	goMockCtrl := gomock.NewController(t)
+	testGit := git.NewMockRepositories(goMockCtrl)
+	testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
+
+	planner := &Planner{
+		ClusterEnvName: "default",
+		Repos:          testGit,
+		RedisClient:    testRedis,
+		Log:            slog.Default(),
+	}
+
+	module := &tfaplv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "one"},
+		Spec: tfaplv1beta1.ModuleSpec{
+			RepoURL: "https://github.com/owner-a/repo-a.git",
+			Path:    "foo/one",
+		},
+	}
+
+	p := generateMockPR(123, "ref1", []string{"@terraform-applier plan one"})
+	p.Comments.Nodes[0].DatabaseID = 11
+	p.BaseRepository.URL = "https://github.com/owner-a/repo-a"
+
+	t.Run("commit already requested", func(t *testing.T) {
+		testRedis.EXPECT().PRPlanState(gomock.Any(), "default", "github.com/owner-a/repo-a", 123, types.NamespacedName{Namespace: "foo", Name: "one"}, "hash1").
+			Return(&sysutil.PRPlanState{State: sysutil.PRPlanStateCompleted}, nil)
+
+		commitsInfo := []repository.CommitInfo{{Hash: "hash1", ChangedFiles: []string{"foo/one/main.tf"}}}
+
+		// no comments or API calls are expected
+		req, err := planner.checkPRCommits(ctx, p, commitsInfo, module)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if req != nil {
+			t.Errorf("checkPRCommits() returned non-nil Request")
+		}
+	})
+
+	t.Run("comment already processed", func(t *testing.T) {
+		testGit.EXPECT().Hash(gomock.Any(), gomock.Any(), "ref1", "foo/one").Return("hash2", nil)
+		testRedis.EXPECT().PRPlanState(gomock.Any(), "default", "github.com/owner-a/repo-a", 123, types.NamespacedName{Namespace: "foo", Name: "one"}, "hash2").
+			Return(&sysutil.PRPlanState{State: sysutil.PRPlanStateCompleted, TriggerCommentID: 11}, nil)
+
+		req, err := planner.checkPRCommentsForPlanRequests(p, module)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if req != nil {
+			t.Errorf("checkPRCommentsForPlanRequests() returned non-nil Request")
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package prplanner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// summaryCommentID returns ID of the summary comment of the cluster
+// if its already posted on the PR
+func (p *Planner) summaryCommentID(pr *pr) int {
+	for i := len(pr.Comments.Nodes) - 1; i >= 0; i-- {
+		if parseSummaryMsg(pr.Comments.Nodes[i].Body) == p.ClusterEnvName {
+			return pr.Comments.Nodes[i].DatabaseID
+		}
+	}
+	return 0
+}
+
+// summaryEntries returns latest request of each module of the PR
+// sorted by module name
+func (p *Planner) summaryEntries(ctx context.Context, repoURL string, prNumber int) ([]summaryEntry, error) {
+	states, err := p.RedisClient.PRPlanStates(ctx, p.ClusterEnvName, repoKey(repoURL), prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[types.NamespacedName]*sysutil.PRPlanState)
+	for _, s := range states {
+		if l, ok := latest[s.Module]; ok && l.UpdatedAt.After(s.UpdatedAt) {
+			continue
+		}
+		latest[s.Module] = s
+	}
+
+	var entries []summaryEntry
+	for module, s := range latest {
+		e := summaryEntry{
+			Module:    module,
+			CommitID:  s.CommitID,
+			RunStatus: s.RunStatus,
+		}
+		if s.Request != nil {
+			e.ReqType = s.Request.Type
+		}
+		// output might not be available if run is still pending or its expired
+		if run, err := p.RedisClient.PRRun(ctx, module, prNumber, s.CommitID); err == nil {
+			e.Run = run
+		}
+		entries = append(entries, e)
+	}
+
+	slices.SortFunc(entries, func(a, b summaryEntry) int {
+		return strings.Compare(a.Module.String(), b.Module.String())
+	})
+
+	return entries, nil
+}
+
+// updateSummaryComment posts or updates (if commentID is set) PR summary
+// comment with latest state of all the module requests
+func (p *Planner) updateSummaryComment(ctx context.Context, repoURL, repoOwner, repoName string, prNumber, commentID int) (int, error) {
+	// summary is rendered from the stored state hence concurrent updates
+	// must be serialised to avoid overwriting with older state
+	p.summaryMu.Lock()
+	defer p.summaryMu.Unlock()
+
+	entries, err := p.summaryEntries(ctx, repoURL, prNumber)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get PR plan states: %w", err)
+	}
+
+	payload := prComment{Body: summaryMsg(p.ClusterEnvName, entries, p.WebserverURL)}
+
+	id, err := p.provider(repoURL).postComment(repoOwner, repoName, commentID, prNumber, payload)
+	if err != nil {
+		return 0, fmt.Errorf("unable to post summary comment: %w", err)
+	}
+	return id, nil
+}
+
+// uploadSummaryOutput completes pending requests of the PR whose run output
+// is available and updates summary comment. this is used in summary comment
+// mode instead of uploading output of each request
+func (p *Planner) uploadSummaryOutput(ctx context.Context, pr *pr) {
+	states, err := p.RedisClient.PRPlanStates(ctx, p.ClusterEnvName, repoKey(pr.BaseRepository.URL), pr.Number)
+	if err != nil {
+		p.Log.Error("unable to get PR plan states", "pr", pr.Number, "error", err)
+		return
+	}
+
+	updated := false
+	for _, s := range states {
+		if s.State == sysutil.PRPlanStateCompleted {
+			continue
+		}
+
+		run, err := p.RedisClient.PRRun(ctx, s.Module, pr.Number, s.CommitID)
+		if err != nil || run.Output == "" {
+			continue
+		}
+
+		// module path is only required for check run annotations
+		if run.Request != nil && run.Request.PR != nil && run.Request.PR.CheckRunID != 0 {
+			var module tfaplv1beta1.Module
+			if err := p.ClusterClt.Get(ctx, s.Module, &module); err == nil {
+				p.completeCheckRun(ctx, pr.BaseRepository.URL, pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, module.Spec.Path, run)
+			}
+		}
+		p.completePlanState(ctx, pr.BaseRepository.URL, pr.Number, s.Module, s.CommitID, s.AckCommentID, string(run.Status))
+		updated = true
+	}
+
+	if !updated {
+		return
+	}
+
+	if _, err := p.updateSummaryComment(ctx, pr.BaseRepository.URL, pr.BaseRepository.Owner.Login, pr.BaseRepository.Name, pr.Number, p.summaryCommentID(pr)); err != nil {
+		p.Log.Error("unable to update summary comment", "pr", pr.Number, "error", err)
+		return
+	}
+	p.Log.Info("summary comment updated", "pr", pr.Number)
+}
@@ -0,0 +1,184 @@
+package prplanner
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_summaryMsg(t *testing.T) {
+	admins := types.NamespacedName{Namespace: "foo", Name: "admins"}
+	users := types.NamespacedName{Namespace: "foo", Name: "users"}
+	groups := types.NamespacedName{Namespace: "foo", Name: "groups"}
+
+	entries := []summaryEntry{
+		{
+			Module: admins, CommitID: "0123456789abcdef", ReqType: tfaplv1beta1.PRPlan,
+			Run: &tfaplv1beta1.Run{Status: "Ok", Summary: "Plan: 1 to add | 0 to change", Output: "admins plan output"},
+		},
+		{
+			Module: users, CommitID: "fedcba9876543210", ReqType: tfaplv1beta1.PRApply,
+			Run: &tfaplv1beta1.Run{Status: tfaplv1beta1.StatusErrored, InitOutput: "init failed", Output: "users output"},
+		},
+		{Module: groups, CommitID: "abc", ReqType: tfaplv1beta1.PRPlan},
+	}
+
+	got := summaryMsg("default", entries, "https://tf-applier.io")
+
+	for _, want := range []string{
+		"### Terraform runs on default",
+		"| [`foo/admins`](https://tf-applier.io/#foo_admins) | Plan | `0123456` | ✅ Ok | Plan: 1 to add \\| 0 to change |",
+		"| [`foo/users`](https://tf-applier.io/#foo_users) | Apply | `fedcba9` | ⛔ Errored |  |",
+		"| [`foo/groups`](https://tf-applier.io/#foo_groups) | Plan | `abc` | ⏳ Pending |  |",
+		"<details><summary><b>✅ foo/admins</b></summary>\n\n```terraform\nadmins plan output\n```\n</details>",
+		"```terraform\ninit failed\nusers output\n```",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summaryMsg() missing %q in:\n%s", want, got)
+		}
+	}
+
+	if cluster := parseSummaryMsg(got); cluster != "default" {
+		t.Errorf("parseSummaryMsg() = %v, want default", cluster)
+	}
+	if strings.Contains(got, "groups</b>") {
+		t.Errorf("summaryMsg() pending run should not have output section")
+	}
+
+	t.Run("long output is linked", func(t *testing.T) {
+		long := []summaryEntry{
+			{Module: admins, CommitID: "c1", Run: &tfaplv1beta1.Run{Status: "Ok", Output: strings.Repeat("a", 40000)}},
+			{Module: users, CommitID: "c1", Run: &tfaplv1beta1.Run{Status: "Ok", Output: strings.Repeat("b", 40000)}},
+		}
+		got := summaryMsg("default", long, "https://tf-applier.io")
+
+		if l := len([]rune(got)); l > summaryCharacterLimit {
+			t.Errorf("summaryMsg() length %d is over the limit", l)
+		}
+		if !strings.Contains(got, strings.Repeat("a", 40000)) {
+			t.Errorf("summaryMsg() first output should be included")
+		}
+		if !strings.Contains(got, "please [view it in default terraform-applier web UI](https://tf-applier.io/#foo_users)") {
+			t.Errorf("summaryMsg() second output should be linked")
+		}
+	})
+}
+
+func Test_summaryMode(t *testing.T) {
+	ctx := context.Background()
+	goMockCtrl := gomock.NewController(t)
+	testRedis := sysutil.NewMockRedisInterface(goMockCtrl)
+	testGithub := NewMockProviderInterface(goMockCtrl)
+
+	enabled := true
+	p := &Planner{
+		ClusterEnvName: "default",
+		Log:            slog.Default(),
+		RedisClient:    testRedis,
+		github:         testGithub,
+		WebserverURL:   "https://tf-applier.io",
+		Config:         Config{Defaults: PlannerConfig{SummaryComment: &enabled}},
+	}
+
+	repo := "github.com/owner-a/repo-a"
+	admins := types.NamespacedName{Namespace: "foo", Name: "admins"}
+	users := types.NamespacedName{Namespace: "foo", Name: "users"}
+
+	module := &tfaplv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Name: "admins", Namespace: "foo"},
+		Spec:       tfaplv1beta1.ModuleSpec{RepoURL: "git@github.com:owner-a/repo-a.git", Path: "foo/admins"},
+	}
+
+	withRepo := func(pr *pr) *pr {
+		pr.BaseRepository.URL = "git@github.com:owner-a/repo-a.git"
+		pr.BaseRepository.Name = "repo-a"
+		pr.BaseRepository.Owner.Login = "owner-a"
+		return pr
+	}
+
+	t.Run("first request posts summary comment", func(t *testing.T) {
+		pr := withRepo(generateMockPR(123, "branch1", nil))
+
+		// 1st state without comment ID and 2nd with new comment ID
+		var states []sysutil.PRPlanState
+		testRedis.EXPECT().SetPRPlanState(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, s *sysutil.PRPlanState) error {
+				states = append(states, *s)
+				return nil
+			}).Times(2)
+
+		testRedis.EXPECT().PRPlanStates(gomock.Any(), "default", repo, 123).
+			Return([]*sysutil.PRPlanState{{Module: admins, CommitID: "c1", State: sysutil.PRPlanStateRequested}}, nil)
+		testRedis.EXPECT().PRRun(gomock.Any(), admins, 123, "c1").Return(nil, sysutil.ErrKeyNotFound)
+
+		testGithub.EXPECT().postComment("owner-a", "repo-a", 0, 123, gomock.Any()).
+			DoAndReturn(func(_, _ string, _, _ int, c prComment) (int, error) {
+				if !strings.Contains(c.Body, "| [`foo/admins`](https://tf-applier.io/#foo_admins) | Plan | `c1` | ⏳ Pending |  |") {
+					t.Errorf("unexpected summary comment %s", c.Body)
+				}
+				return 555, nil
+			})
+
+		req, err := p.addNewRequest(module, pr, "c1", tfaplv1beta1.PRPlan, 0)
+		if err != nil {
+			t.Fatalf("addNewRequest() error = %v", err)
+		}
+		if req.PR.CommentID != 555 {
+			t.Errorf("request comment ID = %d, want 555", req.PR.CommentID)
+		}
+		if len(states) != 2 || states[0].AckCommentID != 0 || states[1].AckCommentID != 555 {
+			t.Errorf("unexpected states %+v", states)
+		}
+		// next request should update same comment
+		if id := p.summaryCommentID(pr); id != 555 {
+			t.Errorf("summaryCommentID() = %d, want 555", id)
+		}
+	})
+
+	t.Run("output updates summary comment", func(t *testing.T) {
+		pr := withRepo(generateMockPR(123, "branch1", []string{summaryMsg("default", nil, "")}))
+		pr.Comments.Nodes[0].DatabaseID = 555
+
+		pending := &sysutil.PRPlanState{Module: users, CommitID: "c2", AckCommentID: 555, State: sysutil.PRPlanStateRequested, UpdatedAt: time.Now()}
+		completed := &sysutil.PRPlanState{Module: admins, CommitID: "c1", AckCommentID: 555, State: sysutil.PRPlanStateCompleted, RunStatus: "Ok", UpdatedAt: time.Now()}
+		testRedis.EXPECT().PRPlanStates(gomock.Any(), "default", repo, 123).
+			Return([]*sysutil.PRPlanState{pending, completed}, nil).Times(2)
+
+		usersRun := &tfaplv1beta1.Run{Module: users, Status: "Ok", Summary: "No changes", Output: "users plan output", CommitHash: "c2"}
+		testRedis.EXPECT().PRRun(gomock.Any(), users, 123, "c2").Return(usersRun, nil).Times(2)
+		// admins output is expired
+		testRedis.EXPECT().PRRun(gomock.Any(), admins, 123, "c1").Return(nil, sysutil.ErrKeyNotFound)
+
+		testRedis.EXPECT().PRPlanState(gomock.Any(), "default", repo, 123, users, "c2").Return(pending, nil)
+		testRedis.EXPECT().SetPRPlanState(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, s *sysutil.PRPlanState) error {
+				if s.Module != users || s.State != sysutil.PRPlanStateCompleted || s.OutputCommentID != 555 {
+					t.Errorf("unexpected state %+v", s)
+				}
+				return nil
+			})
+
+		testGithub.EXPECT().postComment("owner-a", "repo-a", 555, 123, gomock.Any()).
+			DoAndReturn(func(_, _ string, _, _ int, c prComment) (int, error) {
+				adminsIdx := strings.Index(c.Body, "| [`foo/admins`](https://tf-applier.io/#foo_admins) | Plan | `c1` | Ok |  |")
+				usersIdx := strings.Index(c.Body, "| [`foo/users`](https://tf-applier.io/#foo_users) | Plan | `c2` | ✅ Ok | No changes |")
+				if adminsIdx == -1 || usersIdx == -1 || adminsIdx > usersIdx {
+					t.Errorf("unexpected summary comment %s", c.Body)
+				}
+				if !strings.Contains(c.Body, "users plan output") {
+					t.Errorf("summary comment missing output %s", c.Body)
+				}
+				return 555, nil
+			})
+
+		p.uploadRequestOutput(ctx, pr)
+	})
+}
@@ -1,18 +1,44 @@
 package prplanner
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
+// open PRs are ordered by last updated so that pagination can stop
+// once PRs are older than max PR age
 const queryRepoPRs = `
-query ($owner: String!,$repoName: String! ) {
+query ($owner: String!,$repoName: String!, $cursor: String ) {
+  ` + rateLimitQuery + `
   repository(owner: $owner, name: $repoName) {
-    pullRequests(states: OPEN, last: 20) {
+    pullRequests(states: OPEN, first: 50, after: $cursor, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
       nodes { ` + prFieldQuery + `}}}}`
 
 const queryRepoPR = `
-query ($owner: String!,$repoName: String!, $prNumber: Int! ) {
+query ($owner: String!,$repoName: String!, $prNumber: Int!, $cursor: String ) {
+  ` + rateLimitQuery + `
   repository(owner: $owner, name: $repoName ) {
     pullRequest(number: $prNumber) {
-     ` + prFieldQuery + `}}}`
+     ` + prFieldQuery + prCommentsQuery + `}}}`
+
+// comments are paginated backwards so that latest comments are fetched first
+const queryPRComments = `
+query ($owner: String!,$repoName: String!, $prNumber: Int!, $cursor: String ) {
+  ` + rateLimitQuery + `
+  repository(owner: $owner, name: $repoName ) {
+    pullRequest(number: $prNumber) {
+     ` + prCommentsQuery + `}}}`
+
+const rateLimitQuery = `
+rateLimit {
+  cost
+  remaining
+  resetAt
+}`
 
 const prFieldQuery = `
 baseRefName
@@ -26,7 +52,11 @@ baseRepository {
 number
 updatedAt
 headRefName
+headRefOid
 isDraft
+reviewDecision
+mergeable
+mergeStateStatus
 closed
 merged
 mergeCommit {
@@ -35,11 +65,23 @@ mergeCommit {
 author {
   login
 }
-comments(last:50) {
+labels(first: 20) {
+  nodes {
+    name
+  }
+}
+`
+
+const prCommentsQuery = `
+comments(last: 100, before: $cursor) {
+  pageInfo {
+    hasPreviousPage
+    startCursor
+  }
   nodes {
     databaseId
     body
-	updatedAt
+    updatedAt
     author {
       login
     }
@@ -53,9 +95,23 @@ type gitPRRequest struct {
 		Owner    string `json:"owner"`
 		RepoName string `json:"repoName"`
 		PRNumber int    `json:"prNumber"`
+		Cursor   string `json:"cursor,omitempty"`
 	} `json:"variables"`
 }
 
+type rateLimit struct {
+	Cost      int       `json:"cost"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+type pageInfo struct {
+	HasNextPage     bool   `json:"hasNextPage"`
+	EndCursor       string `json:"endCursor"`
+	HasPreviousPage bool   `json:"hasPreviousPage"`
+	StartCursor     string `json:"startCursor"`
+}
+
 type Error struct {
 	Message   string   `json:"message"`
 	Path      []string `json:"path"`
@@ -67,9 +123,11 @@ type Error struct {
 
 type gitPRsResponse struct {
 	Data struct {
+		RateLimit  rateLimit `json:"rateLimit"`
 		Repository struct {
 			PullRequests struct {
-				Nodes []*pr `json:"nodes"`
+				PageInfo pageInfo `json:"pageInfo"`
+				Nodes    []*pr    `json:"nodes"`
 			} `json:"pullRequests"`
 		} `json:"repository"`
 	} `json:"data"`
@@ -78,6 +136,7 @@ type gitPRsResponse struct {
 
 type gitPRResponse struct {
 	Data struct {
+		RateLimit  rateLimit `json:"rateLimit"`
 		Repository struct {
 			PullRequest *pr `json:"pullRequest"`
 		} `json:"repository"`
@@ -86,19 +145,47 @@ type gitPRResponse struct {
 }
 
 type pr struct {
-	Number         int       `json:"number"`
-	BaseRefName    string    `json:"baseRefName"`
-	BaseRepository prRepo    `json:"baseRepository"`
-	HeadRefName    string    `json:"headRefName"`
-	IsDraft        bool      `json:"isDraft"`
-	Closed         bool      `json:"closed"`
-	Merged         bool      `json:"merged"`
-	MergeCommit    Commit    `json:"mergeCommit"`
-	UpdatedAt      time.Time `json:"updatedAt"`
-	Author         author    `json:"author"`
-	Comments       struct {
-		Nodes []prComment `json:"nodes"`
-	} `json:"comments"`
+	Number         int    `json:"number"`
+	BaseRefName    string `json:"baseRefName"`
+	BaseRepository prRepo `json:"baseRepository"`
+	HeadRefName    string `json:"headRefName"`
+	HeadRefOid     string `json:"headRefOid"`
+	IsDraft        bool   `json:"isDraft"`
+	// ReviewDecision, Mergeable and MergeStateStatus are used to validate
+	// apply requests posted as comment
+	ReviewDecision   string     `json:"reviewDecision"`
+	Mergeable        string     `json:"mergeable"`
+	MergeStateStatus string     `json:"mergeStateStatus"`
+	Closed           bool       `json:"closed"`
+	Merged           bool       `json:"merged"`
+	MergeCommit      Commit     `json:"mergeCommit"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+	Author           author     `json:"author"`
+	Comments         prComments `json:"comments"`
+	Labels           prLabels   `json:"labels"`
+}
+
+type prLabels struct {
+	Nodes []prLabel `json:"nodes"`
+}
+
+type prLabel struct {
+	Name string `json:"name"`
+}
+
+// hasLabel returns true if PR has any of the given labels
+func (p *pr) hasLabel(names []string) bool {
+	for _, l := range p.Labels.Nodes {
+		if slices.Contains(names, l.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+type prComments struct {
+	PageInfo pageInfo    `json:"pageInfo"`
+	Nodes    []prComment `json:"nodes"`
 }
 
 type author struct {
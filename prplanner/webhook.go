@@ -10,7 +10,7 @@ import (
 func (p *Planner) ProcessPRWebHookEvent(event GitHubWebhook, prNumber int) {
 	ctx := context.Background()
 
-	pr, err := p.github.PR(ctx, event.Repository.Owner.Login, event.Repository.Name, prNumber)
+	pr, err := p.provider(event.Repository.URL).PR(ctx, event.Repository.Owner.Login, event.Repository.Name, prNumber)
 	if err != nil {
 		p.Log.Error("unable to get PR info", "repo", event.Repository.Name, "pr", prNumber, "err", err)
 		return
@@ -56,8 +56,10 @@ func (p *Planner) ProcessPRCloseEvent(e GitHubWebhook) {
 			continue
 		}
 
+		deps := p.moduleDeps(ctx, &module, commitsInfo)
+
 		for _, commit := range commitsInfo {
-			if !isModuleUpdated(&module, commit) {
+			if !isModuleUpdated(&module, deps, commit) {
 				continue
 			}
 
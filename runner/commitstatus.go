@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"context"
+	"time"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+)
+
+const (
+	CommitStatusPending = "pending"
+	CommitStatusSuccess = "success"
+	CommitStatusFailure = "failure"
+)
+
+// CommitStatusPublisher publishes run status on the run's commit to the git provider
+type CommitStatusPublisher interface {
+	PublishCommitStatus(ctx context.Context, repoURL string, run *tfaplv1beta1.Run, state, description string) error
+}
+
+// publishCommitStatus publishes commit status for the default branch runs
+// ie. scheduled and polling runs
+func (r *Runner) publishCommitStatus(run *tfaplv1beta1.Run, repoURL, state, description string) {
+	if r.CommitStatus == nil || run.CommitHash == "" {
+		return
+	}
+	if run.Request.Type != tfaplv1beta1.ScheduledRun &&
+		run.Request.Type != tfaplv1beta1.PollingRun {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := r.CommitStatus.PublishCommitStatus(ctx, repoURL, run, state, description); err != nil {
+		r.Log.Error("unable to publish commit status", "module", run.Module, "commit", run.CommitHash, "state", state, "err", err)
+	}
+}
@@ -0,0 +1,48 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+)
+
+type fakeCommitStatus struct {
+	states []string
+}
+
+func (f *fakeCommitStatus) PublishCommitStatus(ctx context.Context, repoURL string, run *tfaplv1beta1.Run, state, description string) error {
+	f.states = append(f.states, state)
+	return nil
+}
+
+func Test_publishCommitStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		reqType    string
+		commitHash string
+		want       []string
+	}{
+		{"scheduled run", tfaplv1beta1.ScheduledRun, "abc", []string{CommitStatusSuccess}},
+		{"polling run", tfaplv1beta1.PollingRun, "abc", []string{CommitStatusSuccess}},
+		{"forced apply", tfaplv1beta1.ForcedApply, "abc", nil},
+		{"pr plan", tfaplv1beta1.PRPlan, "abc", nil},
+		{"commit not known", tfaplv1beta1.PollingRun, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakeCommitStatus{}
+			r := &Runner{Log: slog.Default(), CommitStatus: publisher}
+			run := &tfaplv1beta1.Run{
+				Request:    &tfaplv1beta1.Request{Type: tt.reqType},
+				CommitHash: tt.commitHash,
+			}
+			r.publishCommitStatus(run, "git@github.com:owner/repo.git", CommitStatusSuccess, "applied")
+			if diff := cmp.Diff(tt.want, publisher.states); diff != "" {
+				t.Errorf("publishCommitStatus() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
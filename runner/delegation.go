@@ -8,6 +8,8 @@ import (
 
 	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
 	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"github.com/utilitywarehouse/terraform-applier/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/cert"
@@ -66,7 +68,18 @@ func inClusterDelegatedConfig(token string) (*rest.Config, error) {
 	}, nil
 }
 
-func fetchEnvVars(ctx context.Context, client kubernetes.Interface, module *tfaplv1beta1.Module, envVars []tfaplv1beta1.EnvVar) (map[string]string, error) {
+// delegateToken traces and returns token of the given service account
+func (r *Runner) delegateToken(ctx context.Context, kubeClt kubernetes.Interface, namespace, serviceAccount string) (token string, err error) {
+	ctx, span := tracing.Start(ctx, "DelegateToken", attribute.String("serviceAccount", serviceAccount))
+	defer func() { tracing.End(span, err) }()
+
+	return r.Delegate.DelegateToken(ctx, kubeClt, namespace, serviceAccount)
+}
+
+func fetchEnvVars(ctx context.Context, client kubernetes.Interface, module *tfaplv1beta1.Module, envVars []tfaplv1beta1.EnvVar) (_ map[string]string, err error) {
+	ctx, span := tracing.Start(ctx, "fetchEnvVars")
+	defer func() { tracing.End(span, err) }()
+
 	kvPairs := make(map[string]string)
 	for _, env := range envVars {
 
@@ -101,8 +114,9 @@ func fetchEnvVars(ctx context.Context, client kubernetes.Interface, module *tfap
 }
 
 func (r *Runner) generateVaultAWSCreds(ctx context.Context, module *tfaplv1beta1.Module, jwt string, envs map[string]string) error {
-
+	ctx, span := tracing.Start(ctx, "Vault.GenerateAWSCreds")
 	creds, err := r.Vault.GenerateAWSCreds(ctx, jwt, module.Spec.VaultRequests.AWS)
+	tracing.End(span, err)
 	if err != nil {
 		return err
 	}
@@ -113,8 +127,9 @@ func (r *Runner) generateVaultAWSCreds(ctx context.Context, module *tfaplv1beta1
 }
 
 func (r *Runner) generateVaultGCPToken(ctx context.Context, module *tfaplv1beta1.Module, jwt string, envs map[string]string) error {
-
+	ctx, span := tracing.Start(ctx, "Vault.GenerateGCPToken")
 	token, err := r.Vault.GenerateGCPToken(ctx, jwt, module.Spec.VaultRequests.GCP)
+	tracing.End(span, err)
 	if err != nil {
 		return err
 	}
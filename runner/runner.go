@@ -11,10 +11,15 @@ import (
 	"time"
 
 	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/cloudevents"
 	"github.com/utilitywarehouse/terraform-applier/git"
 	"github.com/utilitywarehouse/terraform-applier/metrics"
+	"github.com/utilitywarehouse/terraform-applier/notifier"
 	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"github.com/utilitywarehouse/terraform-applier/tracing"
 	"github.com/utilitywarehouse/terraform-applier/vault"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -41,6 +46,7 @@ type Runner struct {
 	Recorder               record.EventRecorder
 	KubeClt                kubernetes.Interface
 	Repos                  git.Repositories
+	ModuleDeps             *git.ModuleDeps
 	GHCredsProvider        sysutil.CredsProvider
 	Redis                  sysutil.RedisInterface
 	Log                    *slog.Logger
@@ -54,11 +60,28 @@ type Runner struct {
 	pluginCacheEnabled     bool
 	pluginCache            *pluginCache
 	DataRootPath           string
+	// StaleLockTimeout is the min age of the state lock created by this controller
+	// after which it is considered stale and removed automatically. 0 disables it
+	StaleLockTimeout time.Duration
+	// CommitStatus is used to publish status of default branch runs, its optional
+	CommitStatus CommitStatusPublisher
+	// Notifier sends run events to the module's notification sinks, its optional
+	Notifier *notifier.Notifier
+	// CloudEvents emits run lifecycle events, its optional
+	CloudEvents *cloudevents.Emitter
+	hostname    string
 }
 
 func (r *Runner) Init(enablePluginCache bool, maxRunners int) error {
 	var err error
 
+	// terraform records lock holder as 'user@hostname', hostname is used
+	// to identify locks created by this controller
+	r.hostname, err = os.Hostname()
+	if err != nil {
+		r.Log.Error("unable to get hostname, stale state lock recovery is disabled", "err", err)
+	}
+
 	if enablePluginCache {
 		r.pluginCacheEnabled = true
 		r.pluginCache, err = newPluginCache(
@@ -81,7 +104,7 @@ func (r *Runner) Start(run *tfaplv1beta1.Run, cancelChan chan struct{}) bool {
 		// there are no annotations for schedule and polling runs
 		if run.Request.Type == tfaplv1beta1.ScheduledRun ||
 			run.Request.Type == tfaplv1beta1.PollingRun ||
-			run.Request.Type == tfaplv1beta1.PRPlan {
+			run.Request.IsPRRun() {
 			return
 		}
 		if err := sysutil.RemoveRequest(context.Background(), r.ClusterClt, run.Module, run.Request); err != nil {
@@ -100,10 +123,20 @@ func (r *Runner) Start(run *tfaplv1beta1.Run, cancelChan chan struct{}) bool {
 		return false
 	}
 
+	ctx, span := tracing.Start(context.Background(), "run",
+		attribute.String("module", run.Module.String()),
+		attribute.String("run.type", run.Request.Type),
+		attribute.String("run.mode", string(run.Mode)),
+	)
+	defer span.End()
+
+	run.TraceID = tracing.TraceID(ctx)
+	log := r.runLogger(run)
+
 	envs := make(map[string]string)
 	maps.Copy(envs, r.GlobalENV)
 
-	r.Log.Info("starting run", "module", run.Module, "type", run.Request.Type)
+	log.Info("starting run", "type", run.Request.Type)
 
 	start := time.Now()
 
@@ -113,32 +146,50 @@ func (r *Runner) Start(run *tfaplv1beta1.Run, cancelChan chan struct{}) bool {
 		if pluginCacheDir != "" {
 			envs["TF_PLUGIN_CACHE_DIR"] = pluginCacheDir
 			defer r.pluginCache.done(pluginCacheDir)
-			r.Log.Info("plugin cache folder is ready", "module", run.Module)
+			log.Info("plugin cache folder is ready")
 		}
 	}
 
-	success := r.process(run, module, cancelChan, envs)
+	success := r.process(ctx, run, module, cancelChan, envs)
 
 	dur := time.Since(start).Seconds()
 
 	r.Metrics.UpdateModuleSuccess(run.Module.Name, run.Module.Namespace, run.Request.Type, success)
 	r.Metrics.UpdateModuleRunDuration(run.Module.Name, run.Module.Namespace, run.Request.Type, dur, success)
+	if run.Request.Type == tfaplv1beta1.PRPlan {
+		r.Metrics.IncPRPlanCount(module.Spec.RepoURL)
+	}
 
+	span.SetAttributes(attribute.String("git.commit", run.CommitHash))
 	if success {
-		r.Log.Info("run completed successfully", "module", run.Module, "duration", dur)
+		log.Info("run completed successfully", "duration", dur)
 	} else {
-		r.Log.Error("run completed with error", "module", run.Module, "duration", dur)
+		span.SetStatus(codes.Error, "run completed with error")
+		log.Error("run completed with error", "duration", dur)
 	}
 
 	return success
 }
 
+// observePhase records duration of the run phase started at given time
+func (r *Runner) observePhase(run *tfaplv1beta1.Run, phase string, start time.Time) {
+	r.Metrics.ObservePhaseDuration(run.Module.Name, run.Module.Namespace, phase, time.Since(start).Seconds())
+}
+
+// runLogger returns logger with module and trace ID (if set) of the run
+func (r *Runner) runLogger(run *tfaplv1beta1.Run) *slog.Logger {
+	if run.TraceID == "" {
+		return r.Log.With("module", run.Module)
+	}
+	return r.Log.With("module", run.Module, "traceID", run.TraceID)
+}
+
 // process will prepare and run module it returns bool indicating failed run
-func (r *Runner) process(run *tfaplv1beta1.Run, module *tfaplv1beta1.Module, cancelChan <-chan struct{}, envs map[string]string) bool {
-	log := r.Log.With("module", run.Module)
+func (r *Runner) process(ctx context.Context, run *tfaplv1beta1.Run, module *tfaplv1beta1.Module, cancelChan <-chan struct{}, envs map[string]string) bool {
+	log := r.runLogger(run)
 
 	// create new context
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// make sure module is not already running
@@ -147,8 +198,9 @@ func (r *Runner) process(run *tfaplv1beta1.Run, module *tfaplv1beta1.Module, can
 		log.Error("skipping run request as another run is in progress on this module")
 		return false
 	}
-	// set running status
-	r.RunStatus.Store(run.Module.String(), true)
+	// set running status, cancel func is stored so that run can be
+	// cancelled by user
+	r.RunStatus.Store(run.Module.String(), &sysutil.ActiveRun{Request: run.Request, Cancel: cancel})
 	defer r.RunStatus.Delete(run.Module.String())
 
 	run.Status = tfaplv1beta1.StatusRunning
@@ -187,7 +239,7 @@ func (r *Runner) process(run *tfaplv1beta1.Run, module *tfaplv1beta1.Module, can
 
 	run.StartedAt = &metav1.Time{Time: r.Clock.Now()}
 
-	commitHash, err := r.Repos.Hash(ctx, module.Spec.RepoURL, run.RepoRef, module.Spec.Path)
+	commitHash, err := git.ModuleHash(ctx, r.Repos, r.ModuleDeps, module.Spec.RepoURL, run.RepoRef, module.Spec.Path, module.Spec.WatchPaths)
 	if err != nil {
 		msg := fmt.Sprintf("unable to get commit hash: err:%s", err)
 		log.Error(msg)
@@ -217,8 +269,17 @@ func (r *Runner) process(run *tfaplv1beta1.Run, module *tfaplv1beta1.Module, can
 		return false
 	}
 
+	// merge referenced defaults, module's own spec takes precedence
+	if err := sysutil.MergeModuleDefaults(ctx, r.ClusterClt, module); err != nil {
+		msg := fmt.Sprintf("unable to get module defaults: err:%s", err)
+		log.Error(msg)
+		r.setFailedStatus(run, module, tfaplv1beta1.ReasonRunPreparationFailed, msg)
+		return false
+	}
+
 	// Setup Delegation and get vars and envs
-	delegateToken, err := r.Delegate.DelegateToken(ctx, r.KubeClt, module.Namespace, module.Spec.DelegateServiceAccount)
+	credsStart := time.Now()
+	delegateToken, err := r.delegateToken(ctx, r.KubeClt, module.Namespace, module.Spec.DelegateServiceAccount)
 	if err != nil {
 		msg := fmt.Sprintf("unable to get service account token: err:%s", err)
 		log.Error(msg)
@@ -236,7 +297,7 @@ func (r *Runner) process(run *tfaplv1beta1.Run, module *tfaplv1beta1.Module, can
 	// if run as sa set use that creds
 	if module.Spec.RunAsServiceAccount != "" {
 		// use delegatedClient client to fetch run-as sa's token
-		delegateToken, err = r.Delegate.DelegateToken(ctx, delegatedClient, module.Namespace, module.Spec.RunAsServiceAccount)
+		delegateToken, err = r.delegateToken(ctx, delegatedClient, module.Namespace, module.Spec.RunAsServiceAccount)
 		if err != nil {
 			msg := fmt.Sprintf("unable to get service account token: err:%s", err)
 			log.Error(msg)
@@ -301,6 +362,7 @@ func (r *Runner) process(run *tfaplv1beta1.Run, module *tfaplv1beta1.Module, can
 			}
 		}
 	}
+	r.observePhase(run, metrics.PhaseCredentials, credsStart)
 
 	// run should happen on the head of the reference instead of commit to capture
 	// non-module path related changes
@@ -328,10 +390,12 @@ func (r *Runner) runTF(
 	commitHash string,
 	cancelChan <-chan struct{},
 ) bool {
-	log := r.Log.With("module", run.Module, "ref", run.RepoRef)
+	log := r.runLogger(run).With("ref", run.RepoRef)
 	var err error
 
+	initStart := time.Now()
 	run.InitOutput, err = te.init(ctx, backendConf)
+	r.observePhase(run, metrics.PhaseInit, initStart)
 	if err != nil {
 		// tf err contains new lines not suitable logging
 		log.Error("unable to init module", "err", fmt.Sprintf("%q", err))
@@ -359,15 +423,28 @@ func (r *Runner) runTF(
 		return false
 	}
 
+	planStart := time.Now()
 	diffDetected, planOut, err := te.plan(ctx)
+	if err != nil && r.recoverStaleLock(ctx, run, module, te, planOut) {
+		log.Info("retrying plan after removing stale state lock")
+		diffDetected, planOut, err = te.plan(ctx)
+	}
+	r.observePhase(run, metrics.PhasePlan, planStart)
 	if err != nil {
 		run.Output = planOut
 		// tf err contains new lines not suitable logging
 		log.Error("unable to plan module", "err", fmt.Sprintf("%q", err))
+		if lock := setStateLock(module, planOut); lock != nil {
+			r.setFailedStatus(run, module, tfaplv1beta1.ReasonStateLocked, "unable to plan module: state is locked: id:"+lock.ID)
+			return false
+		}
 		r.setFailedStatus(run, module, tfaplv1beta1.ReasonPlanFailed, "unable to plan module")
 		return false
 	}
 
+	// state lock was acquired
+	module.Status.StateLock = nil
+
 	run.DiffDetected = diffDetected
 
 	// extract last line of output
@@ -378,6 +455,13 @@ func (r *Runner) runTF(
 
 	log.Info("planned", "status", planStatus)
 	run.Summary = planStatus
+	module.Status.LastPlan = parsePlanSummary(planStatus)
+
+	// PR plans don't reflect drift of the default branch
+	if !run.Request.IsPRRun() {
+		r.Metrics.SetDriftedResources(run.Module.Name, run.Module.Namespace,
+			module.Status.LastPlan.Add+module.Status.LastPlan.Change+module.Status.LastPlan.Destroy)
+	}
 
 	// get saved plan to update status
 	run.Output, err = te.showPlanFileRaw(ctx)
@@ -393,6 +477,11 @@ func (r *Runner) runTF(
 		reason := tfaplv1beta1.ReasonNoDriftDetected
 		if diffDetected {
 			reason = tfaplv1beta1.ReasonPlanOnlyDriftDetected
+			// reconciler will trigger new run once apply window opens
+			if run.Request.IsApplyDeferred(module) {
+				reason = tfaplv1beta1.ReasonApplyDeferred
+				planStatus = "apply deferred as module is outside of its apply windows: " + planStatus
+			}
 		}
 		if err = r.SetRunFinishedStatus(run, module, reason, planStatus, r.Clock.Now()); err != nil {
 			log.Error("unable to set drift status", "err", err)
@@ -409,16 +498,33 @@ func (r *Runner) runTF(
 		return false
 	}
 
+	if run.Request.BreakGlass && !module.IsApplyAllowedAt(run.Request.RequestedAt.Time) {
+		msg := "break-glass apply requested outside of module's apply windows"
+		log.Warn(msg)
+		r.Recorder.Event(module, corev1.EventTypeWarning, tfaplv1beta1.ReasonBreakGlassApply, msg)
+	}
+
+	applyStart := time.Now()
 	applyOut, err := te.apply(ctx)
+	if err != nil && r.recoverStaleLock(ctx, run, module, te, applyOut) {
+		log.Info("retrying apply after removing stale state lock")
+		applyOut, err = te.apply(ctx)
+	}
+	r.observePhase(run, metrics.PhaseApply, applyStart)
 	run.Output += applyOut
 	if err != nil {
 		// tf err contains new lines not suitable logging
 		log.Error("unable to apply module", "err", fmt.Sprintf("%q", err))
+		if lock := setStateLock(module, applyOut); lock != nil {
+			r.setFailedStatus(run, module, tfaplv1beta1.ReasonStateLocked, "unable to apply module: state is locked: id:"+lock.ID)
+			return false
+		}
 		r.setFailedStatus(run, module, tfaplv1beta1.ReasonApplyFailed, "unable to apply module")
 		return false
 	}
 	module.Status.LastAppliedAt = &metav1.Time{Time: r.Clock.Now()}
 	module.Status.LastAppliedCommitHash = commitHash
+	r.Metrics.SetLastApplyTimestamp(run.Module.Name, run.Module.Namespace, module.Status.LastAppliedAt.Time)
 
 	// extract last line of output
 	// Apply complete! Resources: 1 added, 0 changed, 0 destroyed.
@@ -427,6 +533,14 @@ func (r *Runner) runTF(
 	log.Info("applied", "status", applyStatus)
 	run.Summary = applyStatus
 
+	changes := parseApplySummary(applyStatus)
+	r.Metrics.AddResourceChanges(run.Module.Name, run.Module.Namespace, changes.Add, changes.Change, changes.Destroy)
+	// applied changes are not pending anymore
+	if !run.Request.IsPRRun() {
+		module.Status.LastPlan = &tfaplv1beta1.PlanSummary{}
+		r.Metrics.SetDriftedResources(run.Module.Name, run.Module.Namespace, 0)
+	}
+
 	if err = r.SetRunFinishedStatus(run, module, tfaplv1beta1.ReasonApplied, applyStatus, r.Clock.Now()); err != nil {
 		log.Error("unable to set finished status", "err", err)
 		return false
@@ -440,6 +554,7 @@ func (r *Runner) SetRunStartedStatus(run *tfaplv1beta1.Run, m *tfaplv1beta1.Modu
 	run.CommitMsg = commitMsg
 
 	r.Recorder.Eventf(m, corev1.EventTypeNormal, tfaplv1beta1.ReasonRunTriggered, "%s: type:%s, commit:%s", msg, run.Request.Type, commitHash)
+	r.CloudEvents.Emit(cloudevents.TypeRunStarted, run, tfaplv1beta1.ReasonRunTriggered, msg)
 
 	if run.Request.SkipStatusUpdate() {
 		return nil
@@ -451,6 +566,9 @@ func (r *Runner) SetRunStartedStatus(run *tfaplv1beta1.Run, m *tfaplv1beta1.Modu
 	m.Status.ObservedGeneration = m.Generation
 	m.Status.LastDefaultRunCommitHash = commitHash
 	m.Status.StateReason = tfaplv1beta1.ReasonRunTriggered
+	m.SetRunningConditions(fmt.Sprintf("%s: type:%s, commit:%s", msg, run.Request.Type, commitHash))
+
+	r.publishCommitStatus(run, remoteURL, CommitStatusPending, msg)
 
 	return sysutil.PatchModuleStatus(context.Background(), r.ClusterClt, run.Module, m.Status)
 }
@@ -460,6 +578,15 @@ func (r *Runner) SetRunFinishedStatus(run *tfaplv1beta1.Run, m *tfaplv1beta1.Mod
 	run.Duration = time.Since(run.StartedAt.Time)
 
 	r.Recorder.Event(m, corev1.EventTypeNormal, reason, msg)
+	r.CloudEvents.Emit(finishedEventType(run, reason), run, reason, msg)
+	r.publishCommitStatus(run, m.Spec.RepoURL, CommitStatusSuccess, msg)
+
+	switch reason {
+	case tfaplv1beta1.ReasonApplied:
+		r.Notifier.Notify(m, run, tfaplv1beta1.NotificationEventApplied, reason, msg)
+	case tfaplv1beta1.ReasonPlanOnlyDriftDetected, tfaplv1beta1.ReasonApplyDeferred:
+		r.Notifier.Notify(m, run, tfaplv1beta1.NotificationEventDriftDetected, reason, msg)
+	}
 
 	if run.Request.SkipStatusUpdate() {
 		return nil
@@ -467,13 +594,33 @@ func (r *Runner) SetRunFinishedStatus(run *tfaplv1beta1.Run, m *tfaplv1beta1.Mod
 
 	m.Status.StateReason = reason
 	m.Status.CurrentState = string(tfaplv1beta1.StatusOk)
-	if reason == tfaplv1beta1.ReasonPlanOnlyDriftDetected {
+	m.Status.LastRunDuration = &metav1.Duration{Duration: run.Duration.Round(time.Second)}
+	m.Status.LastFailureMessage = ""
+	m.Status.LastErrorExcerpt = ""
+	if reason == tfaplv1beta1.ReasonPlanOnlyDriftDetected ||
+		reason == tfaplv1beta1.ReasonApplyDeferred {
 		m.Status.CurrentState = string(tfaplv1beta1.StatusDriftDetected)
 	}
+	m.SetFinishedConditions(reason, msg)
 
 	return sysutil.PatchModuleStatus(context.Background(), r.ClusterClt, m.NamespacedName(), m.Status)
 }
 
+// finishedEventType returns cloud event type of the successful run. PR plans
+// only show changes of the PR branch so they are never reported as drift
+func finishedEventType(run *tfaplv1beta1.Run, reason string) string {
+	switch reason {
+	case tfaplv1beta1.ReasonApplied:
+		return cloudevents.TypeRunApplied
+	case tfaplv1beta1.ReasonPlanOnlyDriftDetected, tfaplv1beta1.ReasonApplyDeferred:
+		if run.Request.IsPRRun() {
+			return cloudevents.TypeRunPlanned
+		}
+		return cloudevents.TypeDriftDetected
+	}
+	return cloudevents.TypeRunPlanned
+}
+
 func (r *Runner) setFailedStatus(run *tfaplv1beta1.Run, module *tfaplv1beta1.Module, reason, msg string) {
 	run.Status = tfaplv1beta1.StatusErrored
 	run.Duration = time.Since(run.StartedAt.Time)
@@ -482,6 +629,13 @@ func (r *Runner) setFailedStatus(run *tfaplv1beta1.Run, module *tfaplv1beta1.Mod
 	run.Output = msg + "\n" + run.Output
 
 	r.Recorder.Event(module, corev1.EventTypeWarning, reason, msg)
+	r.CloudEvents.Emit(cloudevents.TypeRunFailed, run, reason, msg)
+	r.publishCommitStatus(run, module.Spec.RepoURL, CommitStatusFailure, msg)
+
+	// run will be retried after restart
+	if reason != tfaplv1beta1.ReasonControllerShutdown {
+		r.Notifier.Notify(module, run, tfaplv1beta1.NotificationEventFailed, reason, msg)
+	}
 
 	if run.Request.SkipStatusUpdate() {
 		return
@@ -489,6 +643,13 @@ func (r *Runner) setFailedStatus(run *tfaplv1beta1.Run, module *tfaplv1beta1.Mod
 
 	module.Status.CurrentState = string(tfaplv1beta1.StatusErrored)
 	module.Status.StateReason = reason
+	module.Status.LastRunDuration = &metav1.Duration{Duration: run.Duration.Round(time.Second)}
+	module.Status.LastFailureMessage = msg
+	module.Status.LastErrorExcerpt = errorExcerpt(run.Output)
+	if reason == tfaplv1beta1.ReasonInitialiseFailed {
+		module.Status.LastErrorExcerpt = errorExcerpt(run.InitOutput)
+	}
+	module.SetFailedConditions(reason, msg)
 
 	if err := sysutil.PatchModuleStatus(context.Background(), r.ClusterClt, run.Module, module.Status); err != nil {
 		r.Log.With("module", run).Error("unable to set failed status", "err", err)
@@ -510,7 +671,7 @@ func isChannelClosed(cancelChan <-chan struct{}) bool {
 // updateRedis will add given run to Redis
 func (r *Runner) updateRedis(ctx context.Context, run *tfaplv1beta1.Run) error {
 	// if its PR run only update relevant PR key
-	if run.Request.Type == tfaplv1beta1.PRPlan {
+	if run.Request.IsPRRun() {
 		return r.Redis.SetPRRun(ctx, run)
 	}
 
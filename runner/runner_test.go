@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"testing"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/cloudevents"
+)
+
+func Test_finishedEventType(t *testing.T) {
+	defaultRun := &tfaplv1beta1.Run{Request: &tfaplv1beta1.Request{Type: tfaplv1beta1.PollingRun}}
+	prRun := &tfaplv1beta1.Run{Request: &tfaplv1beta1.Request{Type: tfaplv1beta1.PRPlan, PR: &tfaplv1beta1.PullRequest{Number: 1}}}
+
+	tests := []struct {
+		name   string
+		run    *tfaplv1beta1.Run
+		reason string
+		want   string
+	}{
+		{"applied", defaultRun, tfaplv1beta1.ReasonApplied, cloudevents.TypeRunApplied},
+		{"no drift", defaultRun, tfaplv1beta1.ReasonNoDriftDetected, cloudevents.TypeRunPlanned},
+		{"drift", defaultRun, tfaplv1beta1.ReasonPlanOnlyDriftDetected, cloudevents.TypeDriftDetected},
+		{"deferred apply", defaultRun, tfaplv1beta1.ReasonApplyDeferred, cloudevents.TypeDriftDetected},
+		// PR plan only shows changes of the PR branch
+		{"PR plan with changes", prRun, tfaplv1beta1.ReasonPlanOnlyDriftDetected, cloudevents.TypeRunPlanned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := finishedEventType(tt.run, tt.reason); got != tt.want {
+				t.Errorf("finishedEventType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// terraform prints lock's created time using go's default time format
+const lockCreatedLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+var (
+	reStateLockErr  = regexp.MustCompile(`Error acquiring the state lock`)
+	reLockInfoField = regexp.MustCompile(`(?m)^\s*(ID|Operation|Who|Created):[ \t]*(.*?)\s*$`)
+)
+
+// parseStateLock parses terraform output and returns lock info if the run
+// failed because terraform was unable to acquire the state lock.
+//
+//	Error: Error acquiring the state lock
+//
+//	Error message: ConditionalCheckFailedException: The conditional request failed
+//	Lock Info:
+//	  ID:        8d1c8bc6-3f6c-5e4a-7c1b-1d8a7a5b4c1e
+//	  Path:      dev-terraform-state/hello/terraform.tfstate
+//	  Operation: OperationTypePlan
+//	  Who:       root@terraform-applier-0
+//	  Version:   1.9.0
+//	  Created:   2024-01-02 10:00:00.123456789 +0000 UTC
+//	  Info:
+func parseStateLock(output string) *tfaplv1beta1.StateLock {
+	if !reStateLockErr.MatchString(output) {
+		return nil
+	}
+
+	// only look for lock info after the error msg
+	output = output[reStateLockErr.FindStringIndex(output)[0]:]
+
+	lock := &tfaplv1beta1.StateLock{}
+	for _, match := range reLockInfoField.FindAllStringSubmatch(output, -1) {
+		switch match[1] {
+		case "ID":
+			// keep 1st value only, it belongs to the 1st lock info block
+			if lock.ID == "" {
+				lock.ID = match[2]
+			}
+		case "Operation":
+			if lock.Operation == "" {
+				lock.Operation = match[2]
+			}
+		case "Who":
+			if lock.Who == "" {
+				lock.Who = match[2]
+			}
+		case "Created":
+			if lock.Created != nil {
+				continue
+			}
+			if t, err := time.Parse(lockCreatedLayout, match[2]); err == nil {
+				lock.Created = &metav1.Time{Time: t}
+			}
+		}
+	}
+
+	if lock.ID == "" {
+		return nil
+	}
+
+	return lock
+}
+
+// isStaleLock returns true if given lock was created by this controller and it is
+// older than configured threshold. since only 1 run per module is allowed on a
+// controller such lock must be left behind by previous run which was killed by
+// timeout or controller restart.
+func (r *Runner) isStaleLock(lock *tfaplv1beta1.StateLock, module *tfaplv1beta1.Module) bool {
+	if r.StaleLockTimeout <= 0 || r.hostname == "" {
+		return false
+	}
+
+	if lock.Created == nil || !strings.HasSuffix(lock.Who, "@"+r.hostname) {
+		return false
+	}
+
+	// lock can be held by a run from another module sharing same state,
+	// any run on this controller will finish within its run timeout + grace period
+	minAge := max(r.StaleLockTimeout, time.Duration(module.Spec.RunTimeout)*time.Second+r.TerminationGracePeriod)
+
+	return r.Clock.Now().Sub(lock.Created.Time) > minAge
+}
+
+// recoverStaleLock checks given terraform output for the state lock error and
+// if lock is stale it will force unlock the state and return true so that
+// caller can retry the command.
+func (r *Runner) recoverStaleLock(ctx context.Context, run *tfaplv1beta1.Run, module *tfaplv1beta1.Module, te TFExecuter, output string) bool {
+	log := r.Log.With("module", run.Module)
+
+	lock := parseStateLock(output)
+	if lock == nil {
+		return false
+	}
+
+	log.Warn("unable to acquire the state lock", "lockID", lock.ID, "who", lock.Who, "created", lock.Created)
+
+	if !r.isStaleLock(lock, module) {
+		return false
+	}
+
+	if _, err := te.forceUnlock(ctx, lock.ID); err != nil {
+		log.Error("unable to force unlock stale state lock", "lockID", lock.ID, "err", fmt.Sprintf("%q", err))
+		return false
+	}
+
+	msg := fmt.Sprintf("stale state lock removed: id:%s who:%s created:%s", lock.ID, lock.Who, lock.Created.Format(time.RFC3339))
+	log.Info(msg)
+	r.Recorder.Event(module, corev1.EventTypeNormal, tfaplv1beta1.ReasonStaleLockRemoved, msg)
+
+	return true
+}
+
+// setStateLock records lock details on module status if given output of the
+// failed terraform command contains state lock error, otherwise lock recorded
+// by previous runs is cleared
+func setStateLock(module *tfaplv1beta1.Module, output string) *tfaplv1beta1.StateLock {
+	module.Status.StateLock = parseStateLock(output)
+	return module.Status.StateLock
+}
@@ -0,0 +1,191 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func Test_parseStateLock(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   *tfaplv1beta1.StateLock
+	}{
+		{"empty", "", nil},
+		{"other error", "Error: Invalid provider configuration\n  ID: 1234\n", nil},
+		{
+			"lock error",
+			`data.aws_caller_identity.current: Reading...
+
+Error: Error acquiring the state lock
+
+Error message: ConditionalCheckFailedException: The conditional request failed
+Lock Info:
+  ID:        8d1c8bc6-3f6c-5e4a-7c1b-1d8a7a5b4c1e
+  Path:      dev-terraform-state/hello/terraform.tfstate
+  Operation: OperationTypeApply
+  Who:       root@terraform-applier-0
+  Version:   1.9.0
+  Created:   2024-01-02 10:00:00.123456789 +0000 UTC
+  Info:
+
+
+Terraform acquires a state lock to protect the state from being written
+by multiple users at the same time.`,
+			&tfaplv1beta1.StateLock{
+				ID:        "8d1c8bc6-3f6c-5e4a-7c1b-1d8a7a5b4c1e",
+				Operation: "OperationTypeApply",
+				Who:       "root@terraform-applier-0",
+				Created:   &metav1.Time{Time: time.Date(2024, 1, 2, 10, 0, 0, 123456789, time.UTC)},
+			},
+		},
+		{
+			"lock error without created",
+			"Error: Error acquiring the state lock\nLock Info:\n  ID:        abc\n  Who:       user@laptop\n",
+			&tfaplv1beta1.StateLock{ID: "abc", Who: "user@laptop"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseStateLock(tt.output)
+			if diff := cmp.Diff(tt.want, got, cmp.Comparer(func(a, b metav1.Time) bool { return a.Equal(&b) })); diff != "" {
+				t.Errorf("parseStateLock() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunner_isStaleLock(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	r := &Runner{
+		Clock:                  &sysutil.FakeClock{T: now},
+		StaleLockTimeout:       time.Hour,
+		TerminationGracePeriod: time.Minute,
+		hostname:               "terraform-applier-0",
+	}
+	module := &tfaplv1beta1.Module{Spec: tfaplv1beta1.ModuleSpec{RunTimeout: 900}}
+
+	created := func(age time.Duration) *metav1.Time { return &metav1.Time{Time: now.Add(-age)} }
+
+	tests := []struct {
+		name string
+		r    *Runner
+		lock *tfaplv1beta1.StateLock
+		want bool
+	}{
+		{"stale", r, &tfaplv1beta1.StateLock{ID: "1", Who: "root@terraform-applier-0", Created: created(2 * time.Hour)}, true},
+		{"not old enough", r, &tfaplv1beta1.StateLock{ID: "1", Who: "root@terraform-applier-0", Created: created(30 * time.Minute)}, false},
+		{"other host", r, &tfaplv1beta1.StateLock{ID: "1", Who: "root@terraform-applier-1", Created: created(2 * time.Hour)}, false},
+		{"user lock", r, &tfaplv1beta1.StateLock{ID: "1", Who: "user@laptop", Created: created(2 * time.Hour)}, false},
+		{"created unknown", r, &tfaplv1beta1.StateLock{ID: "1", Who: "root@terraform-applier-0"}, false},
+		{"disabled", &Runner{Clock: r.Clock, hostname: r.hostname}, &tfaplv1beta1.StateLock{ID: "1", Who: "root@terraform-applier-0", Created: created(2 * time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.isStaleLock(tt.lock, module); got != tt.want {
+				t.Errorf("isStaleLock() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// lock can be held by the active run of other module sharing same state
+	longRun := &tfaplv1beta1.Module{Spec: tfaplv1beta1.ModuleSpec{RunTimeout: 3 * 3600}}
+	lock := &tfaplv1beta1.StateLock{ID: "1", Who: "root@terraform-applier-0", Created: created(2 * time.Hour)}
+	if r.isStaleLock(lock, longRun) {
+		t.Errorf("isStaleLock() = true for lock younger than module's run timeout")
+	}
+}
+
+func TestRunner_recoverStaleLock(t *testing.T) {
+	goMockCtrl := gomock.NewController(t)
+
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	recorder := record.NewFakeRecorder(10)
+	r := &Runner{
+		Clock:            &sysutil.FakeClock{T: now},
+		Recorder:         recorder,
+		Log:              slog.Default(),
+		StaleLockTimeout: time.Hour,
+		hostname:         "terraform-applier-0",
+	}
+	run := &tfaplv1beta1.Run{Module: types.NamespacedName{Namespace: "foo", Name: "hello"}}
+
+	lockOutput := func(who string, created time.Time) string {
+		return "Error: Error acquiring the state lock\nLock Info:\n" +
+			"  ID:        lock-1\n" +
+			"  Who:       " + who + "\n" +
+			"  Created:   " + created.Format(lockCreatedLayout) + "\n"
+	}
+
+	t.Run("not a lock error", func(t *testing.T) {
+		te := NewMockTFExecuter(goMockCtrl)
+		module := &tfaplv1beta1.Module{}
+		if r.recoverStaleLock(context.Background(), run, module, te, "Error: Invalid provider configuration") {
+			t.Errorf("recoverStaleLock() = true, want false")
+		}
+	})
+
+	t.Run("lock held by other user", func(t *testing.T) {
+		te := NewMockTFExecuter(goMockCtrl)
+		module := &tfaplv1beta1.Module{}
+		if r.recoverStaleLock(context.Background(), run, module, te, lockOutput("user@laptop", now.Add(-2*time.Hour))) {
+			t.Errorf("recoverStaleLock() = true, want false")
+		}
+	})
+
+	t.Run("force unlock stale lock", func(t *testing.T) {
+		te := NewMockTFExecuter(goMockCtrl)
+		te.EXPECT().forceUnlock(gomock.Any(), "lock-1").Return("Terraform state has been successfully unlocked!", nil)
+
+		module := &tfaplv1beta1.Module{}
+		if !r.recoverStaleLock(context.Background(), run, module, te, lockOutput("root@terraform-applier-0", now.Add(-2*time.Hour))) {
+			t.Fatalf("recoverStaleLock() = false, want true")
+		}
+
+		select {
+		case event := <-recorder.Events:
+			if !strings.Contains(event, tfaplv1beta1.ReasonStaleLockRemoved) || !strings.Contains(event, "lock-1") {
+				t.Errorf("unexpected event %q", event)
+			}
+		default:
+			t.Errorf("stale lock removed event not recorded")
+		}
+	})
+
+	t.Run("force unlock failed", func(t *testing.T) {
+		te := NewMockTFExecuter(goMockCtrl)
+		te.EXPECT().forceUnlock(gomock.Any(), "lock-1").Return("", fmt.Errorf("lock not found"))
+
+		module := &tfaplv1beta1.Module{}
+		if r.recoverStaleLock(context.Background(), run, module, te, lockOutput("root@terraform-applier-0", now.Add(-2*time.Hour))) {
+			t.Errorf("recoverStaleLock() = true, want false")
+		}
+	})
+}
+
+func Test_setStateLock(t *testing.T) {
+	module := &tfaplv1beta1.Module{}
+	module.Status.StateLock = &tfaplv1beta1.StateLock{ID: "old"}
+
+	// lock from previous run must be cleared if current error is not a lock error
+	if lock := setStateLock(module, "Error: Invalid provider configuration"); lock != nil || module.Status.StateLock != nil {
+		t.Errorf("setStateLock() = %v, status lock %v, want nil", lock, module.Status.StateLock)
+	}
+
+	lock := setStateLock(module, "Error: Error acquiring the state lock\nLock Info:\n  ID:        new\n  Who:       user@laptop\n")
+	if lock == nil || lock.ID != "new" || module.Status.StateLock != lock {
+		t.Errorf("setStateLock() = %v, status lock %v, want lock 'new'", lock, module.Status.StateLock)
+	}
+}
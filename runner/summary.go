@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+)
+
+// maxErrorExcerptLen is the max length of error output stored in module status
+const maxErrorExcerptLen = 1024
+
+var (
+	rePlanAdd     = regexp.MustCompile(`(\d+) to add`)
+	rePlanChange  = regexp.MustCompile(`(\d+) to change`)
+	rePlanDestroy = regexp.MustCompile(`(\d+) to destroy`)
+	reErrorLine   = regexp.MustCompile(`(?m)^[│ \t]*Error: `)
+
+	reApplyAdded     = regexp.MustCompile(`(\d+) added`)
+	reApplyChanged   = regexp.MustCompile(`(\d+) changed`)
+	reApplyDestroyed = regexp.MustCompile(`(\d+) destroyed`)
+)
+
+// parsePlanSummary extracts resource counts from the plan status line
+// Plan: X to add, 0 to change, 0 to destroy.
+// OR
+// No changes. Your infrastructure matches the configuration.
+func parsePlanSummary(planStatus string) *tfaplv1beta1.PlanSummary {
+	return &tfaplv1beta1.PlanSummary{
+		Add:     matchCount(rePlanAdd, planStatus),
+		Change:  matchCount(rePlanChange, planStatus),
+		Destroy: matchCount(rePlanDestroy, planStatus),
+	}
+}
+
+// parseApplySummary extracts resource counts from the apply status line
+// Apply complete! Resources: 1 added, 0 changed, 0 destroyed.
+func parseApplySummary(applyStatus string) *tfaplv1beta1.PlanSummary {
+	return &tfaplv1beta1.PlanSummary{
+		Add:     matchCount(reApplyAdded, applyStatus),
+		Change:  matchCount(reApplyChanged, applyStatus),
+		Destroy: matchCount(reApplyDestroyed, applyStatus),
+	}
+}
+
+func matchCount(re *regexp.Regexp, s string) int {
+	m := re.FindStringSubmatch(s)
+	if len(m) != 2 {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// errorExcerpt returns terraform output starting from first error block,
+// if error block is not found last part of the output is returned.
+// returned excerpt is truncated to maxErrorExcerptLen
+func errorExcerpt(output string) string {
+	output = strings.TrimSpace(output)
+
+	if loc := reErrorLine.FindStringIndex(output); loc != nil {
+		output = output[loc[0]:]
+		if len(output) > maxErrorExcerptLen {
+			output = output[:maxErrorExcerptLen]
+		}
+		// truncation might have split multi-byte characters
+		return strings.TrimSpace(strings.ToValidUTF8(output, ""))
+	}
+
+	if len(output) > maxErrorExcerptLen {
+		output = output[len(output)-maxErrorExcerptLen:]
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(output, ""))
+}
@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+)
+
+func Test_parsePlanSummary(t *testing.T) {
+	tests := []struct {
+		name       string
+		planStatus string
+		want       *tfaplv1beta1.PlanSummary
+	}{
+		{"empty", "", &tfaplv1beta1.PlanSummary{}},
+		{"no changes", "No changes. Your infrastructure matches the configuration.", &tfaplv1beta1.PlanSummary{}},
+		{"changes", "Plan: 3 to add, 12 to change, 1 to destroy.", &tfaplv1beta1.PlanSummary{Add: 3, Change: 12, Destroy: 1}},
+		{"with imports", "Plan: 1 to import, 0 to add, 2 to change, 0 to destroy.", &tfaplv1beta1.PlanSummary{Change: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parsePlanSummary(tt.planStatus)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parsePlanSummary() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_parseApplySummary(t *testing.T) {
+	tests := []struct {
+		name        string
+		applyStatus string
+		want        *tfaplv1beta1.PlanSummary
+	}{
+		{"empty", "", &tfaplv1beta1.PlanSummary{}},
+		{"no changes", "Apply complete! Resources: 0 added, 0 changed, 0 destroyed.", &tfaplv1beta1.PlanSummary{}},
+		{"changes", "Apply complete! Resources: 3 added, 12 changed, 1 destroyed.", &tfaplv1beta1.PlanSummary{Add: 3, Change: 12, Destroy: 1}},
+		{"with imports", "Apply complete! Resources: 1 imported, 0 added, 2 changed, 0 destroyed.", &tfaplv1beta1.PlanSummary{Change: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseApplySummary(tt.applyStatus)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseApplySummary() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_errorExcerpt(t *testing.T) {
+	long := strings.Repeat("a", 2*maxErrorExcerptLen)
+
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"empty", "", ""},
+		{"no error block", "unable to init module\nsome output\n", "unable to init module\nsome output"},
+		{
+			"error block",
+			"unable to plan module\naws_s3_bucket.a: Refreshing state...\n\nError: Invalid reference\n\n  on main.tf line 3\n",
+			"Error: Invalid reference\n\n  on main.tf line 3",
+		},
+		{
+			"boxed error block",
+			"unable to plan module\n╷\n│ Error: Invalid reference\n│ \n╵\n",
+			"│ Error: Invalid reference\n│ \n╵",
+		},
+		{"long output without error is tail", "start" + long, long[:maxErrorExcerptLen]},
+		{"long error is head", "Error: " + long, ("Error: " + long)[:maxErrorExcerptLen]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errorExcerpt(tt.output)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("errorExcerpt() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -7,10 +7,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/hashicorp/terraform-exec/tfexec"
 	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	"github.com/utilitywarehouse/terraform-applier/metrics"
 	"github.com/utilitywarehouse/terraform-applier/sysutil"
+	"github.com/utilitywarehouse/terraform-applier/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -64,7 +68,11 @@ func (r *Runner) NewTFRunner(
 	// clone repo to new temp dir so that file doesn't change during run.
 	// checkout whole repo as module might contain relative path to modules/files
 	// which are outside of its path
-	_, err = r.Repos.Clone(ctx, module.Spec.RepoURL, tmpRoot, runRef, nil, true)
+	cloneStart := time.Now()
+	cloneCtx, span := tracing.Start(ctx, "Repos.Clone", attribute.String("ref", runRef))
+	_, err = r.Repos.Clone(cloneCtx, module.Spec.RepoURL, tmpRoot, runRef, nil, true)
+	tracing.End(span, err)
+	r.Metrics.ObservePhaseDuration(module.Name, module.Namespace, metrics.PhaseClone, time.Since(cloneStart).Seconds())
 	if err != nil {
 		return nil, fmt.Errorf("unable copy module's tf files to tmp dir err:%w", err)
 	}
@@ -173,7 +181,10 @@ func (te *tfRunner) cleanUp() {
 	sysutil.RemoveAll(te.rootDir)
 }
 
-func (te *tfRunner) init(ctx context.Context, backendConf map[string]string) (string, error) {
+func (te *tfRunner) init(ctx context.Context, backendConf map[string]string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "terraform.init")
+	defer func() { tracing.End(span, err) }()
+
 	var out bytes.Buffer
 	te.tf.SetStdout(&out)
 	te.tf.SetStderr(&out)
@@ -198,7 +209,10 @@ func (te *tfRunner) init(ctx context.Context, backendConf map[string]string) (st
 	return out.String(), nil
 }
 
-func (te *tfRunner) plan(ctx context.Context) (bool, string, error) {
+func (te *tfRunner) plan(ctx context.Context) (_ bool, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "terraform.plan")
+	defer func() { tracing.End(span, err) }()
+
 	var out bytes.Buffer
 	te.tf.SetStdout(&out)
 	te.tf.SetStderr(&out)
@@ -214,19 +228,25 @@ func (te *tfRunner) plan(ctx context.Context) (bool, string, error) {
 
 // showPlanFileRaw reads a given plan file and outputs the plan in a
 // human-friendly, opaque format.
-func (te *tfRunner) showPlanFileRaw(ctx context.Context) (string, error) {
+func (te *tfRunner) showPlanFileRaw(ctx context.Context) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "terraform.show")
+	defer func() { tracing.End(span, err) }()
+
 	planOut := filepath.Join(te.workingDir, te.planFileName)
 	return te.tf.ShowPlanFileRaw(ctx, planOut)
 }
 
-func (te *tfRunner) apply(ctx context.Context) (string, error) {
+func (te *tfRunner) apply(ctx context.Context) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "terraform.apply")
+	defer func() { tracing.End(span, err) }()
+
 	var out bytes.Buffer
 	te.tf.SetStdout(&out)
 	te.tf.SetStderr(&out)
 
 	planOut := filepath.Join(te.workingDir, te.planFileName)
 
-	_, err := os.Stat(planOut)
+	_, err = os.Stat(planOut)
 	if err != nil {
 		return "", fmt.Errorf("plan output file is required for apply run expected_loc:%s err:%w", planOut, err)
 	}
@@ -87,6 +87,35 @@ func GetModule(ctx context.Context, client client.Client, key types.NamespacedNa
 	return module, nil
 }
 
+// GetModuleDefaults will use PollUntilContextTimeout to get requested module defaults
+func GetModuleDefaults(ctx context.Context, client client.Client, key types.NamespacedName) (*tfaplv1beta1.ModuleDefaults, error) {
+	defaults := new(tfaplv1beta1.ModuleDefaults)
+
+	err := PollUntilTimeout(ctx, func(ctx context.Context) (err error) {
+		return client.Get(ctx, key, defaults)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("timed out trying to get module defaults err:%w", err)
+	}
+	return defaults, nil
+}
+
+// MergeModuleDefaults will fetch ModuleDefaults referenced by the module
+// and merge it into module's spec
+func MergeModuleDefaults(ctx context.Context, client client.Client, module *tfaplv1beta1.Module) error {
+	if module.Spec.DefaultsRef == "" {
+		return nil
+	}
+
+	defaults, err := GetModuleDefaults(ctx, client, types.NamespacedName{Namespace: module.Namespace, Name: module.Spec.DefaultsRef})
+	if err != nil {
+		return err
+	}
+
+	module.MergeDefaults(defaults)
+	return nil
+}
+
 // GetSecret will use PollUntilContextTimeout to get requested secret
 func GetSecret(ctx context.Context, client kubernetes.Interface, namespace, name string) (*corev1.Secret, error) {
 	var secret *corev1.Secret
@@ -36,6 +36,38 @@ type RedisInterface interface {
 	SetPendingApplyUpload(ctx context.Context, module types.NamespacedName, commit string, prNumber int) error
 
 	CleanupPRKeys(ctx context.Context, module types.NamespacedName, pr int, commit string) error
+
+	PRPlanState(ctx context.Context, cluster, repo string, pr int, module types.NamespacedName, commit string) (*PRPlanState, error)
+	PRPlanStates(ctx context.Context, cluster, repo string, pr int) ([]*PRPlanState, error)
+	SetPRPlanState(ctx context.Context, state *PRPlanState) error
+}
+
+const (
+	// PR plan request states
+	PRPlanStateRequested = "Requested"
+	PRPlanStateCompleted = "Completed"
+)
+
+// PRPlanState is the state of the PR plan (or apply) request of the module
+// for a commit, its used by PR planner to dedup requests instead of parsing
+// PR comments
+type PRPlanState struct {
+	Cluster  string                `json:"cluster"`
+	Repo     string                `json:"repo"`
+	PR       int                   `json:"pr"`
+	Module   types.NamespacedName  `json:"module"`
+	CommitID string                `json:"commitID"`
+	Request  *tfaplv1beta1.Request `json:"request,omitempty"`
+	// HeadCommit is the PR head commit at the time of the request
+	HeadCommit string `json:"headCommit,omitempty"`
+	// TriggerCommentID is the ID of the comment which requested the run
+	TriggerCommentID int    `json:"triggerCommentID,omitempty"`
+	AckCommentID     int    `json:"ackCommentID,omitempty"`
+	OutputCommentID  int    `json:"outputCommentID,omitempty"`
+	State            string `json:"state"`
+	// RunStatus is the status of the completed run
+	RunStatus string    `json:"runStatus,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 type Redis struct {
@@ -58,6 +90,16 @@ func DefaultPRLastRunsKey(module types.NamespacedName, pr int, hash string) stri
 	return fmt.Sprintf("%sPR:%d:%s", keyPrefix(module), pr, hash)
 }
 
+// prPlanStateKey doesn't start with module prefix and uses lower case 'pr' so
+// that it doesn't match run keys
+func prPlanStateKey(cluster, repo string, pr int, module types.NamespacedName, commit string) string {
+	return fmt.Sprintf("planner:%s:%s:pr:%d:%s:%s:%s", cluster, repo, pr, module.Namespace, module.Name, commit)
+}
+
+func prPlanStatesPattern(cluster, repo string, pr int) string {
+	return fmt.Sprintf("planner:%s:%s:pr:%d:*", cluster, repo, pr)
+}
+
 func PendingApplyRunOutputUploadKey(module types.NamespacedName, hash string) string {
 	return fmt.Sprintf("pending:apply_upload:%shash:%s", keyPrefix(module), hash)
 }
@@ -130,6 +172,63 @@ func (r Redis) SetPendingApplyUpload(ctx context.Context, module types.Namespace
 	return r.Client.Set(ctx, PendingApplyRunOutputUploadKey(module, commit), prNumber, PRApplyUploadExpDur).Err()
 }
 
+// PRPlanState returns state of the PR plan request
+func (r Redis) PRPlanState(ctx context.Context, cluster, repo string, pr int, module types.NamespacedName, commit string) (*PRPlanState, error) {
+	output, err := r.Client.Get(ctx, prPlanStateKey(cluster, repo, pr, module, commit)).Result()
+	if err == redis.Nil {
+		return nil, ErrKeyNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get value err:%w", err)
+	}
+
+	state := PRPlanState{}
+	if err := json.Unmarshal([]byte(output), &state); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal plan state err:%w", err)
+	}
+
+	return &state, nil
+}
+
+// PRPlanStates returns states of all the plan requests of the PR
+func (r Redis) PRPlanStates(ctx context.Context, cluster, repo string, pr int) ([]*PRPlanState, error) {
+	keys, err := r.Client.Keys(ctx, prPlanStatesPattern(cluster, repo, pr)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("unable to get plan state keys err:%w", err)
+	}
+
+	var states []*PRPlanState
+	for _, key := range keys {
+		output, err := r.Client.Get(ctx, key).Result()
+		if err == redis.Nil {
+			// key might have expired since listing
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to get value err:%w", err)
+		}
+
+		state := PRPlanState{}
+		if err := json.Unmarshal([]byte(output), &state); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal plan state err:%w", err)
+		}
+		states = append(states, &state)
+	}
+
+	return states, nil
+}
+
+// SetPRPlanState puts given PR plan state in to cache with expiration
+func (r Redis) SetPRPlanState(ctx context.Context, state *PRPlanState) error {
+	state.UpdatedAt = time.Now()
+
+	str, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("unable to marshal plan state err:%w", err)
+	}
+
+	key := prPlanStateKey(state.Cluster, state.Repo, state.PR, state.Module, state.CommitID)
+	return r.Client.Set(ctx, key, str, PRKeyExpirationDur).Err()
+}
+
 func (r Redis) setKV(ctx context.Context, key string, run *tfaplv1beta1.Run, exp time.Duration) error {
 	str, err := json.Marshal(run)
 	if err != nil {
@@ -95,6 +95,36 @@ func (mr *MockRedisInterfaceMockRecorder) GetCommitHash(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommitHash", reflect.TypeOf((*MockRedisInterface)(nil).GetCommitHash), arg0, arg1)
 }
 
+// PRPlanState mocks base method.
+func (m *MockRedisInterface) PRPlanState(arg0 context.Context, arg1, arg2 string, arg3 int, arg4 types.NamespacedName, arg5 string) (*PRPlanState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PRPlanState", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(*PRPlanState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PRPlanState indicates an expected call of PRPlanState.
+func (mr *MockRedisInterfaceMockRecorder) PRPlanState(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PRPlanState", reflect.TypeOf((*MockRedisInterface)(nil).PRPlanState), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// PRPlanStates mocks base method.
+func (m *MockRedisInterface) PRPlanStates(arg0 context.Context, arg1, arg2 string, arg3 int) ([]*PRPlanState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PRPlanStates", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]*PRPlanState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PRPlanStates indicates an expected call of PRPlanStates.
+func (mr *MockRedisInterfaceMockRecorder) PRPlanStates(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PRPlanStates", reflect.TypeOf((*MockRedisInterface)(nil).PRPlanStates), arg0, arg1, arg2, arg3)
+}
+
 // PRRun mocks base method.
 func (m *MockRedisInterface) PRRun(arg0 context.Context, arg1 types.NamespacedName, arg2 int, arg3 string) (*v1beta1.Run, error) {
 	m.ctrl.T.Helper()
@@ -183,6 +213,20 @@ func (mr *MockRedisInterfaceMockRecorder) SetDefaultLastRun(arg0, arg1 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDefaultLastRun", reflect.TypeOf((*MockRedisInterface)(nil).SetDefaultLastRun), arg0, arg1)
 }
 
+// SetPRPlanState mocks base method.
+func (m *MockRedisInterface) SetPRPlanState(arg0 context.Context, arg1 *PRPlanState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPRPlanState", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPRPlanState indicates an expected call of SetPRPlanState.
+func (mr *MockRedisInterfaceMockRecorder) SetPRPlanState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPRPlanState", reflect.TypeOf((*MockRedisInterface)(nil).SetPRPlanState), arg0, arg1)
+}
+
 // SetPRRun mocks base method.
 func (m *MockRedisInterface) SetPRRun(arg0 context.Context, arg1 *v1beta1.Run) error {
 	m.ctrl.T.Helper()
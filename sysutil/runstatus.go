@@ -1,6 +1,17 @@
 package sysutil
 
-import "sync"
+import (
+	"context"
+	"sync"
+
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+)
+
+// ActiveRun is stored in RunStatus for the module which is currently running
+type ActiveRun struct {
+	Request *tfaplv1beta1.Request
+	Cancel  context.CancelFunc
+}
 
 // RunStatus is the Map with Lock so its safe for concurrent use
 // sync.Map is not used as it doesn't have Len() function and normal map with
@@ -50,3 +61,18 @@ func (rs *RunStatus) Store(key string, value any) {
 
 	rs.status[key] = value
 }
+
+// Cancel cancels active run stored for the key if match returns true for
+// the request of the run. it returns false if key is not found, stored value
+// is not an active run or run's request doesn't match
+func (rs *RunStatus) Cancel(key string, match func(req *tfaplv1beta1.Request) bool) bool {
+	rs.RLock()
+	defer rs.RUnlock()
+
+	run, ok := rs.status[key].(*ActiveRun)
+	if !ok || !match(run.Request) {
+		return false
+	}
+	run.Cancel()
+	return true
+}
@@ -0,0 +1,8 @@
+package sysutil
+
+import "k8s.io/apimachinery/pkg/types"
+
+// ModuleWebURL returns url of the module on the web UI
+func ModuleWebURL(webserverURL string, module types.NamespacedName) string {
+	return webserverURL + "/#" + module.Namespace + "_" + module.Name
+}
@@ -0,0 +1,82 @@
+// Package tracing configures OpenTelemetry tracing of the module runs
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "terraform-applier"
+	tracerName  = "github.com/utilitywarehouse/terraform-applier"
+)
+
+// Init sets up global tracer provider which exports spans to the OTLP
+// collector over HTTP. exporter, sampler and resource can be configured
+// using standard OTEL_EXPORTER_OTLP_*, OTEL_TRACES_SAMPLER* and
+// OTEL_RESOURCE_ATTRIBUTES env variables. returned func must be called on
+// shutdown to flush pending spans.
+func Init(ctx context.Context, cluster string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create OTLP trace exporter err:%w", err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(
+			semconv.ServiceName(serviceName),
+			attribute.String("cluster", cluster),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create tracing resource err:%w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	return tp.Shutdown, nil
+}
+
+// Start creates a span and a context containing the newly-created span.
+// if tracing is not initialised spans are no-op
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records given error on the span if its not nil and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns trace ID of the span in the given context,
+// it returns empty string if context doesn't contain valid span
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
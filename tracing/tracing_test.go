@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpans(t *testing.T) {
+	// without tracer provider spans are no-op
+	ctx, span := Start(context.Background(), "noop")
+	if id := TraceID(ctx); id != "" {
+		t.Errorf("TraceID() = %q, want empty string for no-op span", id)
+	}
+	End(span, nil)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	ctx, root := Start(context.Background(), "run")
+	traceID := TraceID(ctx)
+	if traceID == "" || traceID != root.SpanContext().TraceID().String() {
+		t.Fatalf("TraceID() = %q, want %q", traceID, root.SpanContext().TraceID())
+	}
+
+	_, child := Start(ctx, "terraform.plan")
+	End(child, fmt.Errorf("plan failed"))
+	End(root, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	plan := spans[0]
+	if plan.Name != "terraform.plan" || plan.Parent.SpanID() != root.SpanContext().SpanID() {
+		t.Errorf("terraform.plan span should be child of root span: %+v", plan)
+	}
+	if plan.Status.Code != codes.Error || plan.Status.Description != "plan failed" {
+		t.Errorf("unexpected span status %+v", plan.Status)
+	}
+	if len(plan.Events) != 1 || plan.Events[0].Name != "exception" {
+		t.Errorf("error should be recorded on span events:%+v", plan.Events)
+	}
+
+	if spans[1].Status.Code != codes.Unset {
+		t.Errorf("unexpected root span status %+v", spans[1].Status)
+	}
+}
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/utilitywarehouse/terraform-applier/prplanner"
+)
+
+func (wh *Webhook) handleBitbucketWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	event := r.Header.Get("X-Event-Key")
+
+	switch event {
+	case "diagnostics:ping":
+		w.Write([]byte("pong"))
+		return
+	case "repo:refs_changed",
+		"pr:opened", "pr:from_ref_updated", "pr:merged",
+		"pr:comment:added", "pr:comment:edited":
+	default:
+		// exit early
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		wh.Log.Error("cannot read request body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !wh.SkipWebhookValidation && !wh.isValidBitbucketSignature(r, body, wh.BitbucketWebhookSecret) {
+		wh.Log.Error("invalid bitbucket signature")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var payload BitbucketEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		wh.Log.Error("cannot unmarshal json payload", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	e := wh.bitbucketToPRPlannerEvent(payload)
+
+	// only process event if its from synced repository
+	if _, err := wh.Repos.Repository(e.Repository.URL); err != nil {
+		return
+	}
+
+	switch event {
+	case "repo:refs_changed":
+		go wh.processPushEvent(e)
+
+	case "pr:opened", "pr:from_ref_updated":
+		if wh.PRPlanner == nil || payload.PullRequest.Draft {
+			return
+		}
+		go wh.processPRWebHookEvent(e, payload.PullRequest.FromRef.LatestCommit)
+
+	case "pr:merged":
+		if wh.PRPlanner == nil {
+			return
+		}
+		go wh.processPRCloseEvent(e)
+
+	case "pr:comment:added", "pr:comment:edited":
+		if wh.PRPlanner == nil || prplanner.IsSelfComment(payload.Comment.Text) {
+			return
+		}
+		go wh.PRPlanner.ProcessPRWebHookEvent(e, e.Number)
+	}
+}
+
+// bitbucketToPRPlannerEvent converts Bitbucket event to planner event. payload
+// doesn't contain clone url of the repository hence its generated from
+// the BitbucketURL, same as http clone url
+func (wh *Webhook) bitbucketToPRPlannerEvent(event BitbucketEvent) prplanner.GitHubWebhook {
+	e := prplanner.GitHubWebhook{
+		Number: event.PullRequest.ID,
+	}
+
+	repo := event.Repository
+	if event.PullRequest.ID != 0 {
+		repo = event.PullRequest.ToRef.Repository
+	}
+
+	e.Repository.Name = repo.Slug
+	e.Repository.Owner.Login = repo.Project.Key
+	e.Repository.URL = fmt.Sprintf("%s/scm/%s/%s.git",
+		strings.TrimSuffix(wh.BitbucketURL, "/"), strings.ToLower(repo.Project.Key), repo.Slug)
+
+	e.PullRequest.Draft = event.PullRequest.Draft
+	if event.EventKey == "pr:merged" {
+		e.Action = "closed"
+		e.PullRequest.Merged = true
+		e.PullRequest.MergeCommitSHA = event.PullRequest.Properties.MergeCommit.ID
+	}
+
+	return e
+}
+
+// isValidBitbucketSignature validates signature which is in same format as
+// GitHub signature but set on 'X-Hub-Signature' header
+func (wh *Webhook) isValidBitbucketSignature(r *http.Request, message []byte, secret string) bool {
+	gotSignature := r.Header.Get("X-Hub-Signature")
+
+	expSignature := wh.computeHMAC(message, secret)
+	if expSignature == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(gotSignature), []byte(expSignature))
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/utilitywarehouse/terraform-applier/git"
+	"github.com/utilitywarehouse/terraform-applier/prplanner"
+)
+
+func Test_bitbucketWebhook(t *testing.T) {
+	goMockCtrl := gomock.NewController(t)
+	testRepos := git.NewMockRepositories(goMockCtrl)
+
+	wh := &Webhook{
+		BitbucketWebhookSecret: "a1b2c3d4e5",
+		BitbucketURL:           "https://bitbucket.example.com/",
+		Repos:                  testRepos,
+		Log:                    slog.Default(),
+	}
+
+	// draft PR is not processed
+	body := `{"eventKey":"pr:opened","pullRequest":{"id":1,"draft":true,"toRef":{"repository":{"slug":"foo","project":{"key":"INFRA"}}}}}`
+	validSig := wh.computeHMAC([]byte(body), wh.BitbucketWebhookSecret)
+
+	tests := []struct {
+		name       string
+		method     string
+		event      string
+		sig        string
+		wantStatus int
+	}{
+		{"invalid method", "GET", "pr:opened", validSig, http.StatusBadRequest},
+		{"ping", "POST", "diagnostics:ping", "", http.StatusOK},
+		{"invalid event", "POST", "pr:reviewer:approved", validSig, http.StatusOK},
+		{"invalid signature", "POST", "pr:opened", "sha256=invalid", http.StatusBadRequest},
+		{"valid event", "POST", "pr:opened", validSig, http.StatusOK},
+	}
+
+	testRepos.EXPECT().Repository("https://bitbucket.example.com/scm/infra/foo.git").Return(nil, nil).Times(1)
+
+	server := httptest.NewServer(http.HandlerFunc(wh.handleBitbucketWebhook))
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("Failed to make a request: %v", err)
+			}
+			req.Header.Set("X-Event-Key", tt.event)
+			req.Header.Set("X-Hub-Signature", tt.sig)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %v, got %v", tt.wantStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func Test_bitbucketToPRPlannerEvent(t *testing.T) {
+	wh := &Webhook{BitbucketURL: "https://bitbucket.example.com"}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    prplanner.GitHubWebhook
+	}{
+		{
+			"refs changed",
+			`{"eventKey":"repo:refs_changed","repository":{"slug":"foo","project":{"key":"INFRA"}}}`,
+			func() prplanner.GitHubWebhook {
+				e := prplanner.GitHubWebhook{}
+				e.Repository.Name = "foo"
+				e.Repository.Owner.Login = "INFRA"
+				e.Repository.URL = "https://bitbucket.example.com/scm/infra/foo.git"
+				return e
+			}(),
+		},
+		{
+			"pr merged",
+			`{"eventKey":"pr:merged","pullRequest":{"id":4,"toRef":{"repository":{"slug":"foo","project":{"key":"INFRA"}}},"properties":{"mergeCommit":{"id":"abc"}}}}`,
+			func() prplanner.GitHubWebhook {
+				e := prplanner.GitHubWebhook{Number: 4, Action: "closed"}
+				e.Repository.Name = "foo"
+				e.Repository.Owner.Login = "INFRA"
+				e.Repository.URL = "https://bitbucket.example.com/scm/infra/foo.git"
+				e.PullRequest.Merged = true
+				e.PullRequest.MergeCommitSHA = "abc"
+				return e
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event BitbucketEvent
+			if err := json.Unmarshal([]byte(tt.payload), &event); err != nil {
+				t.Fatal(err)
+			}
+			got := wh.bitbucketToPRPlannerEvent(event)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("bitbucketToPRPlannerEvent() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
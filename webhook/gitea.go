@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// handleGiteaWebhook handles Gitea and Forgejo webhooks, payload of these
+// events are compatible with GitHub events
+func (wh *Webhook) handleGiteaWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	event := r.Header.Get("X-Gitea-Event")
+	if event == "" {
+		event = r.Header.Get("X-Forgejo-Event")
+	}
+
+	if !slices.Contains(expectedEvents, event) {
+		// exit early
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		wh.Log.Error("cannot read request body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !wh.SkipWebhookValidation && !wh.isValidGiteaSignature(r, body, wh.GiteaWebhookSecret) {
+		wh.Log.Error("invalid gitea signature")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var payload GitHubEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		wh.Log.Error("cannot unmarshal json payload", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	wh.processEvent(event, payload)
+}
+
+// isValidGiteaSignature validates signature which is hex encoded HMAC SHA256
+// of the body without 'sha256=' prefix
+func (wh *Webhook) isValidGiteaSignature(r *http.Request, message []byte, secret string) bool {
+	gotSignature := r.Header.Get("X-Gitea-Signature")
+	if gotSignature == "" {
+		gotSignature = r.Header.Get("X-Forgejo-Signature")
+	}
+
+	expSignature := strings.TrimPrefix(wh.computeHMAC(message, secret), "sha256=")
+	if expSignature == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(gotSignature), []byte(expSignature))
+}
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/utilitywarehouse/terraform-applier/git"
+)
+
+func Test_giteaWebhook(t *testing.T) {
+	goMockCtrl := gomock.NewController(t)
+	testRepos := git.NewMockRepositories(goMockCtrl)
+
+	wh := &Webhook{
+		GiteaWebhookSecret: "a1b2c3d4e5",
+		Repos:              testRepos,
+		Log:                slog.Default(),
+	}
+
+	body := `{"action":"foo","repository":{"html_url":"https://gitea.example.com/infra/foo"}}`
+	validSig := strings.TrimPrefix(wh.computeHMAC([]byte(body), wh.GiteaWebhookSecret), "sha256=")
+
+	tests := []struct {
+		name        string
+		method      string
+		eventHeader string
+		event       string
+		sigHeader   string
+		sig         string
+		wantStatus  int
+	}{
+		{"invalid method", "GET", "X-Gitea-Event", "pull_request", "X-Gitea-Signature", validSig, http.StatusBadRequest},
+		{"invalid event", "POST", "X-Gitea-Event", "release", "X-Gitea-Signature", validSig, http.StatusOK},
+		{"github style signature", "POST", "X-Gitea-Event", "pull_request", "X-Gitea-Signature", "sha256=" + validSig, http.StatusBadRequest},
+		{"invalid signature", "POST", "X-Gitea-Event", "pull_request", "X-Gitea-Signature", "invalid", http.StatusBadRequest},
+		{"valid gitea event", "POST", "X-Gitea-Event", "pull_request", "X-Gitea-Signature", validSig, http.StatusOK},
+		{"valid forgejo event", "POST", "X-Forgejo-Event", "pull_request", "X-Forgejo-Signature", validSig, http.StatusOK},
+	}
+
+	testRepos.EXPECT().Repository("https://gitea.example.com/infra/foo").Return(nil, nil).Times(2)
+
+	server := httptest.NewServer(http.HandlerFunc(wh.handleGiteaWebhook))
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("Failed to make a request: %v", err)
+			}
+			req.Header.Set(tt.eventHeader, tt.event)
+			req.Header.Set(tt.sigHeader, tt.sig)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %v, got %v", tt.wantStatus, resp.StatusCode)
+			}
+		})
+	}
+}
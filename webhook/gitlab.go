@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+
+	"github.com/utilitywarehouse/terraform-applier/prplanner"
+)
+
+var expectedGitLabEvents = []string{"Push Hook", "Merge Request Hook", "Note Hook"}
+
+func (wh *Webhook) handleGitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	event := r.Header.Get("X-Gitlab-Event")
+
+	if !slices.Contains(expectedGitLabEvents, event) {
+		// exit early
+		return
+	}
+
+	if !wh.SkipWebhookValidation && !isValidGitLabToken(r, wh.GitLabWebhookSecret) {
+		wh.Log.Error("invalid gitlab token")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		wh.Log.Error("cannot read request body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var payload GitLabEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		wh.Log.Error("cannot unmarshal json payload", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// only process event if its from synced repository
+	if _, err := wh.Repos.Repository(payload.Project.GitHTTPURL); err != nil {
+		return
+	}
+
+	e := gitLabToPRPlannerEvent(payload)
+
+	switch event {
+	case "Push Hook":
+		go wh.processPushEvent(e)
+
+	case "Merge Request Hook":
+		if wh.PRPlanner == nil || payload.ObjectAttributes.Draft {
+			return
+		}
+
+		switch payload.ObjectAttributes.Action {
+		case "open", "update", "reopen":
+			go wh.processPRWebHookEvent(e, payload.ObjectAttributes.LastCommit.ID)
+		case "merge":
+			go wh.processPRCloseEvent(e)
+		}
+
+	case "Note Hook":
+		if wh.PRPlanner == nil ||
+			payload.ObjectAttributes.NoteableType != "MergeRequest" ||
+			prplanner.IsSelfComment(payload.ObjectAttributes.Note) {
+			return
+		}
+
+		// we know the body, but we still need to know the module user is requesting
+		// plan run for belongs to this MR hence we need to do full reconcile of MR
+		go wh.PRPlanner.ProcessPRWebHookEvent(e, payload.MergeRequest.IID)
+	}
+}
+
+// gitLabToPRPlannerEvent converts GitLab event to planner event, merge request
+// is mapped to pull request and namespace of the project is used as owner
+func gitLabToPRPlannerEvent(event GitLabEvent) prplanner.GitHubWebhook {
+	e := prplanner.GitHubWebhook{
+		Number: event.ObjectAttributes.IID,
+	}
+
+	e.Repository.Name = event.Project.Path
+	e.Repository.URL = event.Project.GitHTTPURL
+	e.Repository.Owner.Login = path.Dir(event.Project.PathWithNamespace)
+
+	if event.ObjectKind == "merge_request" {
+		e.PullRequest.Draft = event.ObjectAttributes.Draft
+		if event.ObjectAttributes.Action == "merge" {
+			e.Action = "closed"
+			e.PullRequest.Merged = true
+			e.PullRequest.MergeCommitSHA = event.ObjectAttributes.MergeCommitSHA
+			// merge commit is not created for fast-forward merges
+			if e.PullRequest.MergeCommitSHA == "" {
+				e.PullRequest.MergeCommitSHA = event.ObjectAttributes.LastCommit.ID
+			}
+		}
+	}
+
+	return e
+}
+
+// isValidGitLabToken checks secret token set on GitLab webhook
+func isValidGitLabToken(r *http.Request, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(secret)) == 1
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/utilitywarehouse/terraform-applier/git"
+	"github.com/utilitywarehouse/terraform-applier/prplanner"
+)
+
+func Test_gitLabWebhook(t *testing.T) {
+	goMockCtrl := gomock.NewController(t)
+	testRepos := git.NewMockRepositories(goMockCtrl)
+
+	wh := &Webhook{
+		GitLabWebhookSecret: "a1b2c3d4e5",
+		Repos:               testRepos,
+		Log:                 slog.Default(),
+	}
+
+	body := `{"object_kind":"merge_request","project":{"git_http_url":"https://gitlab.example.com/infra/foo.git"},"object_attributes":{"action":"foo"}}`
+
+	tests := []struct {
+		name       string
+		method     string
+		event      string
+		token      string
+		wantStatus int
+	}{
+		{"invalid method", "GET", "Merge Request Hook", "a1b2c3d4e5", http.StatusBadRequest},
+		{"invalid event", "POST", "Job Hook", "a1b2c3d4e5", http.StatusOK},
+		{"invalid token", "POST", "Merge Request Hook", "invalid", http.StatusBadRequest},
+		{"missing token", "POST", "Merge Request Hook", "", http.StatusBadRequest},
+		{"invalid action", "POST", "Merge Request Hook", "a1b2c3d4e5", http.StatusOK},
+	}
+
+	testRepos.EXPECT().Repository("https://gitlab.example.com/infra/foo.git").Return(nil, nil).Times(1)
+
+	server := httptest.NewServer(http.HandlerFunc(wh.handleGitLabWebhook))
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("Failed to make a request: %v", err)
+			}
+			req.Header.Set("X-Gitlab-Event", tt.event)
+			req.Header.Set("X-Gitlab-Token", tt.token)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %v, got %v", tt.wantStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func Test_gitLabToPRPlannerEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    prplanner.GitHubWebhook
+	}{
+		{
+			"merge request update",
+			`{"object_kind":"merge_request",
+			  "project":{"path":"foo","path_with_namespace":"infra/team/foo","git_http_url":"https://gitlab.example.com/infra/team/foo.git"},
+			  "object_attributes":{"iid":12,"action":"update","draft":false,"last_commit":{"id":"abc"}}}`,
+			func() prplanner.GitHubWebhook {
+				e := prplanner.GitHubWebhook{Number: 12}
+				e.Repository.Name = "foo"
+				e.Repository.Owner.Login = "infra/team"
+				e.Repository.URL = "https://gitlab.example.com/infra/team/foo.git"
+				return e
+			}(),
+		},
+		{
+			"merge request fast-forward merged",
+			`{"object_kind":"merge_request",
+			  "project":{"path":"foo","path_with_namespace":"infra/foo","git_http_url":"https://gitlab.example.com/infra/foo.git"},
+			  "object_attributes":{"iid":12,"action":"merge","merge_commit_sha":null,"last_commit":{"id":"abc"}}}`,
+			func() prplanner.GitHubWebhook {
+				e := prplanner.GitHubWebhook{Number: 12, Action: "closed"}
+				e.Repository.Name = "foo"
+				e.Repository.Owner.Login = "infra"
+				e.Repository.URL = "https://gitlab.example.com/infra/foo.git"
+				e.PullRequest.Merged = true
+				e.PullRequest.MergeCommitSHA = "abc"
+				return e
+			}(),
+		},
+		{
+			"note",
+			`{"object_kind":"note",
+			  "project":{"path":"foo","path_with_namespace":"infra/foo","git_http_url":"https://gitlab.example.com/infra/foo.git"},
+			  "object_attributes":{"id":1234,"noteable_type":"MergeRequest","note":"@terraform-applier plan foo"},
+			  "merge_request":{"iid":12}}`,
+			func() prplanner.GitHubWebhook {
+				e := prplanner.GitHubWebhook{}
+				e.Repository.Name = "foo"
+				e.Repository.Owner.Login = "infra"
+				e.Repository.URL = "https://gitlab.example.com/infra/foo.git"
+				return e
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event GitLabEvent
+			if err := json.Unmarshal([]byte(tt.payload), &event); err != nil {
+				t.Fatal(err)
+			}
+			got := gitLabToPRPlannerEvent(event)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("gitLabToPRPlannerEvent() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -41,3 +41,71 @@ type GitHubEvent struct {
 		Body string `json:"body"`
 	} `json:"comment"`
 }
+
+type GitLabEvent struct {
+	ObjectKind string `json:"object_kind"`
+
+	Project struct {
+		Path              string `json:"path"`
+		PathWithNamespace string `json:"path_with_namespace"`
+		GitHTTPURL        string `json:"git_http_url"`
+	} `json:"project"`
+
+	// for merge request events it contains merge request and
+	// for note events it contains note
+	ObjectAttributes struct {
+		IID            int    `json:"iid"`
+		Action         string `json:"action"`
+		Draft          bool   `json:"draft"`
+		MergeCommitSHA string `json:"merge_commit_sha"`
+		LastCommit     struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+
+		// only for notes
+		NoteableType string `json:"noteable_type"`
+		Note         string `json:"note"`
+	} `json:"object_attributes"`
+
+	// only for notes
+	MergeRequest struct {
+		IID   int  `json:"iid"`
+		Draft bool `json:"draft"`
+	} `json:"merge_request"`
+}
+
+type BitbucketEvent struct {
+	EventKey string `json:"eventKey"`
+
+	// only for repository events
+	Repository bitbucketRepository `json:"repository"`
+
+	// only for pull request events
+	PullRequest struct {
+		ID      int  `json:"id"`
+		Draft   bool `json:"draft"`
+		FromRef struct {
+			LatestCommit string `json:"latestCommit"`
+		} `json:"fromRef"`
+		ToRef struct {
+			Repository bitbucketRepository `json:"repository"`
+		} `json:"toRef"`
+		Properties struct {
+			MergeCommit struct {
+				ID string `json:"id"`
+			} `json:"mergeCommit"`
+		} `json:"properties"`
+	} `json:"pullRequest"`
+
+	// only for comment events
+	Comment struct {
+		Text string `json:"text"`
+	} `json:"comment"`
+}
+
+type bitbucketRepository struct {
+	Slug    string `json:"slug"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+}
@@ -23,8 +23,13 @@ import (
 var expectedEvents = []string{"ping", "pull_request", "issue_comment", "push"}
 
 type Webhook struct {
-	ListenAddress         string
-	WebhookSecret         string
+	ListenAddress          string
+	WebhookSecret          string
+	GitLabWebhookSecret    string
+	GiteaWebhookSecret     string
+	BitbucketWebhookSecret string
+	// BitbucketURL is used to generate repository url from Bitbucket events
+	BitbucketURL          string
 	SkipWebhookValidation bool
 	Repos                 git.Repositories
 
@@ -34,6 +39,9 @@ type Webhook struct {
 
 func (wh *Webhook) Start() {
 	http.HandleFunc("/github-events", wh.handleWebhook)
+	http.HandleFunc("/gitlab-events", wh.handleGitLabWebhook)
+	http.HandleFunc("/gitea-events", wh.handleGiteaWebhook)
+	http.HandleFunc("/bitbucket-events", wh.handleBitbucketWebhook)
 	if err := http.ListenAndServe(wh.ListenAddress, nil); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		wh.Log.Error("unable to start server", "err", err)
 	}
@@ -81,13 +89,19 @@ func (wh *Webhook) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	wh.processEvent(event, payload)
+}
+
+// processEvent processes GitHub event, Gitea events are also processed
+// here since payloads are compatible
+func (wh *Webhook) processEvent(event string, payload GitHubEvent) {
 	// only process event if its from synced repository
 	if _, err := wh.Repos.Repository(payload.Repository.URL); err != nil {
 		return
 	}
 
 	if event == "push" {
-		go wh.processPushEvent(payload)
+		go wh.processPushEvent(toPRPlannerEvent(payload))
 		return
 	}
 
@@ -97,13 +111,14 @@ func (wh *Webhook) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		}
 
 		switch payload.Action {
-		case "opened", "synchronize", "reopened":
-			go wh.processPRWebHookEvent(payload)
+		// gitea uses 'synchronized' action for new commits
+		case "opened", "synchronize", "synchronized", "reopened":
+			go wh.processPRWebHookEvent(toPRPlannerEvent(payload), payload.PullRequest.Head.SHA)
 		case "closed":
 			if !payload.PullRequest.Merged {
 				return
 			}
-			go wh.processPRCloseEvent(payload)
+			go wh.processPRCloseEvent(toPRPlannerEvent(payload))
 		}
 	}
 
@@ -136,7 +151,7 @@ func toPRPlannerEvent(event GitHubEvent) prplanner.GitHubWebhook {
 	return e
 }
 
-func (wh *Webhook) processPushEvent(event GitHubEvent) {
+func (wh *Webhook) processPushEvent(event prplanner.GitHubWebhook) {
 	// to avoid simultaneous fetch calls from all tf-appliers
 	time.Sleep(time.Duration(rand.Float64() * float64(time.Minute)))
 
@@ -150,20 +165,20 @@ func (wh *Webhook) processPushEvent(event GitHubEvent) {
 	}
 }
 
-func (wh *Webhook) processPRWebHookEvent(event GitHubEvent) {
-	if err := wh.waitForHeadCommitSync(event.Repository.URL, event.PullRequest.Head.SHA); err != nil {
+func (wh *Webhook) processPRWebHookEvent(event prplanner.GitHubWebhook, headSHA string) {
+	if err := wh.waitForHeadCommitSync(event.Repository.URL, headSHA); err != nil {
 		wh.Log.Error("unable to process pr event", "repo", event.Repository.Name, "number", event.Number, "err", err)
 		return
 	}
-	wh.PRPlanner.ProcessPRWebHookEvent(toPRPlannerEvent(event), event.Number)
+	wh.PRPlanner.ProcessPRWebHookEvent(event, event.Number)
 }
 
-func (wh *Webhook) processPRCloseEvent(event GitHubEvent) {
+func (wh *Webhook) processPRCloseEvent(event prplanner.GitHubWebhook) {
 	if err := wh.waitForHeadCommitSync(event.Repository.URL, event.PullRequest.MergeCommitSHA); err != nil {
 		wh.Log.Error("unable to process pr close event", "repo", event.Repository.Name, "number", event.Number, "err", err)
 		return
 	}
-	wh.PRPlanner.ProcessPRCloseEvent(toPRPlannerEvent(event))
+	wh.PRPlanner.ProcessPRCloseEvent(event)
 }
 
 // waitForHeadCommitSync will check if head SHA commit is mirrored
@@ -23,9 +23,10 @@ type Namespace struct {
 }
 
 type Module struct {
-	Module tfaplv1beta1.Module
-	Runs   []*tfaplv1beta1.Run
-	Events []corev1.Event
+	Module      tfaplv1beta1.Module
+	Runs        []*tfaplv1beta1.Run
+	Events      []corev1.Event
+	DefaultsErr string
 }
 
 func createNamespaceMap(modules []tfaplv1beta1.Module) map[string]*Namespace {
@@ -58,6 +59,24 @@ func listModules(ctx context.Context, clt client.Client) ([]tfaplv1beta1.Module,
 	return moduleList.Items, nil
 }
 
+// mergeModuleDefaults merges ModuleDefaults referenced by the module into its
+// spec. unlike sysutil.MergeModuleDefaults it doesn't retry as web requests
+// should not wait on it and client reads from cache
+func mergeModuleDefaults(ctx context.Context, clt client.Client, module *tfaplv1beta1.Module) error {
+	if module.Spec.DefaultsRef == "" {
+		return nil
+	}
+
+	var defaults tfaplv1beta1.ModuleDefaults
+	err := clt.Get(ctx, types.NamespacedName{Namespace: module.Namespace, Name: module.Spec.DefaultsRef}, &defaults)
+	if err != nil {
+		return fmt.Errorf("unable to get module defaults err:%w", err)
+	}
+
+	module.MergeDefaults(&defaults)
+	return nil
+}
+
 func moduleWithRunsInfo(ctx context.Context, clt client.Client, kubeClient kubernetes.Interface, redis sysutil.RedisInterface, namespacedName types.NamespacedName) (*Module, error) {
 	var m tfaplv1beta1.Module
 
@@ -68,6 +87,11 @@ func moduleWithRunsInfo(ctx context.Context, clt client.Client, kubeClient kuber
 
 	module := Module{Module: m}
 
+	// show effective spec on the UI
+	if err := mergeModuleDefaults(ctx, clt, &module.Module); err != nil {
+		module.DefaultsErr = err.Error()
+	}
+
 	module.Runs = runInfo(ctx, redis, namespacedName)
 
 	// get events
@@ -0,0 +1,59 @@
+package webserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_mergeModuleDefaults(t *testing.T) {
+	if err := tfaplv1beta1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := &tfaplv1beta1.ModuleDefaults{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "defaults"},
+		Spec: tfaplv1beta1.ModuleDefaultsSpec{
+			Env: []tfaplv1beta1.EnvVar{{Name: "TEAM", Value: "foo"}},
+		},
+	}
+	clt := fake.NewFakeClient(defaults)
+
+	tests := []struct {
+		name        string
+		defaultsRef string
+		wantEnv     []tfaplv1beta1.EnvVar
+	}{
+		{"no defaults", "", nil},
+		{"defaults merged", "defaults", []tfaplv1beta1.EnvVar{{Name: "TEAM", Value: "foo"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &tfaplv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "hello"},
+				Spec:       tfaplv1beta1.ModuleSpec{DefaultsRef: tt.defaultsRef},
+			}
+			if err := mergeModuleDefaults(context.Background(), clt, module); err != nil {
+				t.Fatalf("mergeModuleDefaults() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.wantEnv, module.Spec.Env); diff != "" {
+				t.Errorf("mergeModuleDefaults() env mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// missing defaults must be reported without retrying
+	module := &tfaplv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "hello"},
+		Spec:       tfaplv1beta1.ModuleSpec{DefaultsRef: "missing"},
+	}
+	if err := mergeModuleDefaults(context.Background(), clt, module); !apierrors.IsNotFound(err) {
+		t.Errorf("mergeModuleDefaults() error = %v, want not found error", err)
+	}
+}
@@ -49,7 +49,7 @@ function filterModulesList(hash) {
 }
 
 // Send an XHR request to the server to force a run.
-function forceRun(namespace, module, planOnly) {
+function forceRun(namespace, module, planOnly, breakGlass = false) {
   // Disable the buttons and close existing alert
   setForcedButtonDisabled(true)
 
@@ -65,6 +65,7 @@ function forceRun(namespace, module, planOnly) {
       module: module,
       planOnly: planOnly,
       lockID: lockID,
+      breakGlass: String(breakGlass),
     }),
   })
     .then(function (resp) {
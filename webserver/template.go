@@ -6,8 +6,10 @@ import (
 	"strings"
 	"time"
 
+	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
 )
 
 // createTemplate takes in a path to a template file and parses the file to create a Template instance.
@@ -18,6 +20,9 @@ func createTemplate(statusHTML string) (*template.Template, error) {
 			"commitURL":           commitURL,
 			"formattedTime":       formattedTime,
 			"duration":            duration,
+			"applyFrozen":         applyFrozen,
+			"nextApplyWindow":     nextApplyWindow,
+			"toYaml":              toYaml,
 		}).
 		Parse(statusHTML)
 	if err != nil {
@@ -44,6 +49,30 @@ func duration(d time.Duration) string {
 	return d.Round(time.Second).String()
 }
 
+// applyFrozen returns true if module is currently outside of its apply windows,
+// plan only modules are never applied so they are not considered frozen
+func applyFrozen(m tfaplv1beta1.Module) bool {
+	return !m.IsPlanOnly() && !m.IsApplyAllowedAt(time.Now())
+}
+
+// nextApplyWindow returns the time when next apply window opens
+func nextApplyWindow(m tfaplv1beta1.Module) string {
+	next := m.NextApplyWindow(time.Now())
+	if next.IsZero() {
+		return "never"
+	}
+	return next.Format(time.RFC3339)
+}
+
+// toYaml returns yaml representation of given object
+func toYaml(v any) string {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+
 // commitURL will return commit url from given repo url and commit hash
 func commitURL(remoteURL, hash string) string {
 	if remoteURL == "" {
@@ -356,3 +356,28 @@ Plan: 7 to add, 0 to change, 0 to destroy.`,
 		}
 	}
 }
+
+func Test_applyFrozen(t *testing.T) {
+	// window which is only open for a minute in a year
+	closed := []tfaplv1beta1.ApplyWindow{{
+		Schedule: "0 0 1 1 *",
+		Duration: metav1.Duration{Duration: time.Minute},
+	}}
+
+	tests := []struct {
+		name string
+		spec tfaplv1beta1.ModuleSpec
+		want bool
+	}{
+		{"no windows", tfaplv1beta1.ModuleSpec{}, false},
+		{"outside of windows", tfaplv1beta1.ModuleSpec{ApplyWindows: closed}, true},
+		{"plan only", tfaplv1beta1.ModuleSpec{ApplyWindows: closed, PlanOnly: new(true)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyFrozen(tfaplv1beta1.Module{Spec: tt.spec}); got != tt.want {
+				t.Errorf("applyFrozen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	tfaplv1beta1 "github.com/utilitywarehouse/terraform-applier/api/v1beta1"
@@ -201,6 +202,14 @@ func (f *ForceRunHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// RBAC can be set on module defaults
+	if err := mergeModuleDefaults(r.Context(), f.ClusterClt, &module); err != nil {
+		message := fmt.Sprintf("cannot get module defaults '%s'", module.Spec.DefaultsRef)
+		f.Log.Error(message, "module", namespacedName, "error", err)
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
 	// authorisation
 	// check if user has access
 	if f.Authenticator != nil {
@@ -250,7 +259,19 @@ func (f *ForceRunHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	breakGlass := reqType == tfaplv1beta1.ForcedApply && payload["breakGlass"] == "true"
+
+	if reqType == tfaplv1beta1.ForcedApply && !breakGlass && !module.IsApplyAllowedAt(time.Now()) {
+		f.Log.Error("force apply rejected as module is outside of its apply windows", "module", namespacedName)
+		http.Error(w, "module is outside of its apply windows, use break-glass apply to override", http.StatusBadRequest)
+		return
+	}
+
 	req := module.NewRunRequest(reqType, payload["lockID"])
+	req.BreakGlass = breakGlass
+	if breakGlass {
+		f.Log.Warn("break-glass apply requested", "module", namespacedName, "user", userEmail(user))
+	}
 
 	err = sysutil.EnsureRequest(r.Context(), f.ClusterClt, module.NamespacedName(), req)
 	switch {
@@ -271,6 +292,13 @@ func (f *ForceRunHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func userEmail(user *oidc.UserInfo) string {
+	if user == nil {
+		return ""
+	}
+	return user.Email
+}
+
 func parseBody(respBody io.ReadCloser) (map[string]string, error) {
 	payload := map[string]string{}
 